@@ -0,0 +1,275 @@
+// Package alerting evaluates the rules stored in alert_rules against the
+// telemetry tables, records firing and resolved alerts in the alerts table
+// and notifies the configured sinks whenever an alert changes state.
+package alerting
+
+import (
+	"FYNEAPPSSERVER/api/database"
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notifyTimeout bounds a single delivery to one sink
+const notifyTimeout = 30 * time.Second
+
+// Engine evaluates all enabled rules every Interval
+type Engine struct {
+	db       *sql.DB
+	sinks    []Sink
+	interval time.Duration
+}
+
+// NewEngine creates an engine with the sinks from cfg
+func NewEngine(db *sql.DB, cfg Config) *Engine {
+	return &Engine{db: db, sinks: cfg.Sinks, interval: cfg.Interval}
+}
+
+// Run evaluates the rules immediately and then every interval until ctx is
+// done
+func (e *Engine) Run(ctx context.Context) {
+	names := make([]string, 0, len(e.sinks))
+	for _, sink := range e.sinks {
+		names = append(names, sink.Name())
+	}
+	log.Printf("alerting: evaluating rules every %s, sinks: %v", e.interval, names)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Evaluate(ctx, time.Now()); err != nil {
+			log.Printf("alerting: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Evaluate runs every enabled rule once. A failing rule is logged and does
+// not stop the others.
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) error {
+	// Alerts of a rule that was switched off are closed without notifying
+	_, err := e.db.ExecContext(ctx, `
+		UPDATE alerts SET state = $1, resolved_at = $2
+		WHERE state = $3 AND rule_id IN (SELECT rule_id FROM alert_rules WHERE NOT enabled)`,
+		models.AlertResolved, now, models.AlertFiring)
+	if err != nil {
+		return err
+	}
+
+	rules, err := e.rules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := e.evaluateRule(ctx, rule, now); err != nil {
+			log.Printf("alerting: rule %d %q: %v", rule.RuleID, rule.Name, err)
+		}
+	}
+	return nil
+}
+
+type alertKey struct {
+	computerID int
+	series     string
+}
+
+func (e *Engine) evaluateRule(ctx context.Context, rule models.AlertRule, now time.Time) error {
+	results, err := evaluate(ctx, e.db, rule, now)
+	if err != nil {
+		return err
+	}
+	firing, err := e.firingAlerts(ctx, rule)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[alertKey]bool, len(results))
+	for _, r := range results {
+		key := alertKey{r.ComputerID, r.Series}
+		seen[key] = true
+		alert, isFiring := firing[key]
+
+		switch {
+		case r.Firing && !isFiring:
+			alert, err := e.open(ctx, rule, r, now)
+			if err != nil {
+				return err
+			}
+			if alert != nil {
+				e.notify(ctx, rule, alert)
+			}
+		case !r.Firing && isFiring:
+			alert.Value = r.Value
+			alert.Message = describe(rule, r)
+			if err := e.resolve(ctx, rule, alert, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	// A device that stopped reporting (an unplugged disk, a removed
+	// adapter) no longer matches the condition; a silent computer is
+	// reported by no_data rules
+	if rule.Kind == models.AlertRuleThreshold {
+		for key, alert := range firing {
+			if !seen[key] {
+				alert.Message += "; no recent data"
+				if err := e.resolve(ctx, rule, alert, now); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// open records a new firing alert. It returns nil if another server
+// instance recorded it first.
+func (e *Engine) open(ctx context.Context, rule models.AlertRule, r result, now time.Time) (*models.Alert, error) {
+	alert := &models.Alert{
+		RuleID:     rule.RuleID,
+		RuleName:   rule.Name,
+		Severity:   rule.Severity,
+		ComputerID: r.ComputerID,
+		HostName:   r.HostName,
+		Series:     r.Series,
+		State:      models.AlertFiring,
+		Value:      r.Value,
+		Message:    describe(rule, r),
+		StartedAt:  now,
+	}
+	err := e.db.QueryRowContext(ctx, `
+		INSERT INTO alerts (rule_id, computer_id, series, state, value, message, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (rule_id, computer_id, series) WHERE state = 'firing' DO NOTHING
+		RETURNING alert_id`,
+		alert.RuleID, alert.ComputerID, alert.Series, alert.State, alert.Value, alert.Message, alert.StartedAt,
+	).Scan(&alert.AlertID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+// resolve closes a firing alert and notifies the sinks
+func (e *Engine) resolve(ctx context.Context, rule models.AlertRule, alert *models.Alert, now time.Time) error {
+	res, err := e.db.ExecContext(ctx, `
+		UPDATE alerts SET state = $1, resolved_at = $2, value = $3, message = $4
+		WHERE alert_id = $5 AND state = $6`,
+		models.AlertResolved, now, alert.Value, alert.Message, alert.AlertID, models.AlertFiring)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	alert.State = models.AlertResolved
+	alert.ResolvedAt = &now
+	e.notify(ctx, rule, alert)
+	return nil
+}
+
+// notify delivers the alert to the sinks selected by the rule. Delivery
+// errors are logged; the alert state is already stored.
+func (e *Engine) notify(ctx context.Context, rule models.AlertRule, alert *models.Alert) {
+	for _, sink := range e.sinks {
+		if !selected(rule, sink.Name()) {
+			continue
+		}
+		sinkCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		if err := sink.Notify(sinkCtx, alert); err != nil {
+			log.Printf("alerting: %s notification for alert %d: %v", sink.Name(), alert.AlertID, err)
+		}
+		cancel()
+	}
+}
+
+func selected(rule models.AlertRule, sink string) bool {
+	if len(rule.Sinks) == 0 {
+		return true
+	}
+	for _, name := range rule.Sinks {
+		if name == sink {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) rules(ctx context.Context) ([]models.AlertRule, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT rule_id, name, kind, COALESCE(metric, ''), COALESCE(operator, ''),
+			COALESCE(threshold, 0), duration_sec, computer_id, severity, sinks
+		FROM alert_rules
+		WHERE enabled
+		ORDER BY rule_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.AlertRule
+	for rows.Next() {
+		rule := models.AlertRule{Enabled: true}
+		var computerID sql.NullInt64
+		err := rows.Scan(&rule.RuleID, &rule.Name, &rule.Kind, &rule.Metric, &rule.Operator,
+			&rule.Threshold, &rule.DurationSec, &computerID, &rule.Severity, pq.Array(&rule.Sinks))
+		if err != nil {
+			return nil, err
+		}
+		if computerID.Valid {
+			id := int(computerID.Int64)
+			rule.ComputerID = &id
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (e *Engine) firingAlerts(ctx context.Context, rule models.AlertRule) (map[alertKey]*models.Alert, error) {
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT a.alert_id, a.computer_id, c.host_name, a.series, COALESCE(a.value, 0),
+			a.message, a.started_at, a.ticket_id
+		FROM alerts a
+		JOIN computers c ON c.computer_id = a.computer_id
+		WHERE a.rule_id = $1 AND a.state = $2`,
+		rule.RuleID, models.AlertFiring)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	firing := make(map[alertKey]*models.Alert)
+	for rows.Next() {
+		alert := &models.Alert{
+			RuleID:   rule.RuleID,
+			RuleName: rule.Name,
+			Severity: rule.Severity,
+			State:    models.AlertFiring,
+		}
+		var ticketID sql.NullInt64
+		err := rows.Scan(&alert.AlertID, &alert.ComputerID, &alert.HostName, &alert.Series,
+			&alert.Value, &alert.Message, &alert.StartedAt, &ticketID)
+		if err != nil {
+			return nil, err
+		}
+		alert.StartedAt = database.WallClock(alert.StartedAt)
+		if ticketID.Valid {
+			id := int(ticketID.Int64)
+			alert.TicketID = &id
+		}
+		firing[alertKey{alert.ComputerID, alert.Series}] = alert
+	}
+	return firing, rows.Err()
+}
@@ -0,0 +1,70 @@
+package alerting
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config controls the evaluation period and the configured sinks
+type Config struct {
+	Interval time.Duration
+	Sinks    []Sink
+}
+
+// ConfigFromEnv reads the alerting settings:
+//
+//	ALERT_INTERVAL       evaluation period, default 1m
+//	ALERT_WEBHOOK_URL    enables the webhook sink
+//	SMTP_HOST, SMTP_PORT (default 25), SMTP_USERNAME, SMTP_PASSWORD,
+//	SMTP_FROM, ALERT_EMAIL_TO (comma-separated) enable the e-mail sink
+//	ALERT_TICKETS=true   enables tickets for firing alerts
+func ConfigFromEnv(db *sql.DB) (Config, error) {
+	cfg := Config{Interval: time.Minute}
+	if s := os.Getenv("ALERT_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < time.Second {
+			return Config{}, fmt.Errorf("invalid ALERT_INTERVAL %q: expected a duration such as 1m", s)
+		}
+		cfg.Interval = d
+	}
+
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		cfg.Sinks = append(cfg.Sinks, &WebhookSink{URL: url, Client: &http.Client{Timeout: notifyTimeout}})
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		sink := &SMTPSink{
+			Host:     host,
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+		if sink.Port == "" {
+			sink.Port = "25"
+		}
+		for _, to := range strings.Split(os.Getenv("ALERT_EMAIL_TO"), ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				sink.To = append(sink.To, to)
+			}
+		}
+		if sink.From == "" || len(sink.To) == 0 {
+			return Config{}, fmt.Errorf("SMTP_HOST is set, so SMTP_FROM and ALERT_EMAIL_TO are required")
+		}
+		cfg.Sinks = append(cfg.Sinks, sink)
+	}
+
+	switch strings.ToLower(os.Getenv("ALERT_TICKETS")) {
+	case "", "0", "false", "no":
+	case "1", "true", "yes":
+		cfg.Sinks = append(cfg.Sinks, &TicketSink{DB: db})
+	default:
+		return Config{}, fmt.Errorf("invalid ALERT_TICKETS %q: expected true or false", os.Getenv("ALERT_TICKETS"))
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,65 @@
+package alerting
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fakeRows is the result a fakeDB returns for a query
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+// fakeDB is a database/sql driver that hands every query with its
+// arguments to query and returns its rows. It stands in for PostgreSQL
+// where a test only needs the arguments and the scanning of the rows.
+type fakeDB struct {
+	query func(query string, args []driver.NamedValue) (fakeRows, error)
+}
+
+// openFakeDB opens a *sql.DB answering queries with query
+func openFakeDB(t *testing.T, query func(string, []driver.NamedValue) (fakeRows, error)) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(&fakeDB{query: query})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("fakedb: prepare") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("fakedb: begin") }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.db.query(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeCursor{rows: rows}, nil
+}
+
+type fakeCursor struct {
+	rows fakeRows
+	next int
+}
+
+func (r *fakeCursor) Columns() []string { return r.rows.columns }
+func (r *fakeCursor) Close() error      { return nil }
+
+func (r *fakeCursor) Next(dest []driver.Value) error {
+	if r.next == len(r.rows.values) {
+		return io.EOF
+	}
+	copy(dest, r.rows.values[r.next])
+	r.next++
+	return nil
+}
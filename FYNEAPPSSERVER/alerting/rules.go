@@ -0,0 +1,197 @@
+package alerting
+
+import (
+	"FYNEAPPSSERVER/api/database"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/retention"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// staleAfter is how long a series may stay silent before a threshold rule
+// stops evaluating it. Clients write unchanged values at least every
+// heartbeat (5m by default), so a longer silence means the device or the
+// computer is gone; a no_data rule reports the latter.
+const staleAfter = time.Hour
+
+// operators are the comparisons a threshold rule may use
+var operators = map[string]bool{">": true, ">=": true, "<": true, "<=": true}
+
+// result is the state of a rule on one computer and series
+type result struct {
+	ComputerID int
+	HostName   string
+	Series     string
+	Firing     bool
+	Value      float64
+}
+
+// ValidateRule checks a rule before it is stored
+func ValidateRule(rule *models.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if rule.DurationSec < 0 {
+		return fmt.Errorf("duration_sec must not be negative")
+	}
+	switch rule.Kind {
+	case models.AlertRuleThreshold:
+		if _, ok := retention.Metrics[rule.Metric]; !ok {
+			return fmt.Errorf("invalid metric %q: allowed %s", rule.Metric, retention.MetricNames())
+		}
+		if !operators[rule.Operator] {
+			return fmt.Errorf("invalid operator %q: allowed >, >=, <, <=", rule.Operator)
+		}
+	case models.AlertRuleNoData:
+		if rule.DurationSec == 0 {
+			return fmt.Errorf("duration_sec is required for %s rules", models.AlertRuleNoData)
+		}
+		rule.Metric, rule.Operator, rule.Threshold = "", "", 0
+	default:
+		return fmt.Errorf("invalid kind %q: allowed %s, %s", rule.Kind, models.AlertRuleThreshold, models.AlertRuleNoData)
+	}
+	switch rule.Severity {
+	case "":
+		rule.Severity = models.SeverityWarning
+	case models.SeverityWarning, models.SeverityCritical:
+	default:
+		return fmt.Errorf("invalid severity %q: allowed %s, %s", rule.Severity, models.SeverityWarning, models.SeverityCritical)
+	}
+	for _, name := range rule.Sinks {
+		if !knownSink(name) {
+			return fmt.Errorf("invalid sink %q: allowed %s, %s, %s", name, SinkWebhook, SinkEmail, SinkTicket)
+		}
+	}
+	return nil
+}
+
+func knownSink(name string) bool {
+	for _, known := range SinkNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate returns the current state of the rule for every computer and
+// series it applies to
+func evaluate(ctx context.Context, db *sql.DB, rule models.AlertRule, now time.Time) ([]result, error) {
+	if rule.Kind == models.AlertRuleNoData {
+		return evaluateNoData(ctx, db, rule, now)
+	}
+	return evaluateThreshold(ctx, db, rule, now)
+}
+
+// evaluateThreshold fires when every sample of the last DurationSec, and
+// the sample in effect at its start, satisfies the condition. Clients skip
+// unchanged samples, so the value in effect at the start of the window is
+// the last one written before it.
+func evaluateThreshold(ctx context.Context, db *sql.DB, rule models.AlertRule, now time.Time) ([]result, error) {
+	metric := retention.Metrics[rule.Metric]
+	if !operators[rule.Operator] {
+		return nil, fmt.Errorf("invalid operator %q", rule.Operator)
+	}
+	seriesExpr := "''"
+	if metric.SeriesColumn != "" {
+		seriesExpr = "COALESCE(" + metric.SeriesColumn + ", '')"
+	}
+
+	windowStart := now.Add(-time.Duration(rule.DurationSec) * time.Second)
+	query := fmt.Sprintf(`
+		WITH s AS (
+			SELECT computer_id, %[1]s AS series, %[2]s::float8 AS value, timestamp
+			FROM %[3]s
+			WHERE %[2]s IS NOT NULL AND computer_id IS NOT NULL
+				AND timestamp > $2 AND ($4::int IS NULL OR computer_id = $4)
+		),
+		w AS (
+			SELECT computer_id, series, value, timestamp FROM s WHERE timestamp > $1
+			UNION ALL
+			(SELECT DISTINCT ON (computer_id, series) computer_id, series, value, timestamp
+			FROM s WHERE timestamp <= $1
+			ORDER BY computer_id, series, timestamp DESC)
+		)
+		SELECT w.computer_id, c.host_name, w.series,
+			bool_and(w.value %[4]s $3) AND min(w.timestamp) <= $1,
+			(array_agg(w.value ORDER BY w.timestamp DESC))[1]
+		FROM w
+		JOIN computers c ON c.computer_id = w.computer_id
+		GROUP BY w.computer_id, c.host_name, w.series
+		HAVING max(w.timestamp) > $5`,
+		seriesExpr, metric.Column, metric.Table, rule.Operator)
+
+	rows, err := db.QueryContext(ctx, query,
+		windowStart, windowStart.Add(-staleAfter), rule.Threshold, rule.ComputerID, now.Add(-staleAfter))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []result
+	for rows.Next() {
+		var r result
+		if err := rows.Scan(&r.ComputerID, &r.HostName, &r.Series, &r.Firing, &r.Value); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// evaluateNoData fires for computers whose last processor or memory sample
+// is older than DurationSec. Computers that never sent telemetry are
+// skipped. Value is the silence in minutes.
+func evaluateNoData(ctx context.Context, db *sql.DB, rule models.AlertRule, now time.Time) ([]result, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.computer_id, c.host_name, seen.last_seen
+		FROM computers c
+		CROSS JOIN LATERAL (
+			SELECT GREATEST(
+				(SELECT max(timestamp) FROM processors p WHERE p.computer_id = c.computer_id),
+				(SELECT max(timestamp) FROM memory m WHERE m.computer_id = c.computer_id)
+			) AS last_seen
+		) seen
+		WHERE seen.last_seen IS NOT NULL AND ($1::int IS NULL OR c.computer_id = $1)`,
+		rule.ComputerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limit := time.Duration(rule.DurationSec) * time.Second
+	var results []result
+	for rows.Next() {
+		var (
+			r        result
+			lastSeen time.Time
+		)
+		if err := rows.Scan(&r.ComputerID, &r.HostName, &lastSeen); err != nil {
+			return nil, err
+		}
+		silence := now.Sub(database.WallClock(lastSeen))
+		r.Firing = silence >= limit
+		r.Value = silence.Minutes()
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// describe is the alert message for a result
+func describe(rule models.AlertRule, r result) string {
+	if rule.Kind == models.AlertRuleNoData {
+		return fmt.Sprintf("%s: no telemetry for %.0f min", rule.Name, r.Value)
+	}
+	subject := rule.Metric
+	if r.Series != "" {
+		subject += " " + r.Series
+	}
+	unit := retention.Metrics[rule.Metric].Unit
+	text := fmt.Sprintf("%s: %s = %.2f %s (%s %g)", rule.Name, subject, r.Value, unit, rule.Operator, rule.Threshold)
+	if rule.DurationSec > 0 {
+		text += fmt.Sprintf(" for %s", time.Duration(rule.DurationSec)*time.Second)
+	}
+	return text
+}
@@ -0,0 +1,225 @@
+package alerting
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRule(t *testing.T) {
+	threshold := func() models.AlertRule {
+		return models.AlertRule{Name: "disk", Kind: models.AlertRuleThreshold, Metric: "disk_usage", Operator: ">=", Threshold: 90}
+	}
+	tests := []struct {
+		name    string
+		edit    func(*models.AlertRule)
+		wantErr string
+	}{
+		{"threshold", func(r *models.AlertRule) {}, ""},
+		{"all sinks", func(r *models.AlertRule) { r.Sinks = SinkNames }, ""},
+		{"critical", func(r *models.AlertRule) { r.Severity = models.SeverityCritical }, ""},
+		{"no name", func(r *models.AlertRule) { r.Name = "" }, "name is required"},
+		{"negative duration", func(r *models.AlertRule) { r.DurationSec = -1 }, "duration_sec"},
+		{"unknown metric", func(r *models.AlertRule) { r.Metric = "gpu_usage" }, "invalid metric"},
+		{"unknown operator", func(r *models.AlertRule) { r.Operator = "=" }, "invalid operator"},
+		{"unknown kind", func(r *models.AlertRule) { r.Kind = "anomaly" }, "invalid kind"},
+		{"unknown severity", func(r *models.AlertRule) { r.Severity = "info" }, "invalid severity"},
+		{"unknown sink", func(r *models.AlertRule) { r.Sinks = []string{SinkEmail, "sms"} }, "invalid sink"},
+		{"no_data", func(r *models.AlertRule) { r.Kind, r.DurationSec = models.AlertRuleNoData, 600 }, ""},
+		{"no_data without duration", func(r *models.AlertRule) { r.Kind = models.AlertRuleNoData }, "duration_sec is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := threshold()
+			tt.edit(&rule)
+			err := ValidateRule(&rule)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("ValidateRule: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("ValidateRule succeeded, want error %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("ValidateRule: %v, want error %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRuleDefaults(t *testing.T) {
+	rule := models.AlertRule{Name: "cpu", Kind: models.AlertRuleThreshold, Metric: "cpu_usage", Operator: ">"}
+	if err := ValidateRule(&rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Severity != models.SeverityWarning {
+		t.Errorf("Severity = %q, want %q", rule.Severity, models.SeverityWarning)
+	}
+
+	rule = models.AlertRule{Name: "silent", Kind: models.AlertRuleNoData, Metric: "cpu_usage", Operator: ">", Threshold: 5, DurationSec: 600}
+	if err := ValidateRule(&rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Metric != "" || rule.Operator != "" || rule.Threshold != 0 {
+		t.Errorf("no_data rule kept metric %q, operator %q, threshold %g", rule.Metric, rule.Operator, rule.Threshold)
+	}
+}
+
+// TestEvaluateThresholdDuration checks that the "for" duration of a rule
+// sets the window whose samples must all match: the query fires only when
+// the condition holds for every sample after $1 and for the sample in
+// effect at $1.
+func TestEvaluateThresholdDuration(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		durationSec int
+		windowStart time.Time
+	}{
+		{0, now},
+		{300, now.Add(-5 * time.Minute)},
+		{3600, now.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		rule := models.AlertRule{Name: "disk", Kind: models.AlertRuleThreshold, Metric: "disk_usage",
+			Operator: ">=", Threshold: 90, DurationSec: tt.durationSec}
+
+		var (
+			gotQuery string
+			gotArgs  []driver.NamedValue
+		)
+		db := openFakeDB(t, func(query string, args []driver.NamedValue) (fakeRows, error) {
+			gotQuery, gotArgs = query, args
+			return fakeRows{
+				columns: []string{"computer_id", "host_name", "series", "firing", "value"},
+				values: [][]driver.Value{
+					{int64(7), "pc-7", "C:", true, 93.5},
+					{int64(7), "pc-7", "D:", false, 40.0},
+				},
+			}, nil
+		})
+
+		results, err := evaluate(context.Background(), db, rule, now)
+		if err != nil {
+			t.Fatalf("duration %ds: %v", tt.durationSec, err)
+		}
+
+		want := []any{tt.windowStart, tt.windowStart.Add(-staleAfter), 90.0, nil, now.Add(-staleAfter)}
+		if len(gotArgs) != len(want) {
+			t.Fatalf("duration %ds: %d query arguments, want %d", tt.durationSec, len(gotArgs), len(want))
+		}
+		for i, w := range want {
+			if wt, ok := w.(time.Time); ok {
+				if got, _ := gotArgs[i].Value.(time.Time); !got.Equal(wt) {
+					t.Errorf("duration %ds: $%d = %v, want %v", tt.durationSec, i+1, gotArgs[i].Value, wt)
+				}
+			} else if gotArgs[i].Value != w {
+				t.Errorf("duration %ds: $%d = %v, want %v", tt.durationSec, i+1, gotArgs[i].Value, w)
+			}
+		}
+		for _, part := range []string{"FROM disks", "COALESCE(drive_letter, '')", "w.value >= $3", "min(w.timestamp) <= $1"} {
+			if !strings.Contains(gotQuery, part) {
+				t.Errorf("duration %ds: query does not contain %q", tt.durationSec, part)
+			}
+		}
+
+		wantResults := []result{
+			{ComputerID: 7, HostName: "pc-7", Series: "C:", Firing: true, Value: 93.5},
+			{ComputerID: 7, HostName: "pc-7", Series: "D:", Firing: false, Value: 40},
+		}
+		if len(results) != len(wantResults) {
+			t.Fatalf("duration %ds: %d results, want %d", tt.durationSec, len(results), len(wantResults))
+		}
+		for i := range wantResults {
+			if results[i] != wantResults[i] {
+				t.Errorf("duration %ds: result %d = %+v, want %+v", tt.durationSec, i, results[i], wantResults[i])
+			}
+		}
+	}
+}
+
+func TestEvaluateThresholdRejectsOperator(t *testing.T) {
+	db := openFakeDB(t, func(string, []driver.NamedValue) (fakeRows, error) {
+		t.Fatal("query ran with an invalid operator")
+		return fakeRows{}, nil
+	})
+	rule := models.AlertRule{Kind: models.AlertRuleThreshold, Metric: "cpu_usage", Operator: "> 0 OR true --"}
+	if _, err := evaluate(context.Background(), db, rule, time.Now()); err == nil {
+		t.Fatal("evaluate accepted an invalid operator")
+	}
+}
+
+func TestEvaluateNoData(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	// stored returns t as lib/pq returns a TIMESTAMP column: the wall
+	// clock in UTC
+	stored := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	}
+
+	computerID := 3
+	rule := models.AlertRule{Name: "silent", Kind: models.AlertRuleNoData, DurationSec: 600, ComputerID: &computerID}
+	var gotArgs []driver.NamedValue
+	db := openFakeDB(t, func(query string, args []driver.NamedValue) (fakeRows, error) {
+		gotArgs = args
+		return fakeRows{
+			columns: []string{"computer_id", "host_name", "last_seen"},
+			values: [][]driver.Value{
+				{int64(1), "pc-1", stored(now.Add(-20 * time.Minute))},
+				{int64(2), "pc-2", stored(now.Add(-5 * time.Minute))},
+				{int64(3), "pc-3", stored(now.Add(-10 * time.Minute))},
+			},
+		}, nil
+	})
+
+	results, err := evaluate(context.Background(), db, rule, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotArgs) != 1 || gotArgs[0].Value != int64(computerID) {
+		t.Errorf("query arguments %v, want the computer ID %d", gotArgs, computerID)
+	}
+
+	want := []result{
+		{ComputerID: 1, HostName: "pc-1", Firing: true, Value: 20},
+		{ComputerID: 2, HostName: "pc-2", Firing: false, Value: 5},
+		{ComputerID: 3, HostName: "pc-3", Firing: true, Value: 10},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("%d results, want %d", len(results), len(want))
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		rule models.AlertRule
+		r    result
+		want string
+	}{
+		{
+			models.AlertRule{Name: "disk", Kind: models.AlertRuleThreshold, Metric: "disk_usage", Operator: ">=", Threshold: 90, DurationSec: 300},
+			result{Series: "C:", Value: 93.5},
+			"disk: disk_usage C: = 93.50 % (>= 90) for 5m0s",
+		},
+		{
+			models.AlertRule{Name: "cpu", Kind: models.AlertRuleThreshold, Metric: "cpu_usage", Operator: ">", Threshold: 95},
+			result{Value: 99},
+			"cpu: cpu_usage = 99.00 % (> 95)",
+		},
+		{
+			models.AlertRule{Name: "silent", Kind: models.AlertRuleNoData, DurationSec: 600},
+			result{Value: 20.4},
+			"silent: no telemetry for 20 min",
+		},
+	}
+	for _, tt := range tests {
+		if got := describe(tt.rule, tt.r); got != tt.want {
+			t.Errorf("describe = %q, want %q", got, tt.want)
+		}
+	}
+}
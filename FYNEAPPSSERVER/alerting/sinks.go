@@ -0,0 +1,194 @@
+package alerting
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Sink names accepted in alert_rules.sinks
+const (
+	SinkWebhook = "webhook"
+	SinkEmail   = "email"
+	SinkTicket  = "ticket"
+)
+
+// SinkNames lists every sink a rule may name
+var SinkNames = []string{SinkWebhook, SinkEmail, SinkTicket}
+
+// Sink delivers alert state changes. Notify is called once when an alert
+// starts firing and once when it resolves.
+type Sink interface {
+	Name() string
+	Notify(ctx context.Context, alert *models.Alert) error
+}
+
+// WebhookSink posts the alert as JSON to URL
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *WebhookSink) Name() string { return SinkWebhook }
+
+func (s *WebhookSink) Notify(ctx context.Context, alert *models.Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPSink mails the alert to To. STARTTLS is used when the server offers
+// it; Username enables PLAIN authentication.
+type SMTPSink struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (s *SMTPSink) Name() string { return SinkEmail }
+
+func (s *SMTPSink) Notify(ctx context.Context, alert *models.Alert) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(s.Host, s.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(alert)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTPSink) message(alert *models.Alert) []byte {
+	subject := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(alert.State), alert.RuleName, alert.HostName)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(alertText(alert), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// TicketSink opens a ticket when an alert fires and notes the resolution
+// in the same ticket
+type TicketSink struct {
+	DB *sql.DB
+}
+
+func (s *TicketSink) Name() string { return SinkTicket }
+
+func (s *TicketSink) Notify(ctx context.Context, alert *models.Alert) error {
+	if alert.State == models.AlertResolved {
+		if alert.TicketID == nil {
+			return nil
+		}
+		_, err := s.DB.ExecContext(ctx, `
+			UPDATE tickets SET
+				description = COALESCE(description, '') || $1,
+				update_at = $2
+			WHERE id = $3`,
+			"\n\n"+alertText(alert), time.Now(), *alert.TicketID)
+		return err
+	}
+
+	var id int
+	err := s.DB.QueryRowContext(ctx, `
+		INSERT INTO tickets (title, description, user_id, computer_name, status_id, cabinet, created_at)
+		VALUES ($1, $2, $3, $4, 1, 0, $5)
+		RETURNING id`,
+		fmt.Sprintf("%s: %s", alert.RuleName, alert.HostName), alertText(alert), "alerting",
+		alert.HostName, time.Now(),
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+	alert.TicketID = &id
+	_, err = s.DB.ExecContext(ctx, "UPDATE alerts SET ticket_id = $1 WHERE alert_id = $2", id, alert.AlertID)
+	return err
+}
+
+// alertText is the plain-text body shared by e-mail and tickets
+func alertText(alert *models.Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)\n", alert.Message, alert.Severity)
+	fmt.Fprintf(&b, "Computer: %s (id %d)\n", alert.HostName, alert.ComputerID)
+	fmt.Fprintf(&b, "Started: %s", alert.StartedAt.Format("2006-01-02 15:04:05"))
+	if alert.ResolvedAt != nil {
+		fmt.Fprintf(&b, "\nResolved: %s", alert.ResolvedAt.Format("2006-01-02 15:04:05"))
+	}
+	return b.String()
+}
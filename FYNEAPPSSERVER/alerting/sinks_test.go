@@ -0,0 +1,192 @@
+package alerting
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession is what a fakeSMTP server received in one session
+type smtpSession struct {
+	commands []string
+	data     string
+}
+
+// fakeSMTP accepts one session on a local listener. It advertises AUTH
+// PLAIN but not STARTTLS and answers reject to the commands that start
+// with it.
+func fakeSMTP(t *testing.T, reject string) (host, port string, done <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan smtpSession, 1)
+	go func() {
+		var s smtpSession
+		defer func() { ch <- s }()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(lines ...string) {
+			conn.Write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+		}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			s.commands = append(s.commands, cmd)
+			switch verb := strings.ToUpper(strings.Fields(cmd + " x")[0]); {
+			case reject != "" && strings.HasPrefix(cmd, reject):
+				reply("550 rejected")
+			case verb == "EHLO":
+				reply("250-localhost", "250-8BITMIME", "250 AUTH PLAIN")
+			case verb == "AUTH":
+				reply("235 authenticated")
+			case verb == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				s.data = data.String()
+				reply("250 queued")
+			case verb == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, ch
+}
+
+func testAlert() *models.Alert {
+	return &models.Alert{
+		AlertID:    12,
+		RuleName:   "Диск",
+		Severity:   models.SeverityCritical,
+		ComputerID: 7,
+		HostName:   "pc-7",
+		State:      models.AlertFiring,
+		Message:    "Диск: disk_usage C: = 93.50 % (>= 90)",
+		StartedAt:  time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local),
+	}
+}
+
+func TestSMTPSinkNotify(t *testing.T) {
+	host, port, done := fakeSMTP(t, "")
+	sink := &SMTPSink{
+		Host:     host,
+		Port:     port,
+		Username: "alerts",
+		Password: "secret",
+		From:     "monitor@example.org",
+		To:       []string{"admin@example.org", "duty@example.org"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sink.Notify(ctx, testAlert()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	s := <-done
+
+	auth := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00alerts\x00secret"))
+	want := []string{
+		"EHLO localhost",
+		auth,
+		"MAIL FROM:<monitor@example.org> BODY=8BITMIME",
+		"RCPT TO:<admin@example.org>",
+		"RCPT TO:<duty@example.org>",
+		"DATA",
+		"QUIT",
+	}
+	if strings.Join(s.commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(s.commands, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, part := range []string{
+		"From: monitor@example.org\r\n",
+		"To: admin@example.org, duty@example.org\r\n",
+		"Subject: =?utf-8?q?[FIRING]_",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nДиск: disk_usage C: = 93.50 % (>= 90) (critical)\r\nComputer: pc-7 (id 7)\r\nStarted: 2026-10-17 12:00:00\r\n",
+	} {
+		if !strings.Contains(s.data, part) {
+			t.Errorf("message does not contain %q:\n%s", part, s.data)
+		}
+	}
+}
+
+func TestSMTPSinkRejected(t *testing.T) {
+	host, port, done := fakeSMTP(t, "RCPT TO:<duty@")
+	sink := &SMTPSink{Host: host, Port: port, From: "monitor@example.org", To: []string{"admin@example.org", "duty@example.org"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := sink.Notify(ctx, testAlert())
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("Notify: %v, want the 550 reply", err)
+	}
+	if s := <-done; s.data != "" {
+		t.Errorf("message sent after a rejected recipient:\n%s", s.data)
+	}
+}
+
+func TestWebhookSinkNotify(t *testing.T) {
+	var got models.Alert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	if err := (&WebhookSink{URL: srv.URL}).Notify(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got.AlertID != 12 || got.HostName != "pc-7" || got.State != models.AlertFiring {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if err := (&WebhookSink{URL: failing.URL}).Notify(context.Background(), testAlert()); err == nil {
+		t.Error("Notify succeeded on a 502 reply")
+	}
+}
@@ -0,0 +1,9 @@
+package database
+
+import "time"
+
+// WallClock reattaches the local zone to a TIMESTAMP value. Timestamps are
+// stored as local time without a zone and lib/pq returns them as UTC
+func WallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
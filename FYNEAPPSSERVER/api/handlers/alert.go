@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/alerting"
+	"FYNEAPPSSERVER/api/models"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+type AlertHandler struct {
+	DB *sql.DB
+}
+
+func NewAlertHandler(db *sql.DB) *AlertHandler {
+	return &AlertHandler{DB: db}
+}
+
+const alertRuleColumns = `rule_id, name, kind, COALESCE(metric, ''), COALESCE(operator, ''),
+	COALESCE(threshold, 0), duration_sec, computer_id, severity, sinks, enabled, created_at`
+
+var alertRuleList = listSpec{
+	From:     "alert_rules",
+	Columns:  alertRuleColumns,
+	IDColumn: "rule_id",
+	Filters:  map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":   "rule_id",
+		"name": "name",
+	},
+	DefaultSort: "id",
+}
+
+func scanAlertRule(row rowScanner) (models.AlertRule, error) {
+	var (
+		rule       models.AlertRule
+		computerID sql.NullInt64
+	)
+	err := row.Scan(
+		&rule.RuleID,
+		&rule.Name,
+		&rule.Kind,
+		&rule.Metric,
+		&rule.Operator,
+		&rule.Threshold,
+		&rule.DurationSec,
+		&computerID,
+		&rule.Severity,
+		pq.Array(&rule.Sinks),
+		&rule.Enabled,
+		&rule.CreatedAt,
+	)
+	if computerID.Valid {
+		id := int(computerID.Int64)
+		rule.ComputerID = &id
+	}
+	if rule.Sinks == nil {
+		rule.Sinks = []string{}
+	}
+	return rule, err
+}
+
+const alertColumns = `a.alert_id, a.rule_id, r.name, r.severity, a.computer_id, c.host_name,
+	a.series, a.state, COALESCE(a.value, 0), a.message, a.started_at, a.resolved_at, a.ticket_id`
+
+var alertList = listSpec{
+	From: `alerts a
+		JOIN alert_rules r ON r.rule_id = a.rule_id
+		JOIN computers c ON c.computer_id = a.computer_id`,
+	Columns:    alertColumns,
+	IDColumn:   "a.alert_id",
+	TimeColumn: "a.started_at",
+	Filters: map[string]string{
+		"computer_id": "a.computer_id = %s",
+		"rule_id":     "a.rule_id = %s",
+	},
+	Sorts: map[string]string{
+		"id":         "a.alert_id",
+		"started_at": "a.started_at",
+	},
+	DefaultSort: "-started_at",
+}
+
+// activeAlertList is alertList restricted to firing alerts
+var activeAlertList = func() listSpec {
+	spec := alertList
+	spec.Where = "a.state = '" + models.AlertFiring + "'"
+	return spec
+}()
+
+func scanAlert(row rowScanner) (models.Alert, error) {
+	var (
+		alert    models.Alert
+		ticketID sql.NullInt64
+	)
+	err := row.Scan(
+		&alert.AlertID,
+		&alert.RuleID,
+		&alert.RuleName,
+		&alert.Severity,
+		&alert.ComputerID,
+		&alert.HostName,
+		&alert.Series,
+		&alert.State,
+		&alert.Value,
+		&alert.Message,
+		&alert.StartedAt,
+		&alert.ResolvedAt,
+		&ticketID,
+	)
+	if ticketID.Valid {
+		id := int(ticketID.Int64)
+		alert.TicketID = &id
+	}
+	return alert, err
+}
+
+func (h *AlertHandler) GetAlertRules(c echo.Context) error {
+	return listRows(c, h.DB, alertRuleList, scanAlertRule)
+}
+
+func (h *AlertHandler) GetAlertRule(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	rule, err := scanAlertRule(h.DB.QueryRow("SELECT "+alertRuleColumns+" FROM alert_rules WHERE rule_id = $1", id))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, "Alert rule not found")
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// CreateAlertRule stores a rule; the engine picks it up on its next pass.
+// Example body:
+//
+//	{"name": "Disk almost full", "kind": "threshold", "metric": "disk_usage",
+//	 "operator": ">", "threshold": 90, "duration_sec": 600, "sinks": ["email", "ticket"]}
+func (h *AlertHandler) CreateAlertRule(c echo.Context) error {
+	rule := models.AlertRule{Enabled: true}
+	if err := c.Bind(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	rule.Name = strings.TrimSpace(rule.Name)
+	if err := alerting.ValidateRule(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if rule.Sinks == nil {
+		rule.Sinks = []string{}
+	}
+
+	err := h.DB.QueryRow(`
+		INSERT INTO alert_rules (name, kind, metric, operator, threshold, duration_sec, computer_id, severity, sinks, enabled)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10)
+		RETURNING rule_id, created_at`,
+		rule.Name, rule.Kind, rule.Metric, rule.Operator, rule.Threshold, rule.DurationSec,
+		rule.ComputerID, rule.Severity, pq.Array(rule.Sinks), rule.Enabled,
+	).Scan(&rule.RuleID, &rule.CreatedAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateAlertRule replaces a rule. Alerts already firing stay open until
+// the changed rule resolves them.
+func (h *AlertHandler) UpdateAlertRule(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var rule models.AlertRule
+	if err := c.Bind(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	rule.Name = strings.TrimSpace(rule.Name)
+	if err := alerting.ValidateRule(&rule); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if rule.Sinks == nil {
+		rule.Sinks = []string{}
+	}
+
+	err = h.DB.QueryRow(`
+		UPDATE alert_rules SET
+			name = $1,
+			kind = $2,
+			metric = NULLIF($3, ''),
+			operator = NULLIF($4, ''),
+			threshold = $5,
+			duration_sec = $6,
+			computer_id = $7,
+			severity = $8,
+			sinks = $9,
+			enabled = $10
+		WHERE rule_id = $11
+		RETURNING created_at`,
+		rule.Name, rule.Kind, rule.Metric, rule.Operator, rule.Threshold, rule.DurationSec,
+		rule.ComputerID, rule.Severity, pq.Array(rule.Sinks), rule.Enabled, id,
+	).Scan(&rule.CreatedAt)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, "Alert rule not found")
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	rule.RuleID = id
+	return c.JSON(http.StatusOK, rule)
+}
+
+// DeleteAlertRule removes a rule together with its alert history
+func (h *AlertHandler) DeleteAlertRule(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	res, err := h.DB.Exec("DELETE FROM alert_rules WHERE rule_id = $1", id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, "Alert rule not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetAlerts returns the alert history, newest first
+func (h *AlertHandler) GetAlerts(c echo.Context) error {
+	return listRows(c, h.DB, alertList, scanAlert)
+}
+
+// GetActiveAlerts returns the alerts that are firing now
+func (h *AlertHandler) GetActiveAlerts(c echo.Context) error {
+	return listRows(c, h.DB, activeAlertList, scanAlert)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/auth"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+type APIKeyHandler struct {
+	DB *sql.DB
+}
+
+func NewAPIKeyHandler(db *sql.DB) *APIKeyHandler {
+	return &APIKeyHandler{DB: db}
+}
+
+var apiKeyList = listSpec{
+	From:       "api_keys",
+	Columns:    "id, name, prefix, scopes, created_at, last_used_at, revoked_at",
+	IDColumn:   "id",
+	TimeColumn: "created_at",
+	Sorts: map[string]string{
+		"id":           "id",
+		"name":         "name",
+		"created_at":   "created_at",
+		"last_used_at": "last_used_at",
+	},
+	DefaultSort: "-created_at",
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	return key, err
+}
+
+func (h *APIKeyHandler) GetAPIKeys(c echo.Context) error {
+	return listRows(c, h.DB, apiKeyList, scanAPIKey)
+}
+
+// CreateAPIKey issues a scoped key for a headless agent. The plain key is
+// returned only in this response; the server keeps just its hash.
+// Example body: {"name": "lab-204", "scopes": ["telemetry:write"]}
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, "Name is required")
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{string(auth.PermTelemetryWrite)}
+	}
+	if _, err := auth.ParseScopes(req.Scopes); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	plain, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	key := models.APIKey{Name: req.Name, Prefix: prefix, Scopes: req.Scopes, Key: plain}
+	err = h.DB.QueryRow(`
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		key.Name, key.Prefix, hash, pq.Array(key.Scopes), CurrentPrincipal(c).UserID,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAPIKey disables a key; the row is kept for auditing.
+func (h *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	res, err := h.DB.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, "API key not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/auth"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuthHandler struct {
+	DB *sql.DB
+}
+
+func NewAuthHandler(db *sql.DB) *AuthHandler {
+	return &AuthHandler{DB: db}
+}
+
+// Login checks the credentials and issues an opaque bearer token backed by
+// user_sessions. Legacy plaintext passwords are re-hashed on success.
+func (h *AuthHandler) Login(c echo.Context) error {
+	var req models.LoginRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if req.Username == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, "Username and password are required")
+	}
+
+	var user models.User
+	var stored string
+	err := h.DB.QueryRow(`
+		SELECT id, username, COALESCE(full_name, ''), role, must_change_password, created_at,
+			COALESCE(password, '')
+		FROM users WHERE username = $1`,
+		req.Username,
+	).Scan(&user.ID, &user.Username, &user.FullName, &user.Role, &user.MustChangePassword, &user.CreatedAt, &stored)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusUnauthorized, "Invalid username or password")
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	ok, needsRehash, err := auth.CheckPassword(stored, req.Password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, "Invalid username or password")
+	}
+	if needsRehash {
+		if err := auth.UpgradeLegacyPassword(h.DB, user.ID, stored, req.Password); err != nil {
+			log.Printf("password rehash for %s failed: %v", user.Username, err)
+		}
+	}
+
+	token, hash, err := auth.NewSessionToken()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	expiresAt := time.Now().Add(auth.SessionTTL)
+
+	_, err = h.DB.Exec(
+		"INSERT INTO user_sessions (user_id, session_id, expires_at, last_seen_at) VALUES ($1, $2, $3, NOW())",
+		user.ID, hash, expiresAt,
+	)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	// Expired sessions are cleaned up opportunistically on login
+	h.DB.Exec("DELETE FROM user_sessions WHERE expires_at < NOW()")
+
+	return c.JSON(http.StatusOK, models.LoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      user,
+	})
+}
+
+// Logout revokes the session used for the request.
+func (h *AuthHandler) Logout(c echo.Context) error {
+	p := CurrentPrincipal(c)
+
+	if _, err := h.DB.Exec("DELETE FROM user_sessions WHERE session_id = $1", p.tokenHash); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Me returns the user behind the token; clients use it to restore a saved
+// session.
+func (h *AuthHandler) Me(c echo.Context) error {
+	p := CurrentPrincipal(c)
+
+	var user models.User
+	err := h.DB.QueryRow(`
+		SELECT id, username, COALESCE(full_name, ''), role, must_change_password, created_at
+		FROM users WHERE id = $1`,
+		p.UserID,
+	).Scan(&user.ID, &user.Username, &user.FullName, &user.Role, &user.MustChangePassword, &user.CreatedAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword replaces the caller's password after checking the current
+// one.
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	p := CurrentPrincipal(c)
+
+	var req models.ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	var stored string
+	if err := h.DB.QueryRow("SELECT COALESCE(password, '') FROM users WHERE id = $1", p.UserID).Scan(&stored); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	ok, _, err := auth.CheckPassword(stored, req.CurrentPassword)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if !ok {
+		return c.JSON(http.StatusBadRequest, "Current password is incorrect")
+	}
+	if req.CurrentPassword == req.NewPassword {
+		return c.JSON(http.StatusBadRequest, "New password must differ from the current one")
+	}
+	if err := auth.ValidatePassword(req.NewPassword); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := auth.SetPassword(h.DB, p.UserID, req.NewPassword, false); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/ingest"
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -17,50 +19,30 @@ func NewComputerHandler(db *sql.DB) *ComputerHandler {
 	return &ComputerHandler{DB: db}
 }
 
-func (h *ComputerHandler) GetComputers(c echo.Context) error {
-	rows, err := h.DB.Query("SELECT * FROM computers")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var computers []models.Computer
-	for rows.Next() {
-		var computer models.Computer
-		err := rows.Scan(
-			&computer.ComputerID,
-			&computer.HostName,
-			&computer.UserName,
-			&computer.OsName,
-			&computer.OsVersion,
-			&computer.OsPlatform,
-			&computer.OsArchitecture,
-			&computer.KernelVersion,
-			&computer.Uptime,
-			&computer.ProcessCount,
-			&computer.BootTime,
-			&computer.HomeDirectory,
-			&computer.Gid,
-			&computer.Uid,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		computers = append(computers, computer)
-	}
-
-	return c.JSON(http.StatusOK, computers)
+const computerColumns = `computer_id, COALESCE(machine_uuid::text, ''), host_name, COALESCE(user_name, ''), os_name, os_version,
+	COALESCE(os_platform, ''), COALESCE(os_architecture, ''), COALESCE(kernel_version, ''),
+	COALESCE(uptime, 'epoch'), COALESCE(process_count, 0), COALESCE(boot_time, 'epoch'),
+	COALESCE(home_directory, ''), COALESCE(gid, ''), COALESCE(uid, '')`
+
+var computerList = listSpec{
+	From:     "computers",
+	Columns:  computerColumns,
+	IDColumn: "computer_id",
+	Filters:  map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":        "computer_id",
+		"host_name": "host_name",
+		"os_name":   "os_name",
+		"boot_time": "boot_time",
+	},
+	DefaultSort: "id",
 }
 
-func (h *ComputerHandler) GetComputer(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid ID")
-	}
-
+func scanComputer(row rowScanner) (models.Computer, error) {
 	var computer models.Computer
-	err = h.DB.QueryRow("SELECT * FROM computers WHERE computer_id = $1", id).Scan(
+	err := row.Scan(
 		&computer.ComputerID,
+		&computer.MachineUUID,
 		&computer.HostName,
 		&computer.UserName,
 		&computer.OsName,
@@ -75,6 +57,20 @@ func (h *ComputerHandler) GetComputer(c echo.Context) error {
 		&computer.Gid,
 		&computer.Uid,
 	)
+	return computer, err
+}
+
+func (h *ComputerHandler) GetComputers(c echo.Context) error {
+	return listRows(c, h.DB, computerList, scanComputer)
+}
+
+func (h *ComputerHandler) GetComputer(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	computer, err := scanComputer(h.DB.QueryRow("SELECT "+computerColumns+" FROM computers WHERE computer_id = $1", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.JSON(http.StatusNotFound, "Computer not found")
@@ -85,40 +81,73 @@ func (h *ComputerHandler) GetComputer(c echo.Context) error {
 	return c.JSON(http.StatusOK, computer)
 }
 
+// CreateComputer registers a computer. A computer that is already known by
+// its machine_uuid is updated instead and 200 is returned, so clients can
+// register on every start; a new one returns 201.
 func (h *ComputerHandler) CreateComputer(c echo.Context) error {
 	var computer models.Computer
 	if err := c.Bind(&computer); err != nil {
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
-	err := h.DB.QueryRow(
-		`INSERT INTO computers (
-			host_name, user_name, os_name, os_version, os_platform, 
-			os_architecture, kernel_version, uptime, process_count, 
-			boot_time, home_directory, gid, uid
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-		RETURNING computer_id`,
-		computer.HostName,
-		computer.UserName,
-		computer.OsName,
-		computer.OsVersion,
-		computer.OsPlatform,
-		computer.OsArchitecture,
-		computer.KernelVersion,
-		computer.Uptime,
-		computer.ProcessCount,
-		computer.BootTime,
-		computer.HomeDirectory,
-		computer.Gid,
-		computer.Uid,
-	).Scan(&computer.ComputerID)
+	id, created, err := ingest.RegisterComputer(c.Request().Context(), h.DB, computer)
 	if err != nil {
+		var invalid *ingest.ValidationError
+		if errors.As(err, &invalid) {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
+	computer.ComputerID = id
 
+	if !created {
+		return c.JSON(http.StatusOK, computer)
+	}
 	return c.JSON(http.StatusCreated, computer)
 }
 
+// GetComputerHostNames returns the host names the computer has reported,
+// most recent first
+func (h *ComputerHandler) GetComputerHostNames(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT computer_id, host_name, first_seen, last_seen
+		FROM computer_hostnames
+		WHERE computer_id = $1
+		ORDER BY last_seen DESC`, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	names := []models.ComputerHostName{}
+	for rows.Next() {
+		var name models.ComputerHostName
+		if err := rows.Scan(&name.ComputerID, &name.HostName, &name.FirstSeen, &name.LastSeen); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if len(names) == 0 {
+		var exists bool
+		if err := h.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM computers WHERE computer_id = $1)", id).Scan(&exists); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		if !exists {
+			return c.JSON(http.StatusNotFound, "Computer not found")
+		}
+	}
+
+	return c.JSON(http.StatusOK, names)
+}
+
 func (h *ComputerHandler) UpdateComputer(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
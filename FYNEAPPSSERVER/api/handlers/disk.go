@@ -17,43 +17,28 @@ func NewDiskHandler(db *sql.DB) *DiskHandler {
 	return &DiskHandler{DB: db}
 }
 
-func (h *DiskHandler) GetDisks(c echo.Context) error {
-	rows, err := h.DB.Query("SELECT * FROM disks")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var disks []models.Disk
-	for rows.Next() {
-		var disk models.Disk
-		err := rows.Scan(
-			&disk.DiskID,
-			&disk.ComputerID,
-			&disk.DriveLetter,
-			&disk.TotalSpaceGB,
-			&disk.UsedSpaceGB,
-			&disk.FreeSpaceGB,
-			&disk.UsagePercent,
-			&disk.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		disks = append(disks, disk)
-	}
-
-	return c.JSON(http.StatusOK, disks)
+const diskColumns = `disk_id, COALESCE(computer_id, 0), drive_letter, COALESCE(total_space_gb, 0),
+	COALESCE(used_space_gb, 0), COALESCE(free_space_gb, 0), COALESCE(usage_percent, 0), timestamp`
+
+var diskList = listSpec{
+	From:       "disks",
+	Columns:    diskColumns,
+	IDColumn:   "disk_id",
+	TimeColumn: "timestamp",
+	Filters:    map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":            "disk_id",
+		"computer_id":   "computer_id",
+		"drive_letter":  "drive_letter",
+		"usage_percent": "usage_percent",
+		"timestamp":     "timestamp",
+	},
+	DefaultSort: "-timestamp",
 }
 
-func (h *DiskHandler) GetDisk(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid ID")
-	}
-
+func scanDisk(row rowScanner) (models.Disk, error) {
 	var disk models.Disk
-	err = h.DB.QueryRow("SELECT * FROM disks WHERE disk_id = $1", id).Scan(
+	err := row.Scan(
 		&disk.DiskID,
 		&disk.ComputerID,
 		&disk.DriveLetter,
@@ -63,6 +48,20 @@ func (h *DiskHandler) GetDisk(c echo.Context) error {
 		&disk.UsagePercent,
 		&disk.Timestamp,
 	)
+	return disk, err
+}
+
+func (h *DiskHandler) GetDisks(c echo.Context) error {
+	return listRows(c, h.DB, diskList, scanDisk)
+}
+
+func (h *DiskHandler) GetDisk(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	disk, err := scanDisk(h.DB.QueryRow("SELECT "+diskColumns+" FROM disks WHERE disk_id = $1", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.JSON(http.StatusNotFound, "Disk not found")
@@ -79,32 +78,7 @@ func (h *DiskHandler) GetDisksByComputer(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "Invalid Computer ID")
 	}
 
-	rows, err := h.DB.Query("SELECT * FROM disks WHERE computer_id = $1", computerID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var disks []models.Disk
-	for rows.Next() {
-		var disk models.Disk
-		err := rows.Scan(
-			&disk.DiskID,
-			&disk.ComputerID,
-			&disk.DriveLetter,
-			&disk.TotalSpaceGB,
-			&disk.UsedSpaceGB,
-			&disk.FreeSpaceGB,
-			&disk.UsagePercent,
-			&disk.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		disks = append(disks, disk)
-	}
-
-	return c.JSON(http.StatusOK, disks)
+	return listRows(c, h.DB, diskList, scanDisk, fixedFilter{"computer_id", computerID})
 }
 
 func (h *DiskHandler) CreateDisk(c echo.Context) error {
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HardwareChangeHandler struct {
+	DB *sql.DB
+}
+
+func NewHardwareChangeHandler(db *sql.DB) *HardwareChangeHandler {
+	return &HardwareChangeHandler{DB: db}
+}
+
+const hardwareChangeColumns = `change_id, computer_id, detected_at, component, change,
+	COALESCE(old_value, ''), COALESCE(new_value, '')`
+
+var hardwareChangeList = listSpec{
+	From:       "hardware_changes",
+	Columns:    hardwareChangeColumns,
+	IDColumn:   "change_id",
+	TimeColumn: "detected_at",
+	Filters:    map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":          "change_id",
+		"detected_at": "detected_at",
+		"component":   "component",
+	},
+	DefaultSort: "-detected_at",
+}
+
+func scanHardwareChange(row rowScanner) (models.HardwareChange, error) {
+	var change models.HardwareChange
+	err := row.Scan(
+		&change.ChangeID,
+		&change.ComputerID,
+		&change.DetectedAt,
+		&change.Component,
+		&change.Change,
+		&change.OldValue,
+		&change.NewValue,
+	)
+	return change, err
+}
+
+// GetComputerChanges returns the hardware changes detected on the computer,
+// newest first
+func (h *HardwareChangeHandler) GetComputerChanges(c echo.Context) error {
+	computerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid Computer ID")
+	}
+
+	return listRows(c, h.DB, hardwareChangeList, scanHardwareChange, fixedFilter{"computer_id", computerID})
+}
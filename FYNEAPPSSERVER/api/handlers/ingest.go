@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/ingest"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type IngestHandler struct {
+	DB *sql.DB
+}
+
+func NewIngestHandler(db *sql.DB) *IngestHandler {
+	return &IngestHandler{DB: db}
+}
+
+// Ingest stores a batch of telemetry samples from one computer. A batch
+// that has already been stored returns 200 with "duplicate": true, a new
+// one returns 201.
+func (h *IngestHandler) Ingest(c echo.Context) error {
+	var batch models.IngestBatch
+	if err := c.Bind(&batch); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	result, err := ingest.Write(c.Request().Context(), h.DB, &batch)
+	if err != nil {
+		var invalid *ingest.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			return c.JSON(http.StatusBadRequest, err.Error())
+		case errors.Is(err, ingest.ErrUnknownComputer):
+			return c.JSON(http.StatusNotFound, "Computer not found")
+		}
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	if result.Duplicate {
+		return c.JSON(http.StatusOK, result)
+	}
+	return c.JSON(http.StatusCreated, result)
+}
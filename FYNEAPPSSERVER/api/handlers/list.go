@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Pagination limits shared by every list endpoint
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// listSpec describes how a collection endpoint maps query parameters to SQL.
+//
+// Every list endpoint accepts:
+//
+//	?limit=100&offset=0      page size (max 1000) and offset
+//	?sort=field or -field    one of Sorts, "-" for descending
+//	?from=...&to=...         RFC 3339 or YYYY-MM-DD bounds on TimeColumn
+//	?computer_id=1 ...       integer filters listed in Filters
+type listSpec struct {
+	// From is the FROM clause, optionally with joins
+	From string
+	// Where is a fixed condition added to every query, optional
+	Where string
+	// Columns is the explicit select list matching the scan function
+	Columns string
+	// IDColumn breaks ties so that pages are stable
+	IDColumn string
+	// TimeColumn is filtered by from/to; empty disables the filter
+	TimeColumn string
+	// Filters maps query parameters to conditions with one %s placeholder
+	Filters map[string]string
+	// Sorts maps public sort names to columns
+	Sorts map[string]string
+	// DefaultSort is used when ?sort= is absent, e.g. "-timestamp"
+	DefaultSort string
+}
+
+// fixedFilter is a filter taken from the route, such as :computer_id
+type fixedFilter struct {
+	Param string
+	Value int
+}
+
+// listRows runs a paginated query described by spec and writes the
+// models.Page envelope. fixed filters come from path parameters and take
+// precedence over the query string.
+func listRows[T any](c echo.Context, db *sql.DB, spec listSpec, scan func(rowScanner) (T, error), fixed ...fixedFilter) error {
+	query, args, page, err := buildListQuery(c, spec, fixed)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	items := make([]T, 0, page.Limit)
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	// One extra row is fetched to learn whether another page exists
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+		next := nextPageURL(c, page.Limit, page.Offset+page.Limit)
+		page.Next = &next
+	}
+	page.Data = items
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func buildListQuery(c echo.Context, spec listSpec, fixed []fixedFilter) (string, []interface{}, models.Page, error) {
+	page := models.Page{Limit: defaultPageSize}
+
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return "", nil, page, fmt.Errorf("invalid limit %q", v)
+		}
+		if n > maxPageSize {
+			n = maxPageSize
+		}
+		page.Limit = n
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return "", nil, page, fmt.Errorf("invalid offset %q", v)
+		}
+		page.Offset = n
+	}
+
+	var where []string
+	var args []interface{}
+	if spec.Where != "" {
+		where = append(where, spec.Where)
+	}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, "$"+strconv.Itoa(len(args))))
+	}
+
+	fromRoute := make(map[string]bool, len(fixed))
+	for _, f := range fixed {
+		add(spec.Filters[f.Param], f.Value)
+		fromRoute[f.Param] = true
+	}
+	for param, cond := range spec.Filters {
+		v := c.QueryParam(param)
+		if v == "" || fromRoute[param] {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return "", nil, page, fmt.Errorf("invalid %s %q", param, v)
+		}
+		add(cond, n)
+	}
+
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<"}} {
+		v := c.QueryParam(bound.param)
+		if v == "" {
+			continue
+		}
+		if spec.TimeColumn == "" {
+			return "", nil, page, fmt.Errorf("%s is not supported for this resource", bound.param)
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return "", nil, page, fmt.Errorf("invalid %s %q: use RFC 3339 or YYYY-MM-DD", bound.param, v)
+		}
+		add(spec.TimeColumn+" "+bound.op+" %s", t)
+	}
+
+	sortParam := c.QueryParam("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	direction := "ASC"
+	name := sortParam
+	if strings.HasPrefix(name, "-") {
+		direction = "DESC"
+		name = name[1:]
+	}
+	column, ok := spec.Sorts[name]
+	if !ok {
+		return "", nil, page, fmt.Errorf("invalid sort %q: allowed %s", sortParam, sortNames(spec.Sorts))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", spec.Columns, spec.From)
+	if len(where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	fmt.Fprintf(&b, " ORDER BY %s %s", column, direction)
+	if column != spec.IDColumn {
+		fmt.Fprintf(&b, ", %s %s", spec.IDColumn, direction)
+	}
+	fmt.Fprintf(&b, " LIMIT %d OFFSET %d", page.Limit+1, page.Offset)
+
+	return b.String(), args, page, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps and plain dates
+func parseTimeParam(v string) (time.Time, error) {
+	// Timestamps are stored as local TIMESTAMP without time zone
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.Local(), nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
+// nextPageURL keeps every query parameter of the current request and
+// replaces limit and offset.
+func nextPageURL(c echo.Context, limit, offset int) string {
+	q := url.Values{}
+	for k, v := range c.QueryParams() {
+		q[k] = v
+	}
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	return c.Request().URL.Path + "?" + q.Encode()
+}
+
+func sortNames(sorts map[string]string) string {
+	names := make([]string, 0, len(sorts))
+	for name := range sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
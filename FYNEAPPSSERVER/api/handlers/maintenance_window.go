@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/maintenance"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+type MaintenanceWindowHandler struct {
+	DB *sql.DB
+}
+
+func NewMaintenanceWindowHandler(db *sql.DB) *MaintenanceWindowHandler {
+	return &MaintenanceWindowHandler{DB: db}
+}
+
+var maintenanceWindowList = listSpec{
+	From:     "maintenance_windows",
+	Columns:  maintenance.Columns,
+	IDColumn: "window_id",
+	Filters: map[string]string{
+		"target_id": "target_id = %s",
+	},
+	Sorts: map[string]string{
+		"id":        "window_id",
+		"starts_at": "starts_at",
+	},
+	DefaultSort: "id",
+}
+
+func scanMaintenanceWindow(row rowScanner) (models.MaintenanceWindow, error) {
+	return maintenance.Scan(row)
+}
+
+// validateMaintenanceWindow checks that a window names either a target or
+// a group and is either one-off or recurring. One-off times are stored in
+// server local time.
+func validateMaintenanceWindow(w *models.MaintenanceWindow) error {
+	w.Group = strings.TrimSpace(w.Group)
+	w.Schedule = strings.TrimSpace(w.Schedule)
+	w.Reason = strings.TrimSpace(w.Reason)
+
+	if (w.TargetID == nil) == (w.Group == "") {
+		return fmt.Errorf("either target_id or group is required")
+	}
+	if w.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+
+	if w.Schedule == "" {
+		if w.StartsAt == nil || w.EndsAt == nil {
+			return fmt.Errorf("starts_at and ends_at are required without schedule")
+		}
+		if !w.EndsAt.After(*w.StartsAt) {
+			return fmt.Errorf("ends_at must be after starts_at")
+		}
+		if w.DurationMin != 0 {
+			return fmt.Errorf("duration_min is only allowed with schedule")
+		}
+		startsAt, endsAt := w.StartsAt.In(time.Local), w.EndsAt.In(time.Local)
+		w.StartsAt, w.EndsAt = &startsAt, &endsAt
+		return nil
+	}
+
+	if w.StartsAt != nil || w.EndsAt != nil {
+		return fmt.Errorf("starts_at and ends_at are not allowed with schedule")
+	}
+	if _, err := maintenance.ParseSchedule(w.Schedule); err != nil {
+		return err
+	}
+	if w.DurationMin < 1 || w.DurationMin > maintenance.MaxDurationMin {
+		return fmt.Errorf("duration_min must be between 1 and %d", maintenance.MaxDurationMin)
+	}
+	return nil
+}
+
+// nullableGroup stores an empty group as NULL, as the table requires
+func nullableGroup(group string) sql.NullString {
+	return sql.NullString{String: group, Valid: group != ""}
+}
+
+// nullableDuration stores the duration of a one-off window as NULL
+func nullableDuration(minutes int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(minutes), Valid: minutes != 0}
+}
+
+// GetMaintenanceWindows returns the maintenance windows, optionally of one
+// target (?target_id=); windows of groups are returned without the filter
+func (h *MaintenanceWindowHandler) GetMaintenanceWindows(c echo.Context) error {
+	return listRows(c, h.DB, maintenanceWindowList, scanMaintenanceWindow)
+}
+
+func (h *MaintenanceWindowHandler) GetMaintenanceWindow(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	w, err := scanMaintenanceWindow(h.DB.QueryRow("SELECT "+maintenance.Columns+" FROM maintenance_windows WHERE window_id = $1", id))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, "Maintenance window not found")
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, w)
+}
+
+// CreateMaintenanceWindow adds a window. Example bodies, a one-off window
+// of a target and a weekly one of a group:
+//
+//	{"target_id": 2, "starts_at": "2026-11-03T22:00:00+04:00",
+//	 "ends_at": "2026-11-04T02:00:00+04:00", "reason": "Portal upgrade"}
+//	{"group": "ПГАТУ", "schedule": "0 2 * * 3", "duration_min": 120,
+//	 "reason": "Patch night"}
+func (h *MaintenanceWindowHandler) CreateMaintenanceWindow(c echo.Context) error {
+	var w models.MaintenanceWindow
+	if err := c.Bind(&w); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if err := validateMaintenanceWindow(&w); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	err := h.DB.QueryRow(`
+		INSERT INTO maintenance_windows (target_id, group_name, starts_at, ends_at, schedule, duration_min, reason)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		RETURNING window_id, created_at`,
+		w.TargetID, nullableGroup(w.Group), w.StartsAt, w.EndsAt, w.Schedule, nullableDuration(w.DurationMin), w.Reason,
+	).Scan(&w.WindowID, &w.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return c.JSON(http.StatusBadRequest, "Monitoring target not found")
+		}
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, w)
+}
+
+// UpdateMaintenanceWindow replaces a window
+func (h *MaintenanceWindowHandler) UpdateMaintenanceWindow(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var w models.MaintenanceWindow
+	if err := c.Bind(&w); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if err := validateMaintenanceWindow(&w); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	err = h.DB.QueryRow(`
+		UPDATE maintenance_windows SET
+			target_id = $1,
+			group_name = $2,
+			starts_at = $3,
+			ends_at = $4,
+			schedule = NULLIF($5, ''),
+			duration_min = $6,
+			reason = $7
+		WHERE window_id = $8
+		RETURNING created_at`,
+		w.TargetID, nullableGroup(w.Group), w.StartsAt, w.EndsAt, w.Schedule, nullableDuration(w.DurationMin), w.Reason, id,
+	).Scan(&w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, "Maintenance window not found")
+	}
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return c.JSON(http.StatusBadRequest, "Monitoring target not found")
+		}
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	w.WindowID = id
+	return c.JSON(http.StatusOK, w)
+}
+
+func (h *MaintenanceWindowHandler) DeleteMaintenanceWindow(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	res, err := h.DB.Exec("DELETE FROM maintenance_windows WHERE window_id = $1", id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, "Maintenance window not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -17,43 +17,28 @@ func NewMemoryHandler(db *sql.DB) *MemoryHandler {
 	return &MemoryHandler{DB: db}
 }
 
-func (h *MemoryHandler) GetMemory(c echo.Context) error {
-	rows, err := h.DB.Query("SELECT * FROM memory")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var memoryEntries []models.Memory
-	for rows.Next() {
-		var memory models.Memory
-		err := rows.Scan(
-			&memory.MemoryID,
-			&memory.ComputerID,
-			&memory.TotalMemoryGB,
-			&memory.UsedMemoryGB,
-			&memory.FreeMemoryGB,
-			&memory.UsagePercent,
-			&memory.MemoryType,
-			&memory.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		memoryEntries = append(memoryEntries, memory)
-	}
-
-	return c.JSON(http.StatusOK, memoryEntries)
+const memoryColumns = `memory_id, COALESCE(computer_id, 0), COALESCE(total_memory_gb, 0),
+	COALESCE(used_memory_gb, 0), COALESCE(free_memory_gb, 0), COALESCE(usage_percent, 0),
+	COALESCE(memory_type, ''), timestamp`
+
+var memoryList = listSpec{
+	From:       "memory",
+	Columns:    memoryColumns,
+	IDColumn:   "memory_id",
+	TimeColumn: "timestamp",
+	Filters:    map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":            "memory_id",
+		"computer_id":   "computer_id",
+		"usage_percent": "usage_percent",
+		"timestamp":     "timestamp",
+	},
+	DefaultSort: "-timestamp",
 }
 
-func (h *MemoryHandler) GetMemoryEntry(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid ID")
-	}
-
+func scanMemory(row rowScanner) (models.Memory, error) {
 	var memory models.Memory
-	err = h.DB.QueryRow("SELECT * FROM memory WHERE memory_id = $1", id).Scan(
+	err := row.Scan(
 		&memory.MemoryID,
 		&memory.ComputerID,
 		&memory.TotalMemoryGB,
@@ -63,6 +48,20 @@ func (h *MemoryHandler) GetMemoryEntry(c echo.Context) error {
 		&memory.MemoryType,
 		&memory.Timestamp,
 	)
+	return memory, err
+}
+
+func (h *MemoryHandler) GetMemory(c echo.Context) error {
+	return listRows(c, h.DB, memoryList, scanMemory)
+}
+
+func (h *MemoryHandler) GetMemoryEntry(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	memory, err := scanMemory(h.DB.QueryRow("SELECT "+memoryColumns+" FROM memory WHERE memory_id = $1", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.JSON(http.StatusNotFound, "Memory entry not found")
@@ -79,32 +78,7 @@ func (h *MemoryHandler) GetMemoryByComputer(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "Invalid Computer ID")
 	}
 
-	rows, err := h.DB.Query("SELECT * FROM memory WHERE computer_id = $1", computerID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var memoryEntries []models.Memory
-	for rows.Next() {
-		var memory models.Memory
-		err := rows.Scan(
-			&memory.MemoryID,
-			&memory.ComputerID,
-			&memory.TotalMemoryGB,
-			&memory.UsedMemoryGB,
-			&memory.FreeMemoryGB,
-			&memory.UsagePercent,
-			&memory.MemoryType,
-			&memory.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		memoryEntries = append(memoryEntries, memory)
-	}
-
-	return c.JSON(http.StatusOK, memoryEntries)
+	return listRows(c, h.DB, memoryList, scanMemory, fixedFilter{"computer_id", computerID})
 }
 
 func (h *MemoryHandler) CreateMemoryEntry(c echo.Context) error {
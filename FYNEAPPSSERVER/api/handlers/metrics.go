@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/database"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/retention"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Limits for the downsampled metrics endpoint
+const (
+	defaultMetricsRange = 24 * time.Hour
+	defaultMetricsSteps = 300
+	maxMetricsBuckets   = 5000
+	minMetricsStep      = time.Second
+)
+
+type MetricsHandler struct {
+	DB *sql.DB
+}
+
+func NewMetricsHandler(db *sql.DB) *MetricsHandler {
+	return &MetricsHandler{DB: db}
+}
+
+// GetComputerMetrics returns one metric of a computer aggregated into
+// fixed-width time buckets:
+//
+//	GET /computers/:id/metrics?metric=cpu_usage&from=...&to=...&step=5m
+//
+// from/to default to the last 24 hours; step defaults to a width giving
+// about 300 buckets. ?series= restricts per-device metrics to one disk or
+// adapter. Empty buckets are omitted.
+func (h *MetricsHandler) GetComputerMetrics(c echo.Context) error {
+	computerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	name := c.QueryParam("metric")
+	source, ok := retention.Metrics[name]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid metric %q: allowed %s", name, retention.MetricNames()))
+	}
+
+	to := time.Now()
+	if v := c.QueryParam("to"); v != "" {
+		if to, err = parseTimeParam(v); err != nil {
+			return c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid to %q: use RFC 3339 or YYYY-MM-DD", v))
+		}
+	}
+	from := to.Add(-defaultMetricsRange)
+	if v := c.QueryParam("from"); v != "" {
+		if from, err = parseTimeParam(v); err != nil {
+			return c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid from %q: use RFC 3339 or YYYY-MM-DD", v))
+		}
+	}
+	if !from.Before(to) {
+		return c.JSON(http.StatusBadRequest, "from must be before to")
+	}
+
+	step := to.Sub(from) / defaultMetricsSteps
+	if v := c.QueryParam("step"); v != "" {
+		if step, err = parseStep(v); err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+	step = step.Truncate(time.Second)
+	if step < minMetricsStep {
+		step = minMetricsStep
+	}
+	if to.Sub(from)/step > maxMetricsBuckets {
+		return c.JSON(http.StatusBadRequest, fmt.Sprintf("step %s is too small for this range: at most %d buckets", step, maxMetricsBuckets))
+	}
+
+	var exists bool
+	if err := h.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM computers WHERE computer_id = $1)", computerID).Scan(&exists); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, "Computer not found")
+	}
+
+	query, args := buildMetricsQuery(name, source, computerID, from, to, step, c.QueryParam("series"))
+	rows, err := h.DB.Query(query, args...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	result := models.MetricSeries{
+		ComputerID: computerID,
+		Metric:     name,
+		Unit:       source.Unit,
+		From:       from,
+		To:         to,
+		Step:       step.String(),
+		StepSec:    int64(step / time.Second),
+		Buckets:    []models.MetricBucket{},
+	}
+	for rows.Next() {
+		var b models.MetricBucket
+		if err := rows.Scan(&b.Time, &b.Series, &b.Avg, &b.Min, &b.Max, &b.P95, &b.Count); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		b.Time = database.WallClock(b.Time)
+		result.Buckets = append(result.Buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// buildMetricsQuery groups samples into buckets aligned to the Unix epoch so
+// that the same step always yields the same bucket boundaries. Timestamps
+// are stored without a time zone, so the epoch arithmetic is done as UTC
+// and converted back. Periods whose raw samples the retention job has
+// already rolled up are read from the hourly and daily aggregates; there a
+// bucket narrower than the aggregate holds the whole hour or day, and p95
+// is estimated from the aggregated p95 values.
+func buildMetricsQuery(name string, source retention.Metric, computerID int, from, to time.Time, step time.Duration, series string) (string, []interface{}) {
+	seriesExpr := "''"
+	if source.SeriesColumn != "" {
+		seriesExpr = "COALESCE(" + source.SeriesColumn + ", '')"
+	}
+
+	args := []interface{}{computerID, from, to, int64(step / time.Second), name}
+	rawWhere := "computer_id = $1 AND timestamp >= $2 AND timestamp < $3 AND " + source.Column + " IS NOT NULL"
+	rollupWhere := "computer_id = $1 AND bucket >= $2 AND bucket < $3 AND metric = $5"
+	if series != "" && source.SeriesColumn != "" {
+		args = append(args, series)
+		rawWhere += " AND " + source.SeriesColumn + " = $6"
+		rollupWhere += " AND series = $6"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			to_timestamp(floor(extract(epoch FROM t) / $4) * $4) AT TIME ZONE 'UTC' AS bucket,
+			series,
+			(sum(sum) / sum(count))::float8,
+			min(min)::float8,
+			max(max)::float8,
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY p95)::float8,
+			sum(count)::bigint
+		FROM (
+			SELECT timestamp AS t, %[1]s AS series, %[2]s::float8 AS sum, 1 AS count,
+				%[2]s::float8 AS min, %[2]s::float8 AS max, %[2]s::float8 AS p95
+			FROM %[3]s
+			WHERE %[4]s
+			UNION ALL
+			SELECT bucket, series, sum, count, min, max, p95
+			FROM metric_rollups_hourly
+			WHERE %[5]s
+			UNION ALL
+			SELECT bucket, series, sum, count, min, max, p95
+			FROM metric_rollups_daily
+			WHERE %[5]s
+		) samples
+		GROUP BY bucket, series
+		ORDER BY series, bucket`,
+		seriesExpr, source.Column, source.Table, rawWhere, rollupWhere)
+
+	return query, args
+}
+
+// parseStep accepts Go durations ("30s", "5m", "1h") and whole days ("1d")
+func parseStep(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	step, err := time.ParseDuration(v)
+	if err != nil || step <= 0 {
+		return 0, fmt.Errorf("invalid step %q: use e.g. 30s, 5m, 1h or 1d", v)
+	}
+	return step, nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30s", 30 * time.Second, false},
+		{"5m", 5 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"1d", 24 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"", 0, true},
+		{"0s", 0, true},
+		{"-5m", 0, true},
+		{"0d", 0, true},
+		{"-1d", 0, true},
+		{"d", 0, true},
+		{"1.5d", 0, true},
+		{"5", 0, true},
+		{"week", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseStep(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStep(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseStep(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/auth"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// principalKey is the echo.Context key holding the authenticated caller
+const principalKey = "principal"
+
+// Principal is the caller resolved from the request credentials: either a
+// logged-in user or an agent API key.
+type Principal struct {
+	UserID   int
+	Username string
+	Role     auth.Role
+
+	// MustChangePassword is set for users whose password was reset
+	MustChangePassword bool
+
+	// Set for API keys only
+	APIKeyID int
+	Scopes   []auth.Permission
+
+	// tokenHash identifies the session for logout
+	tokenHash string
+}
+
+// Can reports whether the caller may perform perm. Users are checked
+// against their role, API keys against their scopes.
+func (p *Principal) Can(perm auth.Permission) bool {
+	if p.APIKeyID != 0 {
+		for _, s := range p.Scopes {
+			if s == perm {
+				return true
+			}
+		}
+		return false
+	}
+	return p.Role.Can(perm)
+}
+
+// CurrentPrincipal returns the authenticated caller, or nil for anonymous
+// requests.
+func CurrentPrincipal(c echo.Context) *Principal {
+	p, _ := c.Get(principalKey).(*Principal)
+	return p
+}
+
+// Authenticate resolves "Authorization: Bearer <token>" or "X-API-Key" into
+// a Principal. Requests without credentials pass through anonymously and are
+// stopped by RequireAuthForWrites or RequirePermission where needed.
+func Authenticate(db *sql.DB) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := c.Request().Header.Get("X-API-Key")
+			if header := c.Request().Header.Get(echo.HeaderAuthorization); header != "" {
+				var ok bool
+				token, ok = strings.CutPrefix(header, "Bearer ")
+				if !ok || token == "" {
+					return c.JSON(http.StatusUnauthorized, "Invalid authorization header")
+				}
+			}
+			if token == "" {
+				return next(c)
+			}
+
+			var p *Principal
+			var err error
+			if auth.IsAPIKey(token) {
+				p, err = lookupAPIKey(db, token)
+			} else {
+				p, err = lookupSession(db, token)
+			}
+			if err == sql.ErrNoRows {
+				return c.JSON(http.StatusUnauthorized, "Invalid or expired credentials")
+			}
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, err.Error())
+			}
+
+			c.Set(principalKey, p)
+			return next(c)
+		}
+	}
+}
+
+// RequireAuthForWrites rejects anonymous requests with any method other
+// than GET, HEAD or OPTIONS. Paths in public (for example the login route)
+// are exempt.
+func RequireAuthForWrites(public ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+			for _, path := range public {
+				if c.Path() == path {
+					return next(c)
+				}
+			}
+			if CurrentPrincipal(c) == nil {
+				return c.JSON(http.StatusUnauthorized, "Authentication required")
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequirePasswordChanged rejects requests of users who must change a reset
+// password with 403. Paths in allowed (the password change itself and the
+// routes a client needs to get there) are exempt.
+func RequirePasswordChanged(allowed ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p := CurrentPrincipal(c)
+			if p == nil || !p.MustChangePassword {
+				return next(c)
+			}
+			for _, path := range allowed {
+				if c.Path() == path {
+					return next(c)
+				}
+			}
+			return c.JSON(http.StatusForbidden, "Password change required")
+		}
+	}
+}
+
+// RequirePermission rejects anonymous callers with 401 and callers lacking
+// perm with 403.
+func RequirePermission(perm auth.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p := CurrentPrincipal(c)
+			if p == nil {
+				return c.JSON(http.StatusUnauthorized, "Authentication required")
+			}
+			if !p.Can(perm) {
+				return c.JSON(http.StatusForbidden, "Permission denied: "+string(perm))
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireUser rejects callers that are not logged-in users (API keys
+// included).
+func RequireUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := CurrentPrincipal(c)
+		if p == nil || p.UserID == 0 {
+			return c.JSON(http.StatusUnauthorized, "User login required")
+		}
+		return next(c)
+	}
+}
+
+func lookupSession(db *sql.DB, token string) (*Principal, error) {
+	p := &Principal{tokenHash: auth.HashToken(token)}
+
+	var role string
+	err := db.QueryRow(`
+		SELECT u.id, u.username, u.role, u.must_change_password
+		FROM user_sessions s JOIN users u ON u.id = s.user_id
+		WHERE s.session_id = $1 AND s.expires_at > NOW()`,
+		p.tokenHash,
+	).Scan(&p.UserID, &p.Username, &role, &p.MustChangePassword)
+	if err != nil {
+		return nil, err
+	}
+	if p.Role, err = auth.ParseRole(role); err != nil {
+		return nil, err
+	}
+
+	// Touching the row at most once a minute keeps the write load low
+	db.Exec(`
+		UPDATE user_sessions SET last_seen_at = NOW()
+		WHERE session_id = $1 AND (last_seen_at IS NULL OR last_seen_at < NOW() - INTERVAL '1 minute')`,
+		p.tokenHash)
+
+	return p, nil
+}
+
+func lookupAPIKey(db *sql.DB, key string) (*Principal, error) {
+	hash := auth.HashToken(key)
+
+	p := &Principal{}
+	var name string
+	var scopes []string
+	err := db.QueryRow(`
+		SELECT id, name, scopes FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL`,
+		hash,
+	).Scan(&p.APIKeyID, &name, pq.Array(&scopes))
+	if err != nil {
+		return nil, err
+	}
+
+	p.Username = "apikey:" + name
+	for _, s := range scopes {
+		p.Scopes = append(p.Scopes, auth.Permission(s))
+	}
+
+	db.Exec(`
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`,
+		p.APIKeyID)
+
+	return p, nil
+}
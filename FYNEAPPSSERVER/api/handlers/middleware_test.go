@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequirePasswordChanged(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		path      string
+		want      int
+	}{
+		{"anonymous", nil, "/api/v1/computers", http.StatusOK},
+		{"changed", &Principal{UserID: 1}, "/api/v1/computers", http.StatusOK},
+		{"api key", &Principal{APIKeyID: 1}, "/api/v1/ingest", http.StatusOK},
+		{"reset", &Principal{UserID: 1, MustChangePassword: true}, "/api/v1/computers", http.StatusForbidden},
+		{"reset password change", &Principal{UserID: 1, MustChangePassword: true}, "/api/v1/auth/password", http.StatusOK},
+	}
+	mw := RequirePasswordChanged("/api/v1/auth/password")
+	for _, tt := range tests {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, tt.path, nil), rec)
+		c.SetPath(tt.path)
+		if tt.principal != nil {
+			c.Set(principalKey, tt.principal)
+		}
+
+		err := mw(func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})(c)
+		if err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/checker"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// Bounds of monitoring_targets.interval_sec
+const (
+	defaultTargetInterval = 30
+	minTargetInterval     = 5
+	maxTargetInterval     = 24 * 60 * 60
+)
+
+type MonitoringTargetHandler struct {
+	DB *sql.DB
+}
+
+func NewMonitoringTargetHandler(db *sql.DB) *MonitoringTargetHandler {
+	return &MonitoringTargetHandler{DB: db}
+}
+
+const monitoringTargetColumns = checker.TargetColumns
+
+var monitoringTargetList = listSpec{
+	From:     "monitoring_targets",
+	Columns:  monitoringTargetColumns,
+	IDColumn: "target_id",
+	Sorts: map[string]string{
+		"id":    "target_id",
+		"name":  "name",
+		"group": "group_name",
+	},
+	DefaultSort: "group",
+}
+
+func scanMonitoringTarget(row rowScanner) (models.MonitoringTarget, error) {
+	return checker.ScanTarget(row)
+}
+
+// validateMonitoringTarget normalizes a target before it is stored. An
+// empty check list selects every check; a zero interval the default.
+func validateMonitoringTarget(target *models.MonitoringTarget) error {
+	target.Name = strings.TrimSpace(target.Name)
+	target.Host = strings.TrimSpace(target.Host)
+	target.Group = strings.TrimSpace(target.Group)
+
+	if target.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if target.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if strings.ContainsAny(target.Host, " /") {
+		return fmt.Errorf("invalid host %q: expected a host name or IP address", target.Host)
+	}
+
+	switch {
+	case target.IntervalSec == 0:
+		target.IntervalSec = defaultTargetInterval
+	case target.IntervalSec < minTargetInterval || target.IntervalSec > maxTargetInterval:
+		return fmt.Errorf("interval_sec must be between %d and %d", minTargetInterval, maxTargetInterval)
+	}
+
+	if len(target.CheckTypes) == 0 {
+		target.CheckTypes = append([]string(nil), models.CheckTypes...)
+	}
+	seen := make(map[string]bool, len(target.CheckTypes))
+	checks := target.CheckTypes[:0]
+	for _, check := range target.CheckTypes {
+		if !knownCheck(check) {
+			return fmt.Errorf("invalid check type %q: allowed %s", check, strings.Join(models.CheckTypes, ", "))
+		}
+		if !seen[check] {
+			seen[check] = true
+			checks = append(checks, check)
+		}
+	}
+	target.CheckTypes = checks
+
+	return validateCheckSettings(target)
+}
+
+// validateCheckSettings checks the settings of the TCP, TLS, DNS and HTTP
+// checks. Zero values are kept and select the defaults of package checks.
+func validateCheckSettings(target *models.MonitoringTarget) error {
+	target.HTTPURL = strings.TrimSpace(target.HTTPURL)
+	target.DNSResolver = strings.TrimSpace(target.DNSResolver)
+
+	if target.HTTPURL != "" {
+		u, err := url.Parse(target.HTTPURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid http_url %q: expected an http or https URL", target.HTTPURL)
+		}
+	}
+	if target.HTTPExpectStatus != 0 && (target.HTTPExpectStatus < 100 || target.HTTPExpectStatus > 599) {
+		return fmt.Errorf("http_expect_status must be between 100 and 599")
+	}
+	if target.HTTPMaxLatencyMs < 0 || target.HTTPMaxLatencyMs > 60000 {
+		return fmt.Errorf("http_max_latency_ms must be between 0 and 60000")
+	}
+
+	seen := make(map[int]bool, len(target.TCPPorts))
+	ports := []int{}
+	for _, port := range target.TCPPorts {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid TCP port %d", port)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	target.TCPPorts = ports
+	if len(ports) == 0 && hasCheckType(target, models.CheckTCP) {
+		return fmt.Errorf("tcp_ports is required for the tcp check")
+	}
+
+	if target.TLSPort < 0 || target.TLSPort > 65535 {
+		return fmt.Errorf("invalid tls_port %d", target.TLSPort)
+	}
+	if target.TLSWarnDays < 0 || target.TLSWarnDays > 365 {
+		return fmt.Errorf("tls_warn_days must be between 0 and 365")
+	}
+	if strings.ContainsAny(target.DNSResolver, " /") {
+		return fmt.Errorf("invalid dns_resolver %q: expected an address with an optional port", target.DNSResolver)
+	}
+	return nil
+}
+
+func hasCheckType(target *models.MonitoringTarget, check string) bool {
+	for _, c := range target.CheckTypes {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// portsArray converts ports for a tcp_ports parameter
+func portsArray(ports []int) pq.Int64Array {
+	array := make(pq.Int64Array, len(ports))
+	for i, port := range ports {
+		array[i] = int64(port)
+	}
+	return array
+}
+
+func knownCheck(name string) bool {
+	for _, known := range models.CheckTypes {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMonitoringTargets returns the shared server list, grouped by default
+func (h *MonitoringTargetHandler) GetMonitoringTargets(c echo.Context) error {
+	return listRows(c, h.DB, monitoringTargetList, scanMonitoringTarget)
+}
+
+func (h *MonitoringTargetHandler) GetMonitoringTarget(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	target, err := scanMonitoringTarget(h.DB.QueryRow("SELECT "+monitoringTargetColumns+" FROM monitoring_targets WHERE target_id = $1", id))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, "Monitoring target not found")
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, target)
+}
+
+// CreateMonitoringTarget adds a server to the list. Example body:
+//
+//	{"name": "Campus mail", "host": "mail.example.org", "group": "Campus",
+//	 "check_types": ["ping", "tcp", "tls"], "interval_sec": 60,
+//	 "tcp_ports": [25, 993], "tls_port": 993}
+func (h *MonitoringTargetHandler) CreateMonitoringTarget(c echo.Context) error {
+	target := models.MonitoringTarget{Enabled: true}
+	if err := c.Bind(&target); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if err := validateMonitoringTarget(&target); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	err := h.DB.QueryRow(`
+		INSERT INTO monitoring_targets (name, host, group_name, check_types, interval_sec, enabled,
+			http_url, http_expect_status, http_expect_body, http_max_latency_ms, tcp_ports, tls_port, tls_warn_days, dns_resolver)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING target_id, created_at`,
+		target.Name, target.Host, target.Group, pq.Array(target.CheckTypes), target.IntervalSec, target.Enabled,
+		target.HTTPURL, target.HTTPExpectStatus, target.HTTPExpectBody, target.HTTPMaxLatencyMs,
+		portsArray(target.TCPPorts), target.TLSPort, target.TLSWarnDays, target.DNSResolver,
+	).Scan(&target.TargetID, &target.CreatedAt)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, target)
+}
+
+// UpdateMonitoringTarget replaces a target
+func (h *MonitoringTargetHandler) UpdateMonitoringTarget(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var target models.MonitoringTarget
+	if err := c.Bind(&target); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if err := validateMonitoringTarget(&target); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	err = h.DB.QueryRow(`
+		UPDATE monitoring_targets SET
+			name = $1,
+			host = $2,
+			group_name = $3,
+			check_types = $4,
+			interval_sec = $5,
+			enabled = $6,
+			http_url = $7,
+			http_expect_status = $8,
+			http_expect_body = $9,
+			http_max_latency_ms = $10,
+			tcp_ports = $11,
+			tls_port = $12,
+			tls_warn_days = $13,
+			dns_resolver = $14
+		WHERE target_id = $15
+		RETURNING created_at`,
+		target.Name, target.Host, target.Group, pq.Array(target.CheckTypes), target.IntervalSec, target.Enabled,
+		target.HTTPURL, target.HTTPExpectStatus, target.HTTPExpectBody, target.HTTPMaxLatencyMs,
+		portsArray(target.TCPPorts), target.TLSPort, target.TLSWarnDays, target.DNSResolver, id,
+	).Scan(&target.CreatedAt)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, "Monitoring target not found")
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	target.TargetID = id
+	return c.JSON(http.StatusOK, target)
+}
+
+func (h *MonitoringTargetHandler) DeleteMonitoringTarget(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	res, err := h.DB.Exec("DELETE FROM monitoring_targets WHERE target_id = $1", id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, "Monitoring target not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
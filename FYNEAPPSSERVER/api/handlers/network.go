@@ -17,47 +17,31 @@ func NewNetworkHandler(db *sql.DB) *NetworkHandler {
 	return &NetworkHandler{DB: db}
 }
 
-func (h *NetworkHandler) GetNetworkAdapters(c echo.Context) error {
-	rows, err := h.DB.Query("SELECT * FROM network_adapters")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var adapters []models.NetworkAdapter
-	for rows.Next() {
-		var adapter models.NetworkAdapter
-		err := rows.Scan(
-			&adapter.AdapterID,
-			&adapter.ComputerID,
-			&adapter.AdapterName,
-			&adapter.MacAddress,
-			&adapter.UploadSpeed,
-			&adapter.DownloadSpeed,
-			&adapter.SentMB,
-			&adapter.ReceivedMB,
-			&adapter.SentPackets,
-			&adapter.ReceivedPackets,
-			&adapter.IsActive,
-			&adapter.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		adapters = append(adapters, adapter)
-	}
-
-	return c.JSON(http.StatusOK, adapters)
+const networkAdapterColumns = `adapter_id, COALESCE(computer_id, 0), adapter_name,
+	COALESCE(mac_address, ''), COALESCE(upload_speed_mbps, 0), COALESCE(download_speed_mbps, 0),
+	COALESCE(sent_mb, 0), COALESCE(received_mb, 0), COALESCE(sent_packets, 0),
+	COALESCE(received_packets, 0), COALESCE(is_active, FALSE), timestamp`
+
+var networkAdapterList = listSpec{
+	From:       "network_adapters",
+	Columns:    networkAdapterColumns,
+	IDColumn:   "adapter_id",
+	TimeColumn: "timestamp",
+	Filters:    map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":             "adapter_id",
+		"computer_id":    "computer_id",
+		"adapter_name":   "adapter_name",
+		"upload_speed":   "upload_speed_mbps",
+		"download_speed": "download_speed_mbps",
+		"timestamp":      "timestamp",
+	},
+	DefaultSort: "-timestamp",
 }
 
-func (h *NetworkHandler) GetNetworkAdapter(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid ID")
-	}
-
+func scanNetworkAdapter(row rowScanner) (models.NetworkAdapter, error) {
 	var adapter models.NetworkAdapter
-	err = h.DB.QueryRow("SELECT * FROM network_adapters WHERE adapter_id = $1", id).Scan(
+	err := row.Scan(
 		&adapter.AdapterID,
 		&adapter.ComputerID,
 		&adapter.AdapterName,
@@ -71,6 +55,20 @@ func (h *NetworkHandler) GetNetworkAdapter(c echo.Context) error {
 		&adapter.IsActive,
 		&adapter.Timestamp,
 	)
+	return adapter, err
+}
+
+func (h *NetworkHandler) GetNetworkAdapters(c echo.Context) error {
+	return listRows(c, h.DB, networkAdapterList, scanNetworkAdapter)
+}
+
+func (h *NetworkHandler) GetNetworkAdapter(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	adapter, err := scanNetworkAdapter(h.DB.QueryRow("SELECT "+networkAdapterColumns+" FROM network_adapters WHERE adapter_id = $1", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.JSON(http.StatusNotFound, "Network adapter not found")
@@ -87,36 +85,7 @@ func (h *NetworkHandler) GetNetworkAdaptersByComputer(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "Invalid Computer ID")
 	}
 
-	rows, err := h.DB.Query("SELECT * FROM network_adapters WHERE computer_id = $1", computerID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var adapters []models.NetworkAdapter
-	for rows.Next() {
-		var adapter models.NetworkAdapter
-		err := rows.Scan(
-			&adapter.AdapterID,
-			&adapter.ComputerID,
-			&adapter.AdapterName,
-			&adapter.MacAddress,
-			&adapter.UploadSpeed,
-			&adapter.DownloadSpeed,
-			&adapter.SentMB,
-			&adapter.ReceivedMB,
-			&adapter.SentPackets,
-			&adapter.ReceivedPackets,
-			&adapter.IsActive,
-			&adapter.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		adapters = append(adapters, adapter)
-	}
-
-	return c.JSON(http.StatusOK, adapters)
+	return listRows(c, h.DB, networkAdapterList, scanNetworkAdapter, fixedFilter{"computer_id", computerID})
 }
 
 func (h *NetworkHandler) CreateNetworkAdapter(c echo.Context) error {
@@ -17,45 +17,29 @@ func NewProcessorHandler(db *sql.DB) *ProcessorHandler {
 	return &ProcessorHandler{DB: db}
 }
 
-func (h *ProcessorHandler) GetProcessors(c echo.Context) error {
-	rows, err := h.DB.Query("SELECT * FROM processors")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var processors []models.Processor
-	for rows.Next() {
-		var processor models.Processor
-		err := rows.Scan(
-			&processor.ProcessorID,
-			&processor.ComputerID,
-			&processor.Model,
-			&processor.Manufacturer,
-			&processor.Architecture,
-			&processor.ClockSpeed,
-			&processor.CoreCount,
-			&processor.ThreadCount,
-			&processor.UsagePercent,
-			&processor.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		processors = append(processors, processor)
-	}
-
-	return c.JSON(http.StatusOK, processors)
+const processorColumns = `processor_id, COALESCE(computer_id, 0), model,
+	COALESCE(manufacturer, ''), COALESCE(architecture, ''), COALESCE(clock_speed, 0),
+	COALESCE(core_count, 0), COALESCE(thread_count, 0), COALESCE(usage_percent, 0), timestamp`
+
+var processorList = listSpec{
+	From:       "processors",
+	Columns:    processorColumns,
+	IDColumn:   "processor_id",
+	TimeColumn: "timestamp",
+	Filters:    map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":            "processor_id",
+		"computer_id":   "computer_id",
+		"model":         "model",
+		"usage_percent": "usage_percent",
+		"timestamp":     "timestamp",
+	},
+	DefaultSort: "-timestamp",
 }
 
-func (h *ProcessorHandler) GetProcessor(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid ID")
-	}
-
+func scanProcessor(row rowScanner) (models.Processor, error) {
 	var processor models.Processor
-	err = h.DB.QueryRow("SELECT * FROM processors WHERE processor_id = $1", id).Scan(
+	err := row.Scan(
 		&processor.ProcessorID,
 		&processor.ComputerID,
 		&processor.Model,
@@ -67,6 +51,20 @@ func (h *ProcessorHandler) GetProcessor(c echo.Context) error {
 		&processor.UsagePercent,
 		&processor.Timestamp,
 	)
+	return processor, err
+}
+
+func (h *ProcessorHandler) GetProcessors(c echo.Context) error {
+	return listRows(c, h.DB, processorList, scanProcessor)
+}
+
+func (h *ProcessorHandler) GetProcessor(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	processor, err := scanProcessor(h.DB.QueryRow("SELECT "+processorColumns+" FROM processors WHERE processor_id = $1", id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.JSON(http.StatusNotFound, "Processor not found")
@@ -83,34 +81,7 @@ func (h *ProcessorHandler) GetProcessorsByComputer(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "Invalid Computer ID")
 	}
 
-	rows, err := h.DB.Query("SELECT * FROM processors WHERE computer_id = $1", computerID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var processors []models.Processor
-	for rows.Next() {
-		var processor models.Processor
-		err := rows.Scan(
-			&processor.ProcessorID,
-			&processor.ComputerID,
-			&processor.Model,
-			&processor.Manufacturer,
-			&processor.Architecture,
-			&processor.ClockSpeed,
-			&processor.CoreCount,
-			&processor.ThreadCount,
-			&processor.UsagePercent,
-			&processor.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		processors = append(processors, processor)
-	}
-
-	return c.JSON(http.StatusOK, processors)
+	return listRows(c, h.DB, processorList, scanProcessor, fixedFilter{"computer_id", computerID})
 }
 
 func (h *ProcessorHandler) CreateProcessor(c echo.Context) error {
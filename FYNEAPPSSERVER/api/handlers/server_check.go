@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/uptime"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ServerCheckHandler struct {
+	DB *sql.DB
+}
+
+func NewServerCheckHandler(db *sql.DB) *ServerCheckHandler {
+	return &ServerCheckHandler{DB: db}
+}
+
+const serverCheckColumns = `check_id, target_id, checked_at, reachable, status, latency_ms, http_code, results`
+
+var serverCheckList = listSpec{
+	From:       "server_checks",
+	Columns:    serverCheckColumns,
+	IDColumn:   "check_id",
+	TimeColumn: "checked_at",
+	Filters: map[string]string{
+		"target_id": "target_id = %s",
+	},
+	Sorts: map[string]string{
+		"id":         "check_id",
+		"checked_at": "checked_at",
+	},
+	DefaultSort: "-checked_at",
+}
+
+func scanServerCheck(row rowScanner) (models.ServerCheck, error) {
+	var (
+		check     models.ServerCheck
+		latencyMs sql.NullInt64
+		httpCode  sql.NullInt64
+		results   []byte
+	)
+	err := row.Scan(
+		&check.CheckID,
+		&check.TargetID,
+		&check.CheckedAt,
+		&check.Reachable,
+		&check.Status,
+		&latencyMs,
+		&httpCode,
+		&results,
+	)
+	if err != nil {
+		return check, err
+	}
+	if latencyMs.Valid {
+		v := int(latencyMs.Int64)
+		check.LatencyMs = &v
+	}
+	if httpCode.Valid {
+		v := int(httpCode.Int64)
+		check.HTTPCode = &v
+	}
+	if err := json.Unmarshal(results, &check.Results); err != nil {
+		return check, err
+	}
+	return check, nil
+}
+
+// targetExists reports whether the :id route parameter names a target
+func (h *ServerCheckHandler) targetExists(id int) (bool, error) {
+	var exists bool
+	err := h.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM monitoring_targets WHERE target_id = $1)", id).Scan(&exists)
+	return exists, err
+}
+
+// GetServerChecks returns the stored checks of a target, newest first
+func (h *ServerCheckHandler) GetServerChecks(c echo.Context) error {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid Target ID")
+	}
+
+	return listRows(c, h.DB, serverCheckList, scanServerCheck, fixedFilter{"target_id", targetID})
+}
+
+// CreateServerCheck stores the result of checking a target. The check is
+// timestamped by the server. Example body:
+//
+//	{"status": "up", "latency_ms": 12, "http_code": 200,
+//	 "results": [{"check": "ping", "ok": true, "summary": "12 ms", "latency_ms": 12}]}
+func (h *ServerCheckHandler) CreateServerCheck(c echo.Context) error {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid Target ID")
+	}
+
+	var check models.ServerCheck
+	if err := c.Bind(&check); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if !knownCheckStatus(check.Status) {
+		return c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid status %q: allowed %s", check.Status, strings.Join(uptime.Statuses, ", ")))
+	}
+	check.TargetID = targetID
+
+	exists, err := h.targetExists(targetID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, "Monitoring target not found")
+	}
+
+	if err := uptime.Record(c.Request().Context(), h.DB, &check); err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, check)
+}
+
+func knownCheckStatus(status string) bool {
+	for _, known := range uptime.Statuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUptime returns the uptime of a target for the last 24 hours, 7 and 30
+// days with its latency and outages
+func (h *ServerCheckHandler) GetUptime(c echo.Context) error {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid Target ID")
+	}
+
+	exists, err := h.targetExists(targetID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, "Monitoring target not found")
+	}
+
+	report, err := uptime.Report(c.Request().Context(), h.DB, targetID, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -17,54 +17,37 @@ func NewSoftwareDependenciesHandler(db *sql.DB) *SoftwareDependenciesHandler {
 	return &SoftwareDependenciesHandler{DB: db}
 }
 
-func (h *SoftwareDependenciesHandler) GetDependencies(c echo.Context) error {
-	rows, err := h.DB.Query(`
-		SELECT d.*, s.name as required_software_name 
-		FROM software_dependencies d
-		JOIN software s ON d.required_software_id = s.software_id
-	`)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var deps []struct {
-		models.SoftwareDependency
-		RequiredSoftwareName string `json:"required_software_name"`
-	}
+// softwareDependencyRow adds the name of the required software to a dependency
+type softwareDependencyRow struct {
+	models.SoftwareDependency
+	RequiredSoftwareName string `json:"required_software_name"`
+}
 
-	for rows.Next() {
-		var d struct {
-			models.SoftwareDependency
-			RequiredSoftwareName string `json:"required_software_name"`
-		}
-		err := rows.Scan(
-			&d.DependencyID,
-			&d.SoftwareID,
-			&d.RequiredSoftwareID,
-			&d.MinVersion,
-			&d.MaxVersion,
-			&d.IsOptional,
-			&d.Timestamp,
-			&d.RequiredSoftwareName,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		deps = append(deps, d)
-	}
-	return c.JSON(http.StatusOK, deps)
+const softwareDependencyColumns = `d.dependency_id, d.software_id, d.required_software_id,
+	COALESCE(d.min_version, ''), COALESCE(d.max_version, ''), COALESCE(d.is_optional, FALSE),
+	d.timestamp, s.name`
+
+var softwareDependencyList = listSpec{
+	From:       "software_dependencies d JOIN software s ON d.required_software_id = s.software_id",
+	Columns:    softwareDependencyColumns,
+	IDColumn:   "d.dependency_id",
+	TimeColumn: "d.timestamp",
+	Filters: map[string]string{
+		"software_id":          "d.software_id = %s",
+		"required_software_id": "d.required_software_id = %s",
+	},
+	Sorts: map[string]string{
+		"id":                     "d.dependency_id",
+		"software_id":            "d.software_id",
+		"required_software_name": "s.name",
+		"timestamp":              "d.timestamp",
+	},
+	DefaultSort: "id",
 }
 
-func (h *SoftwareDependenciesHandler) GetDependencyByID(c echo.Context) error {
-	id, _ := strconv.Atoi(c.Param("id"))
-	var d models.SoftwareDependency
-	err := h.DB.QueryRow(`
-		SELECT d.*, s.name as required_software_name 
-		FROM software_dependencies d
-		JOIN software s ON d.required_software_id = s.software_id
-		WHERE d.dependency_id = $1
-	`, id).Scan(
+func scanSoftwareDependency(row rowScanner) (softwareDependencyRow, error) {
+	var d softwareDependencyRow
+	err := row.Scan(
 		&d.DependencyID,
 		&d.SoftwareID,
 		&d.RequiredSoftwareID,
@@ -72,10 +55,25 @@ func (h *SoftwareDependenciesHandler) GetDependencyByID(c echo.Context) error {
 		&d.MaxVersion,
 		&d.IsOptional,
 		&d.Timestamp,
+		&d.RequiredSoftwareName,
 	)
-	if err != nil {
+	return d, err
+}
+
+func (h *SoftwareDependenciesHandler) GetDependencies(c echo.Context) error {
+	return listRows(c, h.DB, softwareDependencyList, scanSoftwareDependency)
+}
+
+func (h *SoftwareDependenciesHandler) GetDependencyByID(c echo.Context) error {
+	id, _ := strconv.Atoi(c.Param("id"))
+	d, err := scanSoftwareDependency(h.DB.QueryRow(
+		"SELECT "+softwareDependencyColumns+" FROM "+softwareDependencyList.From+" WHERE d.dependency_id = $1", id))
+	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, "Dependency not found")
 	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
 	return c.JSON(http.StatusOK, d)
 }
 
@@ -133,42 +131,9 @@ func (h *SoftwareDependenciesHandler) DeleteDependency(c echo.Context) error {
 }
 
 func (h *SoftwareDependenciesHandler) GetDependenciesBySoftware(c echo.Context) error {
-	softwareID, _ := strconv.Atoi(c.Param("software_id"))
-	rows, err := h.DB.Query(`
-		SELECT d.*, s.name as required_software_name 
-		FROM software_dependencies d
-		JOIN software s ON d.required_software_id = s.software_id
-		WHERE d.software_id = $1
-	`, softwareID)
+	softwareID, err := strconv.Atoi(c.Param("software_id"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var deps []struct {
-		models.SoftwareDependency
-		RequiredSoftwareName string `json:"required_software_name"`
-	}
-
-	for rows.Next() {
-		var d struct {
-			models.SoftwareDependency
-			RequiredSoftwareName string `json:"required_software_name"`
-		}
-		err := rows.Scan(
-			&d.DependencyID,
-			&d.SoftwareID,
-			&d.RequiredSoftwareID,
-			&d.MinVersion,
-			&d.MaxVersion,
-			&d.IsOptional,
-			&d.Timestamp,
-			&d.RequiredSoftwareName,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		deps = append(deps, d)
+		return c.JSON(http.StatusBadRequest, "Invalid Software ID")
 	}
-	return c.JSON(http.StatusOK, deps)
+	return listRows(c, h.DB, softwareDependencyList, scanSoftwareDependency, fixedFilter{"software_id", softwareID})
 }
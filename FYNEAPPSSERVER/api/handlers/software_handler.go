@@ -17,43 +17,33 @@ func NewSoftwareHandler(db *sql.DB) *SoftwareHandler {
 	return &SoftwareHandler{DB: db}
 }
 
-func (h *SoftwareHandler) GetSoftware(c echo.Context) error {
-	rows, err := h.DB.Query("SELECT * FROM software")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var software []models.Software
-	for rows.Next() {
-		var s models.Software
-		err := rows.Scan(
-			&s.SoftwareID,
-			&s.ComputerID,
-			&s.Name,
-			&s.Version,
-			&s.Publisher,
-			&s.InstallDate,
-			&s.InstallLocation,
-			&s.SizeMB,
-			&s.IsSystemComponent,
-			&s.IsUpdate,
-			&s.Architecture,
-			&s.LastUsedDate,
-			&s.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		software = append(software, s)
-	}
-	return c.JSON(http.StatusOK, software)
+// software also carries catalogue columns (picture, download_url, ...) used
+// by the desktop client, so the columns are always listed explicitly
+const softwareColumns = `software_id, COALESCE(computer_id, 0), name, COALESCE(version, ''),
+	COALESCE(publisher, ''), COALESCE(install_date, 'epoch'), COALESCE(install_location, ''),
+	COALESCE(size_mb, 0), COALESCE(is_system_component, FALSE), COALESCE(is_update, FALSE),
+	COALESCE(architecture, ''), COALESCE(last_used_date, 'epoch'), timestamp`
+
+var softwareList = listSpec{
+	From:       "software",
+	Columns:    softwareColumns,
+	IDColumn:   "software_id",
+	TimeColumn: "timestamp",
+	Filters:    map[string]string{"computer_id": "computer_id = %s"},
+	Sorts: map[string]string{
+		"id":           "software_id",
+		"computer_id":  "computer_id",
+		"name":         "name",
+		"publisher":    "publisher",
+		"install_date": "install_date",
+		"timestamp":    "timestamp",
+	},
+	DefaultSort: "name",
 }
 
-func (h *SoftwareHandler) GetSoftwareByID(c echo.Context) error {
-	id, _ := strconv.Atoi(c.Param("id"))
+func scanSoftware(row rowScanner) (models.Software, error) {
 	var s models.Software
-	err := h.DB.QueryRow("SELECT * FROM software WHERE software_id = $1", id).Scan(
+	err := row.Scan(
 		&s.SoftwareID,
 		&s.ComputerID,
 		&s.Name,
@@ -68,9 +58,22 @@ func (h *SoftwareHandler) GetSoftwareByID(c echo.Context) error {
 		&s.LastUsedDate,
 		&s.Timestamp,
 	)
-	if err != nil {
+	return s, err
+}
+
+func (h *SoftwareHandler) GetSoftware(c echo.Context) error {
+	return listRows(c, h.DB, softwareList, scanSoftware)
+}
+
+func (h *SoftwareHandler) GetSoftwareByID(c echo.Context) error {
+	id, _ := strconv.Atoi(c.Param("id"))
+	s, err := scanSoftware(h.DB.QueryRow("SELECT "+softwareColumns+" FROM software WHERE software_id = $1", id))
+	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, "Software not found")
 	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
 	return c.JSON(http.StatusOK, s)
 }
 
@@ -133,35 +136,9 @@ func (h *SoftwareHandler) DeleteSoftware(c echo.Context) error {
 }
 
 func (h *SoftwareHandler) GetSoftwareByComputer(c echo.Context) error {
-	computerID, _ := strconv.Atoi(c.Param("computer_id"))
-	rows, err := h.DB.Query("SELECT * FROM software WHERE computer_id = $1", computerID)
+	computerID, err := strconv.Atoi(c.Param("computer_id"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var software []models.Software
-	for rows.Next() {
-		var s models.Software
-		err := rows.Scan(
-			&s.SoftwareID,
-			&s.ComputerID,
-			&s.Name,
-			&s.Version,
-			&s.Publisher,
-			&s.InstallDate,
-			&s.InstallLocation,
-			&s.SizeMB,
-			&s.IsSystemComponent,
-			&s.IsUpdate,
-			&s.Architecture,
-			&s.LastUsedDate,
-			&s.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		software = append(software, s)
+		return c.JSON(http.StatusBadRequest, "Invalid Computer ID")
 	}
-	return c.JSON(http.StatusOK, software)
+	return listRows(c, h.DB, softwareList, scanSoftware, fixedFilter{"computer_id", computerID})
 }
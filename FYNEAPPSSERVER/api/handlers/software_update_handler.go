@@ -17,39 +17,32 @@ func NewSoftwareUpdatesHandler(db *sql.DB) *SoftwareUpdatesHandler {
 	return &SoftwareUpdatesHandler{DB: db}
 }
 
-func (h *SoftwareUpdatesHandler) GetUpdates(c echo.Context) error {
-	rows, err := h.DB.Query("SELECT * FROM software_updates")
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var updates []models.SoftwareUpdate
-	for rows.Next() {
-		var u models.SoftwareUpdate
-		err := rows.Scan(
-			&u.UpdateID,
-			&u.SoftwareID,
-			&u.UpdateName,
-			&u.UpdateVersion,
-			&u.KBArticle,
-			&u.InstallDate,
-			&u.SizeMB,
-			&u.IsUninstalled,
-			&u.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		updates = append(updates, u)
-	}
-	return c.JSON(http.StatusOK, updates)
+const softwareUpdateColumns = `update_id, COALESCE(software_id, 0), COALESCE(update_name, ''),
+	COALESCE(update_version, ''), COALESCE(kb_article, ''), COALESCE(install_date, 'epoch'),
+	COALESCE(size_mb, 0), COALESCE(is_uninstalled, FALSE), timestamp`
+
+var softwareUpdateList = listSpec{
+	From:       "software_updates",
+	Columns:    softwareUpdateColumns,
+	IDColumn:   "update_id",
+	TimeColumn: "timestamp",
+	Filters: map[string]string{
+		"software_id": "software_id = %s",
+		"computer_id": "software_id IN (SELECT software_id FROM software WHERE computer_id = %s)",
+	},
+	Sorts: map[string]string{
+		"id":           "update_id",
+		"software_id":  "software_id",
+		"update_name":  "update_name",
+		"install_date": "install_date",
+		"timestamp":    "timestamp",
+	},
+	DefaultSort: "-timestamp",
 }
 
-func (h *SoftwareUpdatesHandler) GetUpdateByID(c echo.Context) error {
-	id, _ := strconv.Atoi(c.Param("id"))
+func scanSoftwareUpdate(row rowScanner) (models.SoftwareUpdate, error) {
 	var u models.SoftwareUpdate
-	err := h.DB.QueryRow("SELECT * FROM software_updates WHERE update_id = $1", id).Scan(
+	err := row.Scan(
 		&u.UpdateID,
 		&u.SoftwareID,
 		&u.UpdateName,
@@ -60,9 +53,22 @@ func (h *SoftwareUpdatesHandler) GetUpdateByID(c echo.Context) error {
 		&u.IsUninstalled,
 		&u.Timestamp,
 	)
-	if err != nil {
+	return u, err
+}
+
+func (h *SoftwareUpdatesHandler) GetUpdates(c echo.Context) error {
+	return listRows(c, h.DB, softwareUpdateList, scanSoftwareUpdate)
+}
+
+func (h *SoftwareUpdatesHandler) GetUpdateByID(c echo.Context) error {
+	id, _ := strconv.Atoi(c.Param("id"))
+	u, err := scanSoftwareUpdate(h.DB.QueryRow("SELECT "+softwareUpdateColumns+" FROM software_updates WHERE update_id = $1", id))
+	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, "Update not found")
 	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
 	return c.JSON(http.StatusOK, u)
 }
 
@@ -121,31 +127,9 @@ func (h *SoftwareUpdatesHandler) DeleteUpdate(c echo.Context) error {
 }
 
 func (h *SoftwareUpdatesHandler) GetUpdatesBySoftware(c echo.Context) error {
-	softwareID, _ := strconv.Atoi(c.Param("software_id"))
-	rows, err := h.DB.Query("SELECT * FROM software_updates WHERE software_id = $1", softwareID)
+	softwareID, err := strconv.Atoi(c.Param("software_id"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
-	}
-	defer rows.Close()
-
-	var updates []models.SoftwareUpdate
-	for rows.Next() {
-		var u models.SoftwareUpdate
-		err := rows.Scan(
-			&u.UpdateID,
-			&u.SoftwareID,
-			&u.UpdateName,
-			&u.UpdateVersion,
-			&u.KBArticle,
-			&u.InstallDate,
-			&u.SizeMB,
-			&u.IsUninstalled,
-			&u.Timestamp,
-		)
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		updates = append(updates, u)
+		return c.JSON(http.StatusBadRequest, "Invalid Software ID")
 	}
-	return c.JSON(http.StatusOK, updates)
+	return listRows(c, h.DB, softwareUpdateList, scanSoftwareUpdate, fixedFilter{"software_id", softwareID})
 }
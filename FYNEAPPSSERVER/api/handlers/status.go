@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/maintenance"
+	"FYNEAPPSSERVER/uptime"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// streamKeepAlive is the period of comments sent on an idle status
+// stream, so that proxies do not close it
+const streamKeepAlive = 30 * time.Second
+
+type StatusHandler struct {
+	DB *sql.DB
+	// Checker is set when this server runs the checks of the targets
+	Checker bool
+}
+
+func NewStatusHandler(db *sql.DB, checker bool) *StatusHandler {
+	return &StatusHandler{DB: db, Checker: checker}
+}
+
+// GetStatus returns every monitoring target with its latest check and the
+// maintenance window it is in
+func (h *StatusHandler) GetStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	targets, err := uptime.Latest(ctx, h.DB)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	windows, err := maintenance.Windows(ctx, h.DB)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	now := time.Now()
+	for i := range targets {
+		targets[i].Maintenance = maintenance.Find(windows, targets[i].TargetID, targets[i].Group, now)
+	}
+	return c.JSON(http.StatusOK, models.StatusReport{Checker: h.Checker, Targets: targets})
+}
+
+// StreamStatus sends the checks stored from now on as server-sent events:
+//
+//	event: check
+//	data: {"check_id": 42, "target_id": 1, "status": "up", ...}
+func (h *StatusHandler) StreamStatus(c echo.Context) error {
+	checks := uptime.Subscribe(c.Request().Context())
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case check, ok := <-checks:
+			if !ok {
+				return nil
+			}
+			data, merr := json.Marshal(check)
+			if merr != nil {
+				return merr
+			}
+			_, err = fmt.Fprintf(res, "event: check\ndata: %s\n\n", data)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(res, ": keep-alive\n\n")
+		}
+		// A write error means the client went away
+		if err != nil {
+			return nil
+		}
+		res.Flush()
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/auth"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+type UserHandler struct {
+	DB *sql.DB
+}
+
+func NewUserHandler(db *sql.DB) *UserHandler {
+	return &UserHandler{DB: db}
+}
+
+var userList = listSpec{
+	From:       "users",
+	Columns:    "id, username, COALESCE(full_name, ''), role, must_change_password, created_at",
+	IDColumn:   "id",
+	TimeColumn: "created_at",
+	Sorts: map[string]string{
+		"id":         "id",
+		"username":   "username",
+		"created_at": "created_at",
+	},
+	DefaultSort: "username",
+}
+
+func scanUser(row rowScanner) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Username, &user.FullName, &user.Role, &user.MustChangePassword, &user.CreatedAt)
+	return user, err
+}
+
+func (h *UserHandler) GetUsers(c echo.Context) error {
+	return listRows(c, h.DB, userList, scanUser)
+}
+
+// CreateUser stores a new user with an argon2id password hash. The password
+// is never returned or logged.
+func (h *UserHandler) CreateUser(c echo.Context) error {
+	var req models.UserCredentials
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		return c.JSON(http.StatusBadRequest, "Username is required")
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if req.Role == "" {
+		req.Role = string(auth.RoleViewer)
+	}
+	if _, err := auth.ParseRole(req.Role); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	user := models.User{Username: req.Username, FullName: req.FullName, Role: req.Role}
+	err = h.DB.QueryRow(`
+		INSERT INTO users (username, password, full_name, role, password_changed_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at`,
+		user.Username, hash, user.FullName, user.Role,
+	).Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return c.JSON(http.StatusConflict, "User already exists")
+		}
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, user)
+}
+
+// ResetPassword sets a temporary password that must be changed at the next
+// login and ends the user's sessions. The body may carry {"password": "..."},
+// which is answered with 204; otherwise a random password is generated and
+// returned once.
+func (h *UserHandler) ResetPassword(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	generated := req.Password == ""
+	if generated {
+		if req.Password, err = auth.GenerateTemporaryPassword(); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	} else if err := auth.ValidatePassword(req.Password); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := auth.SetPassword(h.DB, id, req.Password, true); err != nil {
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, "User not found")
+		}
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	// The admin already knows a password they chose, so it is not echoed
+	if !generated {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, map[string]string{"temporary_password": req.Password})
+}
+
+// UpdateUserRole changes the role of a user. Admins cannot change their own
+// role so the last administrator cannot lock everyone out by accident.
+func (h *UserHandler) UpdateUserRole(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if p := CurrentPrincipal(c); p != nil && p.UserID == id {
+		return c.JSON(http.StatusBadRequest, "Cannot change your own role")
+	}
+
+	res, err := h.DB.Exec("UPDATE users SET role = $1 WHERE id = $2", string(role), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, "User not found")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
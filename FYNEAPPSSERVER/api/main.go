@@ -1,9 +1,17 @@
 package main
 
 import (
+	"FYNEAPPSSERVER/alerting"
 	"FYNEAPPSSERVER/api/database"
 	"FYNEAPPSSERVER/api/handlers"
+	"FYNEAPPSSERVER/auth"
+	"FYNEAPPSSERVER/checker"
+	"FYNEAPPSSERVER/migrations"
+	"FYNEAPPSSERVER/retention"
+	"context"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
@@ -24,60 +32,180 @@ func main() {
 	}
 	defer db.Close()
 
+	// "api migrate up|down [n]|status" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.Run(context.Background(), db, os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			db.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Bring the schema up to date before serving requests
+	if _, err := migrations.Up(context.Background(), db); err != nil {
+		panic(err)
+	}
+
+	// Roll up and expire old telemetry in the background
+	retentionConfig, err := retention.ConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	go retention.Run(context.Background(), db, retentionConfig)
+
+	// Evaluate alert rules and notify the configured sinks
+	alertConfig, err := alerting.ConfigFromEnv(db)
+	if err != nil {
+		panic(err)
+	}
+	go alerting.NewEngine(db, alertConfig).Run(context.Background())
+
+	// Check the monitoring targets from the server, unless CHECKER=false
+	checkerConfig, err := checker.ConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	if checkerConfig.Enabled {
+		go checker.NewScheduler(db, checkerConfig).Run(context.Background())
+	}
+
 	// Create Echo instance
 	e := echo.New()
 
 	// Middleware
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: corsOrigins(),
+		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization, "X-API-Key"},
+	}))
+
+	// Routes. Every route except login needs a session token or an API key:
+	// writes are rejected for anonymous callers here, reads need
+	// inventory:view, and route-level checks below narrow them further.
+	// A user whose password was reset may only change it, look up the
+	// account and log out.
+	api := e.Group("/api/v1",
+		handlers.Authenticate(db),
+		handlers.RequireAuthForWrites("/api/v1/auth/login"),
+		handlers.RequirePasswordChanged(
+			"/api/v1/auth/password",
+			"/api/v1/auth/me",
+			"/api/v1/auth/logout",
+		),
+	)
+
+	// Auth routes
+	authHandler := handlers.NewAuthHandler(db)
+	api.POST("/auth/login", authHandler.Login)
+	api.POST("/auth/logout", authHandler.Logout, handlers.RequireUser)
+	api.GET("/auth/me", authHandler.Me, handlers.RequireUser)
+	api.POST("/auth/password", authHandler.ChangePassword, handlers.RequireUser)
 
-	// Routes
-	api := e.Group("/api/v1")
+	// Agents holding a telemetry:write API key may only POST new samples
+	inventoryView := handlers.RequirePermission(auth.PermInventoryView)
+	telemetryWrite := handlers.RequirePermission(auth.PermTelemetryWrite)
+	inventoryWrite := handlers.RequirePermission(auth.PermInventoryWrite)
+	inventoryDelete := handlers.RequirePermission(auth.PermInventoryDelete)
+	softwareWrite := handlers.RequirePermission(auth.PermSoftwareWrite)
+	softwareDelete := handlers.RequirePermission(auth.PermSoftwareDelete)
 
 	// Computer routes
 	computerHandler := handlers.NewComputerHandler(db)
-	api.GET("/computers", computerHandler.GetComputers)
-	api.GET("/computers/:id", computerHandler.GetComputer)
-	api.POST("/computers", computerHandler.CreateComputer)
-	api.PUT("/computers/:id", computerHandler.UpdateComputer)
-	api.DELETE("/computers/:id", computerHandler.DeleteComputer)
+	api.GET("/computers", computerHandler.GetComputers, inventoryView)
+	api.GET("/computers/:id", computerHandler.GetComputer, inventoryView)
+	api.GET("/computers/:id/hostnames", computerHandler.GetComputerHostNames, inventoryView)
+	api.POST("/computers", computerHandler.CreateComputer, telemetryWrite)
+	api.PUT("/computers/:id", computerHandler.UpdateComputer, inventoryWrite)
+	api.DELETE("/computers/:id", computerHandler.DeleteComputer, inventoryDelete)
+
+	// Batched telemetry: one request per flush instead of one per sample
+	ingestHandler := handlers.NewIngestHandler(db)
+	api.POST("/ingest", ingestHandler.Ingest, telemetryWrite)
+
+	// Downsampled time series over the telemetry tables
+	metricsHandler := handlers.NewMetricsHandler(db)
+	api.GET("/computers/:id/metrics", metricsHandler.GetComputerMetrics, inventoryView)
+
+	// Hardware changes detected from inventories sent with /ingest
+	hardwareChangeHandler := handlers.NewHardwareChangeHandler(db)
+	api.GET("/computers/:id/changes", hardwareChangeHandler.GetComputerChanges, inventoryView)
+
+	// Alert rules and the alerts they raised
+	alertHandler := handlers.NewAlertHandler(db)
+	manageAlerts := handlers.RequirePermission(auth.PermAlertsManage)
+	api.GET("/alert-rules", alertHandler.GetAlertRules, inventoryView)
+	api.GET("/alert-rules/:id", alertHandler.GetAlertRule, inventoryView)
+	api.POST("/alert-rules", alertHandler.CreateAlertRule, manageAlerts)
+	api.PUT("/alert-rules/:id", alertHandler.UpdateAlertRule, manageAlerts)
+	api.DELETE("/alert-rules/:id", alertHandler.DeleteAlertRule, manageAlerts)
+	api.GET("/alerts", alertHandler.GetAlerts, inventoryView)
+	api.GET("/alerts/active", alertHandler.GetActiveAlerts, inventoryView)
+
+	// Servers shown on the client's status tab, shared by all clients
+	targetHandler := handlers.NewMonitoringTargetHandler(db)
+	manageTargets := handlers.RequirePermission(auth.PermTargetsManage)
+	api.GET("/monitoring-targets", targetHandler.GetMonitoringTargets, inventoryView)
+	api.GET("/monitoring-targets/:id", targetHandler.GetMonitoringTarget, inventoryView)
+	api.POST("/monitoring-targets", targetHandler.CreateMonitoringTarget, manageTargets)
+	api.PUT("/monitoring-targets/:id", targetHandler.UpdateMonitoringTarget, manageTargets)
+	api.DELETE("/monitoring-targets/:id", targetHandler.DeleteMonitoringTarget, manageTargets)
+
+	// Check history of the monitoring targets and the uptime computed from it
+	serverCheckHandler := handlers.NewServerCheckHandler(db)
+	api.GET("/monitoring-targets/:id/checks", serverCheckHandler.GetServerChecks, inventoryView)
+	api.POST("/monitoring-targets/:id/checks", serverCheckHandler.CreateServerCheck, telemetryWrite)
+	api.GET("/monitoring-targets/:id/uptime", serverCheckHandler.GetUptime, inventoryView)
+
+	// Planned downtime: failed checks during a window are stored as maintenance
+	maintenanceHandler := handlers.NewMaintenanceWindowHandler(db)
+	api.GET("/maintenance-windows", maintenanceHandler.GetMaintenanceWindows, inventoryView)
+	api.GET("/maintenance-windows/:id", maintenanceHandler.GetMaintenanceWindow, inventoryView)
+	api.POST("/maintenance-windows", maintenanceHandler.CreateMaintenanceWindow, manageTargets)
+	api.PUT("/maintenance-windows/:id", maintenanceHandler.UpdateMaintenanceWindow, manageTargets)
+	api.DELETE("/maintenance-windows/:id", maintenanceHandler.DeleteMaintenanceWindow, manageTargets)
+
+	// Latest check of every target and a live stream of new checks
+	statusHandler := handlers.NewStatusHandler(db, checkerConfig.Enabled)
+	api.GET("/status", statusHandler.GetStatus, inventoryView)
+	api.GET("/status/stream", statusHandler.StreamStatus, inventoryView)
 
 	// Processor routes
 	processorHandler := handlers.NewProcessorHandler(db)
-	api.GET("/processors", processorHandler.GetProcessors)
-	api.GET("/processors/:id", processorHandler.GetProcessor)
-	api.GET("/computers/:computer_id/processors", processorHandler.GetProcessorsByComputer)
-	api.POST("/processors", processorHandler.CreateProcessor)
-	api.PUT("/processors/:id", processorHandler.UpdateProcessor)
-	api.DELETE("/processors/:id", processorHandler.DeleteProcessor)
+	api.GET("/processors", processorHandler.GetProcessors, inventoryView)
+	api.GET("/processors/:id", processorHandler.GetProcessor, inventoryView)
+	api.GET("/computers/:computer_id/processors", processorHandler.GetProcessorsByComputer, inventoryView)
+	api.POST("/processors", processorHandler.CreateProcessor, telemetryWrite)
+	api.PUT("/processors/:id", processorHandler.UpdateProcessor, inventoryWrite)
+	api.DELETE("/processors/:id", processorHandler.DeleteProcessor, inventoryDelete)
 
 	// Memory routes
 	memoryHandler := handlers.NewMemoryHandler(db)
-	api.GET("/memory", memoryHandler.GetMemory)
-	api.GET("/memory/:id", memoryHandler.GetMemoryEntry)
-	api.GET("/computers/:computer_id/memory", memoryHandler.GetMemoryByComputer)
-	api.POST("/memory", memoryHandler.CreateMemoryEntry)
-	api.PUT("/memory/:id", memoryHandler.UpdateMemoryEntry)
-	api.DELETE("/memory/:id", memoryHandler.DeleteMemoryEntry)
+	api.GET("/memory", memoryHandler.GetMemory, inventoryView)
+	api.GET("/memory/:id", memoryHandler.GetMemoryEntry, inventoryView)
+	api.GET("/computers/:computer_id/memory", memoryHandler.GetMemoryByComputer, inventoryView)
+	api.POST("/memory", memoryHandler.CreateMemoryEntry, telemetryWrite)
+	api.PUT("/memory/:id", memoryHandler.UpdateMemoryEntry, inventoryWrite)
+	api.DELETE("/memory/:id", memoryHandler.DeleteMemoryEntry, inventoryDelete)
 
 	// Network routes
 	networkHandler := handlers.NewNetworkHandler(db)
-	api.GET("/network-adapters", networkHandler.GetNetworkAdapters)
-	api.GET("/network-adapters/:id", networkHandler.GetNetworkAdapter)
-	api.GET("/computers/:computer_id/network-adapters", networkHandler.GetNetworkAdaptersByComputer)
-	api.POST("/network-adapters", networkHandler.CreateNetworkAdapter)
-	api.PUT("/network-adapters/:id", networkHandler.UpdateNetworkAdapter)
-	api.DELETE("/network-adapters/:id", networkHandler.DeleteNetworkAdapter)
+	api.GET("/network-adapters", networkHandler.GetNetworkAdapters, inventoryView)
+	api.GET("/network-adapters/:id", networkHandler.GetNetworkAdapter, inventoryView)
+	api.GET("/computers/:computer_id/network-adapters", networkHandler.GetNetworkAdaptersByComputer, inventoryView)
+	api.POST("/network-adapters", networkHandler.CreateNetworkAdapter, telemetryWrite)
+	api.PUT("/network-adapters/:id", networkHandler.UpdateNetworkAdapter, inventoryWrite)
+	api.DELETE("/network-adapters/:id", networkHandler.DeleteNetworkAdapter, inventoryDelete)
 
 	// Disk routes
 	diskHandler := handlers.NewDiskHandler(db)
-	api.GET("/disks", diskHandler.GetDisks)
-	api.GET("/disks/:id", diskHandler.GetDisk)
-	api.GET("/computers/:computer_id/disks", diskHandler.GetDisksByComputer)
-	api.POST("/disks", diskHandler.CreateDisk)
-	api.PUT("/disks/:id", diskHandler.UpdateDisk)
-	api.DELETE("/disks/:id", diskHandler.DeleteDisk)
+	api.GET("/disks", diskHandler.GetDisks, inventoryView)
+	api.GET("/disks/:id", diskHandler.GetDisk, inventoryView)
+	api.GET("/computers/:computer_id/disks", diskHandler.GetDisksByComputer, inventoryView)
+	api.POST("/disks", diskHandler.CreateDisk, telemetryWrite)
+	api.PUT("/disks/:id", diskHandler.UpdateDisk, inventoryWrite)
+	api.DELETE("/disks/:id", diskHandler.DeleteDisk, inventoryDelete)
 
 	// Software routes
 
@@ -85,28 +213,43 @@ func main() {
 	updatesHandler := handlers.NewSoftwareUpdatesHandler(db)
 	depsHandler := handlers.NewSoftwareDependenciesHandler(db)
 
-	api.GET("/software", softwareHandler.GetSoftware)
-	api.GET("/software/:id", softwareHandler.GetSoftwareByID)
-	api.POST("/software", softwareHandler.CreateSoftware)
-	api.PUT("/software/:id", softwareHandler.UpdateSoftware)
-	api.DELETE("/software/:id", softwareHandler.DeleteSoftware)
-	api.GET("/computers/:computer_id/software", softwareHandler.GetSoftwareByComputer)
+	api.GET("/software", softwareHandler.GetSoftware, inventoryView)
+	api.GET("/software/:id", softwareHandler.GetSoftwareByID, inventoryView)
+	api.POST("/software", softwareHandler.CreateSoftware, softwareWrite)
+	api.PUT("/software/:id", softwareHandler.UpdateSoftware, softwareWrite)
+	api.DELETE("/software/:id", softwareHandler.DeleteSoftware, softwareDelete)
+	api.GET("/computers/:computer_id/software", softwareHandler.GetSoftwareByComputer, inventoryView)
 
 	// Software updates routes
-	api.GET("/updates", updatesHandler.GetUpdates)
-	api.GET("/updates/:id", updatesHandler.GetUpdateByID)
-	api.POST("/updates", updatesHandler.CreateUpdate)
-	api.PUT("/updates/:id", updatesHandler.UpdateUpdate)
-	api.DELETE("/updates/:id", updatesHandler.DeleteUpdate)
-	api.GET("/software/:software_id/updates", updatesHandler.GetUpdatesBySoftware)
+	api.GET("/updates", updatesHandler.GetUpdates, inventoryView)
+	api.GET("/updates/:id", updatesHandler.GetUpdateByID, inventoryView)
+	api.POST("/updates", updatesHandler.CreateUpdate, softwareWrite)
+	api.PUT("/updates/:id", updatesHandler.UpdateUpdate, softwareWrite)
+	api.DELETE("/updates/:id", updatesHandler.DeleteUpdate, softwareDelete)
+	api.GET("/software/:software_id/updates", updatesHandler.GetUpdatesBySoftware, inventoryView)
 
 	// Software dependencies routes
-	api.GET("/dependencies", depsHandler.GetDependencies)
-	api.GET("/dependencies/:id", depsHandler.GetDependencyByID)
-	api.POST("/dependencies", depsHandler.CreateDependency)
-	api.PUT("/dependencies/:id", depsHandler.UpdateDependency)
-	api.DELETE("/dependencies/:id", depsHandler.DeleteDependency)
-	api.GET("/software/:software_id/dependencies", depsHandler.GetDependenciesBySoftware)
+	api.GET("/dependencies", depsHandler.GetDependencies, inventoryView)
+	api.GET("/dependencies/:id", depsHandler.GetDependencyByID, inventoryView)
+	api.POST("/dependencies", depsHandler.CreateDependency, softwareWrite)
+	api.PUT("/dependencies/:id", depsHandler.UpdateDependency, softwareWrite)
+	api.DELETE("/dependencies/:id", depsHandler.DeleteDependency, softwareDelete)
+	api.GET("/software/:software_id/dependencies", depsHandler.GetDependenciesBySoftware, inventoryView)
+
+	// User routes
+	userHandler := handlers.NewUserHandler(db)
+	manageUsers := handlers.RequirePermission(auth.PermUsersManage)
+	api.GET("/users", userHandler.GetUsers, manageUsers)
+	api.POST("/users", userHandler.CreateUser, manageUsers)
+	api.POST("/users/:id/reset-password", userHandler.ResetPassword, manageUsers)
+	api.PUT("/users/:id/role", userHandler.UpdateUserRole, manageUsers)
+
+	// API key routes
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	manageKeys := handlers.RequirePermission(auth.PermAPIKeysManage)
+	api.GET("/api-keys", apiKeyHandler.GetAPIKeys, manageKeys)
+	api.POST("/api-keys", apiKeyHandler.CreateAPIKey, manageKeys)
+	api.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey, manageKeys)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -115,3 +258,19 @@ func main() {
 	}
 	e.Logger.Fatal(e.Start(":" + port))
 }
+
+// corsOrigins reads the comma-separated CORS_ALLOW_ORIGINS list. Credentials
+// travel in the Authorization header rather than cookies, so the "*"
+// default does not expose sessions to other sites.
+func corsOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOW_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return []string{"*"}
+	}
+	return origins
+}
@@ -2,8 +2,44 @@ package models
 
 import "time"
 
+// Page is the envelope returned by every list endpoint. Next holds the URL
+// of the following page and is null on the last one.
+type Page struct {
+	Data   interface{} `json:"data"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+	Next   *string     `json:"next"`
+}
+
+// MetricSeries is a downsampled metric returned by /computers/:id/metrics
+type MetricSeries struct {
+	ComputerID int            `json:"computer_id"`
+	Metric     string         `json:"metric"`
+	Unit       string         `json:"unit"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Step       string         `json:"step"`
+	StepSec    int64          `json:"step_seconds"`
+	Buckets    []MetricBucket `json:"buckets"`
+}
+
+// MetricBucket aggregates the samples of one time bucket. Series names the
+// disk or adapter for per-device metrics and is empty otherwise.
+type MetricBucket struct {
+	Time   time.Time `json:"time"`
+	Series string    `json:"series,omitempty"`
+	Avg    float64   `json:"avg"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	P95    float64   `json:"p95"`
+	Count  int       `json:"count"`
+}
+
 type Computer struct {
-	ComputerID     int       `json:"computer_id"`
+	ComputerID int `json:"computer_id"`
+	// MachineUUID is the stable identity reported by the client; the host
+	// name may change over the computer's life
+	MachineUUID    string    `json:"machine_uuid,omitempty"`
 	HostName       string    `json:"host_name"`
 	UserName       string    `json:"user_name"`
 	OsName         string    `json:"os_name"`
@@ -19,6 +55,14 @@ type Computer struct {
 	Uid            string    `json:"uid"`
 }
 
+// ComputerHostName is one host name a computer has reported
+type ComputerHostName struct {
+	ComputerID int       `json:"computer_id"`
+	HostName   string    `json:"host_name"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
 type Processor struct {
 	ProcessorID  int       `json:"processor_id"`
 	ComputerID   int       `json:"computer_id"`
@@ -120,3 +164,359 @@ type SoftwareDependency struct {
 	IsOptional         bool      `json:"is_optional"`
 	Timestamp          time.Time `json:"timestamp"`
 }
+
+type User struct {
+	ID                 int       `json:"id"`
+	Username           string    `json:"username"`
+	FullName           string    `json:"full_name"`
+	Role               string    `json:"role"`
+	MustChangePassword bool      `json:"must_change_password"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// UserCredentials is the request body for creating a user
+type UserCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	FullName string `json:"full_name"`
+	Role     string `json:"role"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse carries the opaque bearer token for subsequent requests
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// Key is only returned once, when the key is created
+	Key string `json:"key,omitempty"`
+}
+
+// IngestBatch is the body of POST /ingest: every sample collected by one
+// computer since the previous flush. BatchID is generated by the client and
+// reused on retries, so a batch is stored at most once.
+type IngestBatch struct {
+	BatchID    string           `json:"batch_id"`
+	ComputerID int              `json:"computer_id"`
+	Processors []Processor      `json:"processors,omitempty"`
+	Memory     []Memory         `json:"memory,omitempty"`
+	Disks      []Disk           `json:"disks,omitempty"`
+	Network    []NetworkAdapter `json:"network_adapters,omitempty"`
+	Software   []SoftwareChange `json:"software,omitempty"`
+	// Inventory is sent from time to time rather than with every batch
+	Inventory *HardwareInventory `json:"inventory,omitempty"`
+}
+
+// HardwareInventory lists the physical components of a computer. Each new
+// inventory is compared with the previous one to detect changes.
+type HardwareInventory struct {
+	CollectedAt     time.Time          `json:"collected_at"`
+	CPUs            []string           `json:"cpus"`
+	MemoryTotalGB   float64            `json:"memory_total_gb"`
+	MemoryModules   []MemoryModule     `json:"memory_modules,omitempty"`
+	Disks           []DiskDevice       `json:"disks,omitempty"`
+	NetworkAdapters []NetworkInterface `json:"network_adapters,omitempty"`
+}
+
+// MemoryModule is one installed memory stick
+type MemoryModule struct {
+	Slot         string `json:"slot"`
+	Manufacturer string `json:"manufacturer"`
+	Size         string `json:"size"`
+	Speed        string `json:"speed"`
+	SerialNumber string `json:"serial_number"`
+}
+
+// DiskDevice is a physical drive, as opposed to the partitions in Disk
+type DiskDevice struct {
+	Name         string  `json:"name"`
+	Model        string  `json:"model"`
+	SerialNumber string  `json:"serial_number"`
+	SizeGB       float64 `json:"size_gb"`
+}
+
+// NetworkInterface is a physical network adapter
+type NetworkInterface struct {
+	Name       string `json:"name"`
+	MacAddress string `json:"mac_address"`
+}
+
+// Hardware change kinds
+const (
+	ComponentCPU     = "cpu"
+	ComponentMemory  = "memory"
+	ComponentDisk    = "disk"
+	ComponentNetwork = "network"
+
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// HardwareChange is an event detected by comparing two inventories
+type HardwareChange struct {
+	ChangeID   int       `json:"change_id"`
+	ComputerID int       `json:"computer_id"`
+	DetectedAt time.Time `json:"detected_at"`
+	Component  string    `json:"component"`
+	Change     string    `json:"change"`
+	OldValue   string    `json:"old_value,omitempty"`
+	NewValue   string    `json:"new_value,omitempty"`
+}
+
+// Alert rule kinds, alert states and severities
+const (
+	AlertRuleThreshold = "threshold"
+	AlertRuleNoData    = "no_data"
+
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// AlertRule is evaluated periodically by the alerting engine. A threshold
+// rule fires when Metric compared with Threshold by Operator holds for
+// DurationSec, e.g. disk_usage > 90 for 600 seconds; a no_data rule fires
+// when a computer has sent no telemetry for DurationSec. A nil ComputerID
+// applies the rule to every computer. Sinks names the notification sinks,
+// empty for all configured ones.
+type AlertRule struct {
+	RuleID      int       `json:"rule_id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Metric      string    `json:"metric,omitempty"`
+	Operator    string    `json:"operator,omitempty"`
+	Threshold   float64   `json:"threshold"`
+	DurationSec int       `json:"duration_sec"`
+	ComputerID  *int      `json:"computer_id,omitempty"`
+	Severity    string    `json:"severity"`
+	Sinks       []string  `json:"sinks"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Alert is one firing episode of a rule on a computer. Series names the
+// disk or adapter for per-device metrics.
+type Alert struct {
+	AlertID    int        `json:"alert_id"`
+	RuleID     int        `json:"rule_id"`
+	RuleName   string     `json:"rule_name"`
+	Severity   string     `json:"severity"`
+	ComputerID int        `json:"computer_id"`
+	HostName   string     `json:"host_name"`
+	Series     string     `json:"series,omitempty"`
+	State      string     `json:"state"`
+	Value      float64    `json:"value"`
+	Message    string     `json:"message"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	TicketID   *int       `json:"ticket_id,omitempty"`
+}
+
+// Checks a monitoring target may run
+const (
+	CheckPing  = "ping"
+	CheckHTTP  = "http"
+	CheckTCP   = "tcp"
+	CheckTLS   = "tls"
+	CheckDNS   = "dns"
+	CheckTrace = "trace"
+)
+
+// CheckTypes lists every check a target may name
+var CheckTypes = []string{CheckPing, CheckHTTP, CheckTCP, CheckTLS, CheckDNS, CheckTrace}
+
+// MonitoringTarget is a server on the client's status tab. CheckTypes
+// selects the checks run against Host; IntervalSec is the auto-refresh
+// period. Disabled targets stay in the list but are not checked.
+//
+// The remaining fields configure individual checks. Zero values mean
+// http://Host for the HTTP check with any status below 400 accepted and
+// no body or latency assertion, port 443 with a 14 day warning for TLS and
+// the system resolver for DNS.
+type MonitoringTarget struct {
+	TargetID    int       `json:"target_id"`
+	Name        string    `json:"name"`
+	Host        string    `json:"host"`
+	Group       string    `json:"group"`
+	CheckTypes  []string  `json:"check_types"`
+	IntervalSec int       `json:"interval_sec"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	HTTPURL          string `json:"http_url"`
+	HTTPExpectStatus int    `json:"http_expect_status"`
+	HTTPExpectBody   string `json:"http_expect_body"`
+	HTTPMaxLatencyMs int    `json:"http_max_latency_ms"`
+	TCPPorts         []int  `json:"tcp_ports"`
+	TLSPort          int    `json:"tls_port"`
+	TLSWarnDays      int    `json:"tls_warn_days"`
+	DNSResolver      string `json:"dns_resolver"`
+}
+
+// CheckResult is the outcome of one check of a monitoring target. Summary
+// fits a table cell; Detail explains the result, one fact per line.
+type CheckResult struct {
+	Check     string `json:"check"`
+	OK        bool   `json:"ok"`
+	Summary   string `json:"summary"`
+	Detail    string `json:"detail,omitempty"`
+	LatencyMs int    `json:"latency_ms"`
+	// Code is the response status of an http check
+	Code int `json:"code,omitempty"`
+	// Method is how a ping or trace check probed the host, one of the
+	// Probe constants
+	Method string `json:"method,omitempty"`
+	// LossPct is the share of unanswered echo requests of a ping check
+	LossPct *float64 `json:"loss_pct,omitempty"`
+	// Hops is the route found by a trace check, starting at TTL 1
+	Hops []Hop `json:"hops,omitempty"`
+}
+
+// Probe methods of ping and trace checks: raw ICMP sockets, unprivileged
+// ICMP datagram sockets and TCP connects when ICMP is not permitted
+const (
+	ProbeICMP = "icmp"
+	ProbeUDP  = "udp"
+	ProbeTCP  = "tcp"
+)
+
+// Hop is one step of a traced route. Addr is empty and RTTMs null when
+// the hop did not answer or the probe method cannot identify it.
+type Hop struct {
+	TTL   int      `json:"ttl"`
+	Addr  string   `json:"addr,omitempty"`
+	RTTMs *float64 `json:"rtt_ms"`
+}
+
+// Statuses of a server check
+const (
+	CheckStatusUp          = "up"
+	CheckStatusDegraded    = "degraded"
+	CheckStatusDown        = "down"
+	CheckStatusMaintenance = "maintenance"
+)
+
+// ServerCheck is one stored check of a monitoring target. Status is down
+// when the target was unreachable and degraded when it was reachable but
+// another check failed; either becomes maintenance during a maintenance
+// window of the target, and a maintenance check counts as reachable.
+// LatencyMs and HTTPCode are null when the checks that measure them were
+// not run or failed.
+type ServerCheck struct {
+	CheckID   int64         `json:"check_id"`
+	TargetID  int           `json:"target_id"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Reachable bool          `json:"reachable"`
+	Status    string        `json:"status"`
+	LatencyMs *int          `json:"latency_ms"`
+	HTTPCode  *int          `json:"http_code"`
+	Results   []CheckResult `json:"results"`
+}
+
+// TargetStatus is the latest check of a monitoring target. Check is null
+// until the target has been checked; Maintenance is the maintenance window
+// the target is in, if any.
+type TargetStatus struct {
+	TargetID    int                `json:"target_id"`
+	Name        string             `json:"name"`
+	Host        string             `json:"host"`
+	Group       string             `json:"group"`
+	Enabled     bool               `json:"enabled"`
+	Check       *ServerCheck       `json:"check"`
+	Maintenance *MaintenanceWindow `json:"maintenance,omitempty"`
+}
+
+// MaintenanceWindow is planned downtime of a monitoring target, or of
+// every target in Group when TargetID is null. A one-off window lasts from
+// StartsAt to EndsAt; a recurring one starts whenever Schedule, a
+// five-field cron expression such as "0 2 * * 3" for 02:00 on Wednesdays,
+// matches in server local time and lasts DurationMin minutes.
+type MaintenanceWindow struct {
+	WindowID    int        `json:"window_id"`
+	TargetID    *int       `json:"target_id,omitempty"`
+	Group       string     `json:"group,omitempty"`
+	StartsAt    *time.Time `json:"starts_at,omitempty"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	Schedule    string     `json:"schedule,omitempty"`
+	DurationMin int        `json:"duration_min,omitempty"`
+	Reason      string     `json:"reason"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// StatusReport is returned by GET /status. Checker is set when the API
+// server runs the checks itself; otherwise the checks were recorded by
+// clients.
+type StatusReport struct {
+	Checker bool           `json:"checker"`
+	Targets []TargetStatus `json:"targets"`
+}
+
+// UptimeReport is the availability of a monitoring target. Uptime values
+// are percentages of reachable checks, null without checks in the period;
+// checks during maintenance are not counted.
+type UptimeReport struct {
+	TargetID  int      `json:"target_id"`
+	Uptime24h *float64 `json:"uptime_24h"`
+	Uptime7d  *float64 `json:"uptime_7d"`
+	Uptime30d *float64 `json:"uptime_30d"`
+	// Latency is the average latency of the last 24 hours in
+	// LatencyStepSec buckets; buckets without measurements are omitted
+	Latency        []LatencyPoint `json:"latency"`
+	LatencyStepSec int64          `json:"latency_step_sec"`
+	// Incidents are the outages of the last 30 days, newest first
+	Incidents []Incident `json:"incidents"`
+}
+
+// LatencyPoint is the average latency of one time bucket
+type LatencyPoint struct {
+	Time  time.Time `json:"time"`
+	AvgMs float64   `json:"avg_ms"`
+}
+
+// Incident is a run of consecutive down checks. End is the first check
+// after it that was not down, null while the outage lasts.
+type Incident struct {
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end"`
+	DurationSec int64      `json:"duration_sec"`
+	Checks      int        `json:"checks"`
+}
+
+// SoftwareChange reports a package installed on or removed from the computer
+type SoftwareChange struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Publisher string `json:"publisher"`
+	Installed string `json:"installed"`
+	Removed   bool   `json:"removed,omitempty"`
+}
+
+// IngestResult is returned by POST /ingest. Duplicate is set when the batch
+// had already been stored and nothing was written.
+type IngestResult struct {
+	BatchID   string `json:"batch_id"`
+	Duplicate bool   `json:"duplicate"`
+	Inserted  int    `json:"inserted"`
+}
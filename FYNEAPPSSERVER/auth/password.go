@@ -0,0 +1,163 @@
+// Package auth holds credential handling shared by the API server and the
+// desktop client.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2id parameters for newly hashed passwords. Stored hashes carry their
+// own parameters, so these can be raised later; older hashes are upgraded
+// on the next successful login.
+const (
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// MinPasswordLength is the shortest password accepted by ValidatePassword.
+const MinPasswordLength = 8
+
+var (
+	// ErrInvalidHash is returned for a malformed argon2id hash string.
+	ErrInvalidHash = errors.New("invalid password hash")
+	// ErrIncompatibleVersion is returned for an unsupported argon2 version.
+	ErrIncompatibleVersion = errors.New("incompatible argon2 version")
+)
+
+// HashPassword returns an argon2id hash in the standard PHC string format:
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword compares password with a stored value. Besides argon2id it
+// accepts bcrypt hashes and legacy plaintext rows; needsRehash reports that
+// the stored value should be replaced with a fresh HashPassword result.
+func CheckPassword(stored, password string) (ok, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(stored, "$argon2id$"):
+		return checkArgon2id(stored, password)
+
+	case strings.HasPrefix(stored, "$2a$"),
+		strings.HasPrefix(stored, "$2b$"),
+		strings.HasPrefix(stored, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(stored), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	}
+
+	// Legacy rows created before hashing was introduced
+	if stored == "" {
+		return false, false, nil
+	}
+	ok = subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	return ok, ok, nil
+}
+
+// IsHashed reports whether stored is a hash rather than a legacy plaintext
+// password.
+func IsHashed(stored string) bool {
+	return strings.HasPrefix(stored, "$argon2id$") ||
+		strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// ValidatePassword checks a new password against the minimal policy.
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+	}
+	if strings.TrimSpace(password) != password {
+		return errors.New("password must not start or end with whitespace")
+	}
+	return nil
+}
+
+// temporaryAlphabet omits characters that are easy to confuse when a
+// temporary password is read out over the phone.
+const temporaryAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateTemporaryPassword returns a random password for admin resets.
+func GenerateTemporaryPassword() (string, error) {
+	const length = 12
+
+	buf := make([]byte, length)
+	max := big.NewInt(int64(len(temporaryAlphabet)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = temporaryAlphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
+
+func checkArgon2id(stored, password string) (ok, needsRehash bool, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return false, false, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return false, false, ErrIncompatibleVersion
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrInvalidHash
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash = memory != argonMemory || time != argonTime ||
+		threads != argonThreads || len(key) != argonKeyLen
+	return true, needsRehash, nil
+}
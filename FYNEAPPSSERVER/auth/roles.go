@@ -0,0 +1,90 @@
+package auth
+
+import "fmt"
+
+// Role is stored in users.role.
+type Role string
+
+const (
+	RoleAdmin      Role = "admin"
+	RoleTechnician Role = "technician"
+	RoleViewer     Role = "viewer"
+)
+
+// Roles lists the known roles from most to least privileged.
+var Roles = []Role{RoleAdmin, RoleTechnician, RoleViewer}
+
+// Permission names a single action that can be granted to a role.
+type Permission string
+
+const (
+	PermTelemetryWrite  Permission = "telemetry:write"
+	PermInventoryView   Permission = "inventory:view"
+	PermInventoryWrite  Permission = "inventory:write"
+	PermInventoryDelete Permission = "inventory:delete"
+	PermSoftwareWrite   Permission = "software:write"
+	PermSoftwareDelete  Permission = "software:delete"
+	PermTicketsView     Permission = "tickets:view"
+	PermTicketsWrite    Permission = "tickets:write"
+	PermTicketsDelete   Permission = "tickets:delete"
+	PermUsersManage     Permission = "users:manage"
+	PermAPIKeysManage   Permission = "apikeys:manage"
+	PermAlertsManage    Permission = "alerts:manage"
+	PermTargetsManage   Permission = "targets:manage"
+)
+
+// rolePermissions is the single source of truth for both the client, which
+// hides disallowed actions, and the API middleware, which rejects them.
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermTelemetryWrite,
+		PermInventoryView, PermInventoryWrite, PermInventoryDelete,
+		PermSoftwareWrite, PermSoftwareDelete,
+		PermTicketsView, PermTicketsWrite, PermTicketsDelete,
+		PermUsersManage, PermAPIKeysManage,
+		PermAlertsManage, PermTargetsManage,
+	},
+	RoleTechnician: {
+		PermTelemetryWrite,
+		PermInventoryView, PermInventoryWrite,
+		PermSoftwareWrite,
+		PermTicketsView, PermTicketsWrite,
+	},
+	RoleViewer: {
+		PermInventoryView,
+		PermTicketsView,
+	},
+}
+
+// ParseRole validates a role name read from the DB or a request.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// Can reports whether the role grants the permission. Unknown roles are
+// granted nothing.
+func (r Role) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Title returns a human-readable role name for the client UI.
+func (r Role) Title() string {
+	switch r {
+	case RoleAdmin:
+		return "Администратор"
+	case RoleTechnician:
+		return "Техник"
+	case RoleViewer:
+		return "Наблюдатель"
+	}
+	return string(r)
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SessionTTL is the lifetime of a login token.
+const SessionTTL = 24 * time.Hour
+
+// APIKeyPrefix marks agent API keys so they can be told apart from session
+// tokens in the Authorization header.
+const APIKeyPrefix = "pgk_"
+
+// NewSessionToken returns an opaque login token and the hash stored in
+// user_sessions.session_id. Only the hash is persisted, so a leaked table
+// dump cannot be replayed.
+func NewSessionToken() (token, hash string, err error) {
+	token, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// NewAPIKey returns a key in the form pgk_<prefix>_<secret>, its short
+// public prefix used to identify the key in listings, and the stored hash.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	raw := make([]byte, 4)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(raw)
+	secret, err := randomString(32)
+	if err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + prefix + "_" + secret
+	return key, prefix, HashToken(key), nil
+}
+
+// IsAPIKey reports whether token looks like an agent API key.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// HashToken returns the hex SHA-256 of a token. Tokens are long random
+// strings, so a fast unsalted hash is sufficient here.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseScopes validates a list of permission names for an API key.
+func ParseScopes(names []string) ([]Permission, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	scopes := make([]Permission, 0, len(names))
+	for _, name := range names {
+		perm := Permission(name)
+		if !isKnownPermission(perm) {
+			return nil, fmt.Errorf("unknown scope %q", name)
+		}
+		if perm == PermUsersManage || perm == PermAPIKeysManage {
+			return nil, fmt.Errorf("scope %q cannot be granted to an API key", name)
+		}
+		scopes = append(scopes, perm)
+	}
+	return scopes, nil
+}
+
+func isKnownPermission(perm Permission) bool {
+	for _, perms := range rolePermissions {
+		for _, p := range perms {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
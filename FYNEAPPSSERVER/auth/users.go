@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SetPassword hashes password and stores it for the user. mustChange forces
+// the user to pick a new password at the next login (used by admin resets).
+// Resetting also ends every open session of the user.
+func SetPassword(db *sql.DB, userID int, password string, mustChange bool) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE users
+		SET password = $1, must_change_password = $2, password_changed_at = NOW()
+		WHERE id = $3`,
+		hash, mustChange, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if mustChange {
+		if _, err := tx.Exec("DELETE FROM user_sessions WHERE user_id = $1", userID); err != nil {
+			return fmt.Errorf("revoke sessions: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpgradeLegacyPassword replaces a plaintext or outdated hash after a
+// successful login. The stored value is compared again so a concurrent
+// password change is never overwritten.
+func UpgradeLegacyPassword(db *sql.DB, userID int, stored, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		"UPDATE users SET password = $1 WHERE id = $2 AND password = $3",
+		hash, userID, stored)
+	return err
+}
@@ -0,0 +1,247 @@
+// Package checker runs the checks of the monitoring targets on the API
+// server, so that every client sees the same results taken from one
+// network position. Each enabled target is checked on its own interval and
+// the results are stored with uptime.Record, which also publishes them to
+// the live status stream.
+//
+// Every server instance with the checker enabled checks all targets, so
+// it should be enabled on one instance only.
+package checker
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/checks"
+	"FYNEAPPSSERVER/uptime"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Bounds of a check interval, the same as the interval_sec constraint of
+// monitoring_targets. The interval is clamped to them, so that a row that
+// predates the constraint cannot stop the server.
+const (
+	minInterval = 5 * time.Second
+	maxInterval = 24 * time.Hour
+)
+
+// Config controls the scheduler
+type Config struct {
+	Enabled bool
+	// Reload is how often the target list is reread to pick up edits
+	Reload time.Duration
+	// Workers bounds the number of targets checked at the same time
+	Workers int
+}
+
+// ConfigFromEnv reads the checker settings:
+//
+//	CHECKER           false disables server-side checks, default true
+//	CHECKER_RELOAD    period of rereading the target list, default 30s
+//	CHECKER_WORKERS   targets checked at the same time, default 8
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Enabled: true, Reload: 30 * time.Second, Workers: 8}
+
+	switch strings.ToLower(os.Getenv("CHECKER")) {
+	case "", "1", "true", "yes":
+	case "0", "false", "no":
+		cfg.Enabled = false
+	default:
+		return Config{}, fmt.Errorf("invalid CHECKER %q: expected true or false", os.Getenv("CHECKER"))
+	}
+	if s := os.Getenv("CHECKER_RELOAD"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < time.Second {
+			return Config{}, fmt.Errorf("invalid CHECKER_RELOAD %q: expected a duration such as 30s", s)
+		}
+		cfg.Reload = d
+	}
+	if s := os.Getenv("CHECKER_WORKERS"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return Config{}, fmt.Errorf("invalid CHECKER_WORKERS %q: expected a positive number", s)
+		}
+		cfg.Workers = n
+	}
+	return cfg, nil
+}
+
+// TargetColumns are the monitoring_targets columns read by ScanTarget
+const TargetColumns = `target_id, name, host, group_name, check_types, interval_sec, enabled, created_at,
+	http_url, http_expect_status, http_expect_body, http_max_latency_ms, tcp_ports, tls_port, tls_warn_days, dns_resolver`
+
+// ScanTarget reads a row of TargetColumns
+func ScanTarget(row interface{ Scan(...interface{}) error }) (models.MonitoringTarget, error) {
+	var (
+		target models.MonitoringTarget
+		ports  pq.Int64Array
+	)
+	err := row.Scan(
+		&target.TargetID,
+		&target.Name,
+		&target.Host,
+		&target.Group,
+		pq.Array(&target.CheckTypes),
+		&target.IntervalSec,
+		&target.Enabled,
+		&target.CreatedAt,
+		&target.HTTPURL,
+		&target.HTTPExpectStatus,
+		&target.HTTPExpectBody,
+		&target.HTTPMaxLatencyMs,
+		&ports,
+		&target.TLSPort,
+		&target.TLSWarnDays,
+		&target.DNSResolver,
+	)
+	if target.CheckTypes == nil {
+		target.CheckTypes = []string{}
+	}
+	target.TCPPorts = make([]int, len(ports))
+	for i, port := range ports {
+		target.TCPPorts[i] = int(port)
+	}
+	return target, err
+}
+
+// Targets returns the enabled monitoring targets
+func Targets(ctx context.Context, db *sql.DB) ([]models.MonitoringTarget, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+TargetColumns+" FROM monitoring_targets WHERE enabled ORDER BY target_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []models.MonitoringTarget
+	for rows.Next() {
+		target, err := ScanTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+// Scheduler checks every enabled target on its interval
+type Scheduler struct {
+	db      *sql.DB
+	reload  time.Duration
+	workers chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler with the settings from cfg
+func NewScheduler(db *sql.DB, cfg Config) *Scheduler {
+	return &Scheduler{db: db, reload: cfg.Reload, workers: make(chan struct{}, cfg.Workers)}
+}
+
+// job is the running check loop of one target
+type job struct {
+	target models.MonitoringTarget
+	cancel context.CancelFunc
+}
+
+// Run reads the target list immediately and then every reload period,
+// keeping one check loop per enabled target, until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("checker: checking targets with %d workers, reloading the list every %s", cap(s.workers), s.reload)
+
+	jobs := make(map[int]*job)
+	defer func() {
+		for _, j := range jobs {
+			j.cancel()
+		}
+		s.wg.Wait()
+	}()
+
+	ticker := time.NewTicker(s.reload)
+	defer ticker.Stop()
+	for {
+		targets, err := Targets(ctx, s.db)
+		switch {
+		case err == nil:
+			s.sync(ctx, jobs, targets)
+		case ctx.Err() == nil:
+			log.Printf("checker: loading targets: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sync starts a loop for every new or edited target and stops the loops
+// of edited, disabled and deleted ones
+func (s *Scheduler) sync(ctx context.Context, jobs map[int]*job, targets []models.MonitoringTarget) {
+	seen := make(map[int]bool, len(targets))
+	for _, target := range targets {
+		seen[target.TargetID] = true
+		if j, ok := jobs[target.TargetID]; ok {
+			if reflect.DeepEqual(j.target, target) {
+				continue
+			}
+			j.cancel()
+		}
+
+		jobCtx, cancel := context.WithCancel(ctx)
+		jobs[target.TargetID] = &job{target: target, cancel: cancel}
+		s.wg.Add(1)
+		go s.watch(jobCtx, target)
+	}
+	for id, j := range jobs {
+		if !seen[id] {
+			j.cancel()
+			delete(jobs, id)
+		}
+	}
+}
+
+// watch checks target immediately and then every IntervalSec until ctx is
+// done
+func (s *Scheduler) watch(ctx context.Context, target models.MonitoringTarget) {
+	defer s.wg.Done()
+	interval := min(max(time.Duration(target.IntervalSec)*time.Second, minInterval), maxInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.check(ctx, target)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check runs the checks of target once a worker is free and stores the
+// result. A check interrupted by an edit of the target or by shutdown is
+// not stored.
+func (s *Scheduler) check(ctx context.Context, target models.MonitoringTarget) {
+	select {
+	case s.workers <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-s.workers }()
+
+	results := checks.Run(ctx, target, target.CheckTypes)
+	if ctx.Err() != nil {
+		return
+	}
+	check := checks.Summarize(target, results)
+	if err := uptime.Record(ctx, s.db, &check); err != nil && ctx.Err() == nil {
+		log.Printf("checker: target %d %q: %v", target.TargetID, target.Name, err)
+	}
+}
@@ -0,0 +1,361 @@
+// Package checks probes monitoring targets: ICMP echo and route, TCP
+// ports, TLS certificates, DNS resolution and HTTP responses. Every check returns a
+// models.CheckResult and never an error; a failure is a result with OK
+// unset.
+package checks
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults applied to zero fields of models.MonitoringTarget
+const (
+	DefaultTLSPort     = 443
+	DefaultTLSWarnDays = 14
+)
+
+const (
+	// dialTimeout bounds a TCP connect, TLS handshake or DNS query
+	dialTimeout = 5 * time.Second
+	// httpTimeout bounds a whole HTTP request
+	httpTimeout = 10 * time.Second
+	// maxBody is how much of an HTTP response is searched for the
+	// expected substring
+	maxBody = 1 << 20
+)
+
+// Run performs the checks of target named in checks concurrently. Unknown
+// checks are skipped.
+func Run(ctx context.Context, target models.MonitoringTarget, checks []string) map[string]models.CheckResult {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]models.CheckResult, len(checks))
+	)
+	for _, check := range checks {
+		var run func() models.CheckResult
+		switch check {
+		case models.CheckPing:
+			run = func() models.CheckResult { return Ping(ctx, target.Host) }
+		case models.CheckTrace:
+			run = func() models.CheckResult { return Trace(ctx, target.Host) }
+		case models.CheckHTTP:
+			run = func() models.CheckResult { return HTTP(ctx, target) }
+		case models.CheckTCP:
+			run = func() models.CheckResult { return TCP(ctx, target.Host, target.TCPPorts) }
+		case models.CheckTLS:
+			run = func() models.CheckResult { return TLS(ctx, target.Host, target.TLSPort, target.TLSWarnDays) }
+		case models.CheckDNS:
+			run = func() models.CheckResult { return DNS(ctx, target.Host, target.DNSResolver) }
+		default:
+			continue
+		}
+		wg.Add(1)
+		go func(check string) {
+			defer wg.Done()
+			result := run()
+			mu.Lock()
+			results[check] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+	return results
+}
+
+// Summarize builds the stored check of target from results. The target is
+// down when the first check run of ping, TCP, HTTP, TLS, DNS and trace
+// failed, and degraded when it is reachable but another check failed. The
+// latency is that of the first passed check in the same order without
+// trace.
+func Summarize(target models.MonitoringTarget, results map[string]models.CheckResult) models.ServerCheck {
+	check := models.ServerCheck{TargetID: target.TargetID, Status: models.CheckStatusUp}
+	order := []string{models.CheckPing, models.CheckTCP, models.CheckHTTP, models.CheckTLS, models.CheckDNS, models.CheckTrace}
+	for _, name := range order {
+		if result, ok := results[name]; ok {
+			if !result.OK {
+				check.Status = models.CheckStatusDown
+			}
+			break
+		}
+	}
+	for _, result := range results {
+		if !result.OK && check.Status == models.CheckStatusUp {
+			check.Status = models.CheckStatusDegraded
+		}
+	}
+
+	for _, name := range models.CheckTypes {
+		if result, ok := results[name]; ok {
+			check.Results = append(check.Results, result)
+		}
+	}
+	for _, name := range order[:len(order)-1] {
+		if result, ok := results[name]; ok && result.OK {
+			latency := result.LatencyMs
+			check.LatencyMs = &latency
+			break
+		}
+	}
+	if result, ok := results[models.CheckHTTP]; ok && result.Code != 0 {
+		code := result.Code
+		check.HTTPCode = &code
+	}
+	return check
+}
+
+// TCP connects to every port of host. The check passes when all ports
+// accept a connection.
+func TCP(ctx context.Context, host string, ports []int) models.CheckResult {
+	result := models.CheckResult{Check: models.CheckTCP}
+	if len(ports) == 0 {
+		result.Summary = "no ports"
+		result.Detail = "no TCP ports configured"
+		return result
+	}
+
+	type portResult struct {
+		port    int
+		err     error
+		latency time.Duration
+	}
+	results := make([]portResult, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i, port int) {
+			defer wg.Done()
+			dialer := net.Dialer{Timeout: dialTimeout}
+			start := time.Now()
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err == nil {
+				conn.Close()
+			}
+			results[i] = portResult{port: port, err: err, latency: time.Since(start)}
+		}(i, port)
+	}
+	wg.Wait()
+
+	var closed, lines []string
+	for _, r := range results {
+		if r.err != nil {
+			closed = append(closed, strconv.Itoa(r.port))
+			lines = append(lines, fmt.Sprintf("%d: closed (%v)", r.port, r.err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d: open, %d ms", r.port, r.latency.Milliseconds()))
+		result.LatencyMs = max(result.LatencyMs, int(r.latency.Milliseconds()))
+	}
+
+	result.OK = len(closed) == 0
+	result.Detail = strings.Join(lines, "\n")
+	if result.OK {
+		result.Summary = fmt.Sprintf("%d/%d open", len(ports), len(ports))
+	} else {
+		result.Summary = "closed " + strings.Join(closed, ",")
+	}
+	return result
+}
+
+// TLS performs a handshake with host:port and reports the days left until
+// the first certificate of the chain expires. The check fails when the
+// chain does not verify for host or expires within warnDays.
+func TLS(ctx context.Context, host string, port, warnDays int) models.CheckResult {
+	result := models.CheckResult{Check: models.CheckTLS}
+	if port == 0 {
+		port = DefaultTLSPort
+	}
+	if warnDays == 0 {
+		warnDays = DefaultTLSWarnDays
+	}
+
+	// Verification is done below, so that an untrusted or expired
+	// certificate still reports its dates
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: dialTimeout},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		result.Summary = "no TLS"
+		result.Detail = fmt.Sprintf("handshake with port %d failed: %v", port, err)
+		return result
+	}
+	defer conn.Close()
+	result.LatencyMs = int(time.Since(start).Milliseconds())
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		result.Summary = "no cert"
+		result.Detail = "server sent no certificate"
+		return result
+	}
+
+	expiring := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(expiring.NotAfter) {
+			expiring = cert
+		}
+	}
+	days := int(time.Until(expiring.NotAfter).Hours() / 24)
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, verifyErr := certs[0].Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+
+	lines := []string{
+		fmt.Sprintf("subject: %s", certs[0].Subject.CommonName),
+		fmt.Sprintf("issuer: %s", certs[0].Issuer.CommonName),
+		fmt.Sprintf("chain expires: %s (%s)", expiring.NotAfter.Format("2006-01-02"), expiring.Subject.CommonName),
+	}
+	switch {
+	case days < 0:
+		result.Summary = "expired"
+	case verifyErr != nil:
+		result.Summary = "untrusted"
+	default:
+		result.Summary = fmt.Sprintf("%d days", days)
+		result.OK = days >= warnDays
+	}
+	if verifyErr != nil {
+		lines = append(lines, fmt.Sprintf("verification: %v", verifyErr))
+	} else if !result.OK {
+		lines = append(lines, fmt.Sprintf("expires within %d days", warnDays))
+	}
+	result.Detail = strings.Join(lines, "\n")
+	return result
+}
+
+// DNS resolves host through resolver ("10.0.0.1" or "10.0.0.1:53"), or
+// the system resolver if it is empty. An IP address is resolved in
+// reverse. Names listed in the local hosts file are answered from it
+// without a query, as with the system resolver.
+func DNS(ctx context.Context, host, resolver string) models.CheckResult {
+	result := models.CheckResult{Check: models.CheckDNS}
+
+	r := net.DefaultResolver
+	via := "system resolver"
+	if resolver != "" {
+		addr := resolver
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "53")
+		}
+		via = addr
+		r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialer := net.Dialer{Timeout: dialTimeout}
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	start := time.Now()
+	var (
+		answers []string
+		err     error
+	)
+	if net.ParseIP(host) != nil {
+		answers, err = r.LookupAddr(ctx, host)
+	} else {
+		answers, err = r.LookupHost(ctx, host)
+	}
+	result.LatencyMs = int(time.Since(start).Milliseconds())
+
+	if err != nil {
+		result.Summary = "failed"
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			result.Summary = "not found"
+		}
+		result.Detail = fmt.Sprintf("%s via %s: %v", host, via, err)
+		return result
+	}
+
+	sort.Strings(answers)
+	result.OK = true
+	result.Summary = fmt.Sprintf("%d ms", result.LatencyMs)
+	result.Detail = fmt.Sprintf("%s via %s: %s", host, via, strings.Join(answers, ", "))
+	return result
+}
+
+// HTTP requests target.HTTPURL, or http://Host if it is empty, and checks
+// the status code, body substring and latency budget of the target.
+func HTTP(ctx context.Context, target models.MonitoringTarget) models.CheckResult {
+	result := models.CheckResult{Check: models.CheckHTTP}
+
+	url := target.HTTPURL
+	if url == "" {
+		url = "http://" + target.Host
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.Summary = "No HTTP"
+		result.Detail = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		result.Summary = "No HTTP"
+		result.Detail = fmt.Sprintf("GET %s: %v", url, err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	if target.HTTPExpectBody != "" {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	}
+	latency := time.Since(start)
+	result.LatencyMs = int(latency.Milliseconds())
+	result.Code = resp.StatusCode
+	result.Summary = fmt.Sprintf("HTTP %d", resp.StatusCode)
+
+	lines := []string{fmt.Sprintf("GET %s: %s, %d ms", url, resp.Status, result.LatencyMs)}
+	var failed []string
+	if target.HTTPExpectStatus != 0 {
+		if resp.StatusCode != target.HTTPExpectStatus {
+			failed = append(failed, fmt.Sprintf("expected status %d", target.HTTPExpectStatus))
+		}
+	} else if resp.StatusCode >= 400 {
+		failed = append(failed, "status 400 or above")
+	}
+	if target.HTTPExpectBody != "" {
+		switch {
+		case err != nil:
+			failed = append(failed, fmt.Sprintf("reading body: %v", err))
+		case !strings.Contains(string(body), target.HTTPExpectBody):
+			failed = append(failed, fmt.Sprintf("body does not contain %q", target.HTTPExpectBody))
+		}
+	}
+	if target.HTTPMaxLatencyMs > 0 && result.LatencyMs > target.HTTPMaxLatencyMs {
+		failed = append(failed, fmt.Sprintf("slower than %d ms", target.HTTPMaxLatencyMs))
+		result.Summary += " slow"
+	}
+
+	result.OK = len(failed) == 0
+	result.Detail = strings.Join(append(lines, failed...), "\n")
+	return result
+}
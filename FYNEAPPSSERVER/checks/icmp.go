@@ -0,0 +1,544 @@
+package checks
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// pingCount is how many echo requests a ping check sends
+	pingCount = 3
+	// probeTimeout bounds the wait for one echo reply or TCP answer
+	probeTimeout = time.Second
+	// traceMaxHops is the largest TTL a trace check probes
+	traceMaxHops = 16
+	// traceWait bounds how long a trace waits for the answers of all hops
+	traceWait = 3 * time.Second
+)
+
+// fallbackPorts are connected to when the process may not open ICMP
+// sockets. A refused connection answers as well as an accepted one: the
+// host replied with a reset.
+var fallbackPorts = []int{443, 80}
+
+// echoID is the identifier of the next raw ICMP socket. Raw sockets
+// receive every ICMP message of the host, so concurrent checks tell their
+// answers apart by it.
+var echoID atomic.Uint32
+
+func init() {
+	echoID.Store(uint32(os.Getpid()))
+}
+
+// PingStats are the answers to the echo requests sent by Echo
+type PingStats struct {
+	Addr   net.IP
+	Method string
+	Sent   int
+	// RTTs are the round trips of the answered requests
+	RTTs []time.Duration
+}
+
+// Loss is the percentage of unanswered requests
+func (s PingStats) Loss() float64 {
+	if s.Sent == 0 {
+		return 100
+	}
+	return 100 * float64(s.Sent-len(s.RTTs)) / float64(s.Sent)
+}
+
+// Avg is the average round trip, zero without answers
+func (s PingStats) Avg() time.Duration {
+	if len(s.RTTs) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, rtt := range s.RTTs {
+		sum += rtt
+	}
+	return sum / time.Duration(len(s.RTTs))
+}
+
+// Route is the path to a host found by Traceroute. Reached is unset when
+// the destination did not answer within the probed hops or reported
+// itself unreachable; Hops then ends at the last probed or the reporting
+// hop.
+type Route struct {
+	Addr    net.IP
+	Method  string
+	Hops    []models.Hop
+	Reached bool
+}
+
+// Ping sends echo requests to host. The check passes when any request is
+// answered; LatencyMs is the average round trip.
+func Ping(ctx context.Context, host string) models.CheckResult {
+	result := models.CheckResult{Check: models.CheckPing, Summary: "Timeout"}
+	stats, err := Echo(ctx, host, pingCount)
+	result.Method = stats.Method
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	loss := stats.Loss()
+	result.LossPct = &loss
+
+	lines := []string{fmt.Sprintf("%s via %s: %d sent, %d received, %.0f%% loss",
+		stats.Addr, stats.Method, stats.Sent, len(stats.RTTs), loss)}
+	if len(stats.RTTs) > 0 {
+		minRTT, maxRTT := stats.RTTs[0], stats.RTTs[0]
+		for _, rtt := range stats.RTTs[1:] {
+			minRTT, maxRTT = min(minRTT, rtt), max(maxRTT, rtt)
+		}
+		lines = append(lines, fmt.Sprintf("rtt min/avg/max: %s/%s/%s ms",
+			formatMs(minRTT), formatMs(stats.Avg()), formatMs(maxRTT)))
+
+		result.OK = true
+		result.LatencyMs = int(stats.Avg().Milliseconds())
+		result.Summary = fmt.Sprintf("%d ms", result.LatencyMs)
+	}
+	result.Detail = strings.Join(lines, "\n")
+	return result
+}
+
+// Trace finds the route to host. The check passes when the destination
+// answered; LatencyMs is its round trip.
+func Trace(ctx context.Context, host string) models.CheckResult {
+	result := models.CheckResult{Check: models.CheckTrace, Summary: "Trace err"}
+	route, err := Traceroute(ctx, host, traceMaxHops)
+	result.Method = route.Method
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	result.Hops = route.Hops
+
+	lines := []string{fmt.Sprintf("%s via %s", route.Addr, route.Method)}
+	for _, hop := range route.Hops {
+		if hop.RTTMs == nil {
+			lines = append(lines, fmt.Sprintf("%d: *", hop.TTL))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d: %s %.1f ms", hop.TTL, hop.Addr, *hop.RTTMs))
+	}
+	if route.Reached {
+		result.OK = true
+		result.LatencyMs = int(*route.Hops[len(route.Hops)-1].RTTMs)
+		result.Summary = fmt.Sprintf("%d hops", len(route.Hops))
+	} else {
+		result.Summary = "unreached"
+		lines = append(lines, fmt.Sprintf("destination did not answer within %d hops", len(route.Hops)))
+	}
+	result.Detail = strings.Join(lines, "\n")
+	return result
+}
+
+// Echo sends count ICMP echo requests to host one after another, waiting
+// up to a second for each answer. Without permission for raw or
+// unprivileged ICMP sockets it times TCP connects to fallbackPorts
+// instead. An error is returned only when host cannot be resolved or a
+// request cannot be sent.
+func Echo(ctx context.Context, host string, count int) (PingStats, error) {
+	ip, err := resolve(ctx, host)
+	if err != nil {
+		return PingStats{}, err
+	}
+	stats := PingStats{Addr: ip}
+
+	c, err := listenICMP(ip)
+	if err != nil {
+		stats.Method = models.ProbeTCP
+		for i := 0; i < count && ctx.Err() == nil; i++ {
+			stats.Sent++
+			if rtt, ok := tcpProbe(ctx, ip, 0, probeTimeout); ok {
+				stats.RTTs = append(stats.RTTs, rtt)
+			}
+		}
+		return stats, nil
+	}
+	defer c.conn.Close()
+	stats.Method = c.method
+
+	for seq := 1; seq <= count && ctx.Err() == nil; seq++ {
+		start := time.Now()
+		if err := c.send(seq, 0); err != nil {
+			return stats, err
+		}
+		stats.Sent++
+		for {
+			answer, err := c.read(ctx, start.Add(probeTimeout))
+			if err != nil {
+				break
+			}
+			if answer.seq == seq && answer.kind == answerReply {
+				stats.RTTs = append(stats.RTTs, answer.at.Sub(start))
+				break
+			}
+		}
+	}
+	return stats, nil
+}
+
+// Traceroute sends one echo request per TTL from 1 to maxHops at once and
+// collects the Time Exceeded messages of the routers and the reply of the
+// destination. Unprivileged ICMP sockets do not receive Time Exceeded, so
+// through them and through the TCP fallback only the hop count and the
+// destination are known and the other hops have no address.
+func Traceroute(ctx context.Context, host string, maxHops int) (Route, error) {
+	ip, err := resolve(ctx, host)
+	if err != nil {
+		return Route{}, err
+	}
+	route := Route{Addr: ip}
+
+	c, err := listenICMP(ip)
+	if err != nil {
+		route.Method = models.ProbeTCP
+		route.Hops, route.Reached = traceTCP(ctx, ip, maxHops)
+		return route, nil
+	}
+	defer c.conn.Close()
+	route.Method = c.method
+
+	hops := make([]models.Hop, maxHops)
+	sent := make([]time.Time, maxHops)
+	for i := range hops {
+		hops[i].TTL = i + 1
+		sent[i] = time.Now()
+		if err := c.send(i+1, i+1); err != nil {
+			return route, err
+		}
+	}
+
+	// last is the lowest TTL known to end the route: the destination
+	// replies to every request that reaches it
+	last := maxHops
+	deadline := time.Now().Add(traceWait)
+	for !answered(hops[:last]) {
+		answer, err := c.read(ctx, deadline)
+		if err != nil {
+			break
+		}
+		if answer.seq < 1 || answer.seq > maxHops {
+			continue
+		}
+		hop := &hops[answer.seq-1]
+		if hop.RTTMs == nil {
+			rtt := msFloat(answer.at.Sub(sent[answer.seq-1]))
+			hop.Addr, hop.RTTMs = answer.from.String(), &rtt
+		}
+		if answer.kind != answerTimeExceeded && answer.seq <= last {
+			last = answer.seq
+			route.Reached = answer.kind == answerReply
+		}
+	}
+	route.Hops = hops[:last]
+	return route, nil
+}
+
+// answered reports whether every hop has answered
+func answered(hops []models.Hop) bool {
+	for _, hop := range hops {
+		if hop.RTTMs == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// traceTCP connects to ip with every TTL from 1 to maxHops at once. The
+// lowest TTL that gets an answer is the distance to the host; the routers
+// before it are not identified.
+func traceTCP(ctx context.Context, ip net.IP, maxHops int) ([]models.Hop, bool) {
+	type probe struct {
+		ttl int
+		rtt time.Duration
+		ok  bool
+	}
+	probes := make(chan probe, maxHops)
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		go func(ttl int) {
+			rtt, ok := tcpProbe(ctx, ip, ttl, traceWait)
+			probes <- probe{ttl: ttl, rtt: rtt, ok: ok}
+		}(ttl)
+	}
+
+	var reached *probe
+	for range maxHops {
+		p := <-probes
+		if p.ok && (reached == nil || p.ttl < reached.ttl) {
+			reached = &p
+		}
+	}
+
+	hops := make([]models.Hop, maxHops)
+	for i := range hops {
+		hops[i].TTL = i + 1
+	}
+	if reached == nil {
+		return hops, false
+	}
+	rtt := msFloat(reached.rtt)
+	hops = hops[:reached.ttl]
+	hops[reached.ttl-1].Addr, hops[reached.ttl-1].RTTMs = ip.String(), &rtt
+	return hops, true
+}
+
+// tcpProbe connects to ip on all fallbackPorts at once and returns the
+// time until the first answer. ttl > 0 limits the hops the SYN may pass.
+func tcpProbe(ctx context.Context, ip net.IP, ttl int, timeout time.Duration) (time.Duration, bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	if ttl > 0 {
+		dialer.Control = func(_, _ string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) { err = setTTL(fd, ip.To4() == nil, ttl) }); cerr != nil {
+				return cerr
+			}
+			return err
+		}
+	}
+
+	answers := make(chan time.Duration, len(fallbackPorts))
+	start := time.Now()
+	for _, port := range fallbackPorts {
+		go func(port int) {
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+			switch {
+			case err == nil:
+				conn.Close()
+			case !errors.Is(err, errConnRefused):
+				answers <- -1
+				return
+			}
+			answers <- time.Since(start)
+		}(port)
+	}
+	for range fallbackPorts {
+		if rtt := <-answers; rtt >= 0 {
+			return rtt, true
+		}
+	}
+	return 0, false
+}
+
+// resolve returns an address of host, preferring IPv4
+func resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s has no addresses", host)
+	}
+	return addrs[0].IP, nil
+}
+
+// Kinds of ICMP answers to an echo request
+const (
+	answerReply = iota
+	answerTimeExceeded
+	answerUnreachable
+)
+
+// echoAnswer is an ICMP message answering the echo request seq
+type echoAnswer struct {
+	seq  int
+	kind int
+	from net.IP
+	at   time.Time
+}
+
+// icmpConn sends echo requests to one address and reads the answers to
+// them
+type icmpConn struct {
+	conn   *icmp.PacketConn
+	method string
+	ip     net.IP
+	dst    net.Addr
+	v6     bool
+	// id identifies the requests of a raw socket; the kernel replaces it
+	// for unprivileged sockets and delivers them only their own answers
+	id int
+}
+
+// listenICMP opens a raw ICMP socket for ip, or an unprivileged ICMP
+// datagram socket if the process may not open raw sockets
+func listenICMP(ip net.IP) (*icmpConn, error) {
+	c := &icmpConn{ip: ip, v6: ip.To4() == nil}
+	raw, udp, addr := "ip4:icmp", "udp4", "0.0.0.0"
+	if c.v6 {
+		raw, udp, addr = "ip6:ipv6-icmp", "udp6", "::"
+	}
+
+	conn, err := icmp.ListenPacket(raw, addr)
+	if err == nil {
+		c.conn, c.method, c.dst = conn, models.ProbeICMP, &net.IPAddr{IP: ip}
+		c.id = int(echoID.Add(1) & 0xffff)
+		return c, nil
+	}
+	conn, err = icmp.ListenPacket(udp, addr)
+	if err != nil {
+		return nil, err
+	}
+	c.conn, c.method, c.dst = conn, models.ProbeUDP, &net.UDPAddr{IP: ip}
+	return c, nil
+}
+
+// send sends the echo request seq; ttl > 0 limits the hops it may pass
+func (c *icmpConn) send(seq, ttl int) error {
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if c.v6 {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+	if ttl > 0 {
+		var err error
+		if c.v6 {
+			err = c.conn.IPv6PacketConn().SetHopLimit(ttl)
+		} else {
+			err = c.conn.IPv4PacketConn().SetTTL(ttl)
+		}
+		if err != nil {
+			return fmt.Errorf("setting TTL %d: %w", ttl, err)
+		}
+	}
+
+	// The kernel computes the ICMPv6 checksum, so no pseudo header is
+	// needed
+	msg := icmp.Message{Type: typ, Body: &icmp.Echo{ID: c.id, Seq: seq, Data: []byte("FYNEAPPS")}}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	if _, err := c.conn.WriteTo(b, c.dst); err != nil {
+		return fmt.Errorf("sending echo request to %s: %w", c.ip, err)
+	}
+	return nil
+}
+
+// read returns the next answer to a request of c. Other ICMP messages are
+// skipped; an error is returned at deadline or when ctx is done.
+func (c *icmpConn) read(ctx context.Context, deadline time.Time) (echoAnswer, error) {
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return echoAnswer{}, err
+	}
+	proto := ipv4.ICMPTypeEcho.Protocol()
+	if c.v6 {
+		proto = ipv6.ICMPTypeEchoRequest.Protocol()
+	}
+
+	buf := make([]byte, 1500)
+	for ctx.Err() == nil {
+		n, peer, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			return echoAnswer{}, err
+		}
+		at := time.Now()
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		answer := echoAnswer{from: peerIP(peer), at: at}
+
+		switch body := msg.Body.(type) {
+		case *icmp.Echo:
+			if msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply {
+				continue
+			}
+			if !answer.from.Equal(c.ip) || (c.method == models.ProbeICMP && body.ID != c.id) {
+				continue
+			}
+			answer.seq, answer.kind = body.Seq, answerReply
+		case *icmp.TimeExceeded:
+			seq, ok := c.quoted(body.Data)
+			if !ok {
+				continue
+			}
+			answer.seq, answer.kind = seq, answerTimeExceeded
+		case *icmp.DstUnreach:
+			seq, ok := c.quoted(body.Data)
+			if !ok {
+				continue
+			}
+			answer.seq, answer.kind = seq, answerUnreachable
+		default:
+			continue
+		}
+		return answer, nil
+	}
+	return echoAnswer{}, ctx.Err()
+}
+
+// quoted returns the sequence number of a request of c quoted in an ICMP
+// error message: the original IP header followed by the first eight bytes
+// of the echo request.
+func (c *icmpConn) quoted(data []byte) (int, bool) {
+	var header int
+	var dst net.IP
+	if c.v6 {
+		if len(data) < ipv6.HeaderLen {
+			return 0, false
+		}
+		header, dst = ipv6.HeaderLen, net.IP(data[24:40])
+	} else {
+		if len(data) < ipv4.HeaderLen {
+			return 0, false
+		}
+		header, dst = int(data[0]&0x0f)<<2, net.IP(data[16:20])
+	}
+	if len(data) < header+8 || !dst.Equal(c.ip) {
+		return 0, false
+	}
+	echo := data[header : header+8]
+	if c.method == models.ProbeICMP && int(binary.BigEndian.Uint16(echo[4:6])) != c.id {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint16(echo[6:8])), true
+}
+
+func peerIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.IPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+	return nil
+}
+
+func msFloat(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func formatMs(d time.Duration) string {
+	return strconv.FormatFloat(msFloat(d), 'f', 1, 64)
+}
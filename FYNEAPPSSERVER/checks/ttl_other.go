@@ -0,0 +1,15 @@
+//go:build !windows
+
+package checks
+
+import "syscall"
+
+const errConnRefused = syscall.ECONNREFUSED
+
+// setTTL limits the hops of packets sent through the socket fd
+func setTTL(fd uintptr, v6 bool, ttl int) error {
+	if v6 {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+}
@@ -0,0 +1,14 @@
+package checks
+
+import "syscall"
+
+// errConnRefused is WSAECONNREFUSED, which syscall does not name
+const errConnRefused = syscall.Errno(10061)
+
+// setTTL limits the hops of packets sent through the socket fd
+func setTTL(fd uintptr, v6 bool, ttl int) error {
+	if v6 {
+		return syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+	}
+	return syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+}
@@ -0,0 +1,115 @@
+package ingest
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// RegisterComputer finds the computer by its machine UUID and refreshes its
+// details, or creates it. A computer that reports a new host name keeps its
+// ID and the name is added to computer_hostnames. Rows created before machine
+// UUIDs existed are claimed by the first machine reporting the same host
+// name. Without a machine UUID (older clients) the computer is matched by
+// host name, user and OS as before. created reports whether a row was
+// inserted.
+func RegisterComputer(ctx context.Context, db *sql.DB, c models.Computer) (id int, created bool, err error) {
+	if c.MachineUUID != "" {
+		c.MachineUUID = strings.ToLower(c.MachineUUID)
+		if !uuidPattern.MatchString(c.MachineUUID) {
+			return 0, false, &ValidationError{"machine_uuid is not a valid UUID"}
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	if c.MachineUUID != "" {
+		err = tx.QueryRowContext(ctx, `
+			SELECT computer_id FROM computers WHERE machine_uuid = $1 FOR UPDATE`,
+			c.MachineUUID).Scan(&id)
+		if err == sql.ErrNoRows {
+			err = tx.QueryRowContext(ctx, `
+				SELECT computer_id FROM computers
+				WHERE host_name = $1 AND machine_uuid IS NULL
+				ORDER BY computer_id
+				LIMIT 1
+				FOR UPDATE`,
+				c.HostName).Scan(&id)
+		}
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			SELECT computer_id FROM computers
+			WHERE host_name = $1 AND user_name = $2 AND os_name = $3 AND os_version = $4
+			ORDER BY computer_id
+			LIMIT 1`,
+			c.HostName, c.UserName, c.OsName, c.OsVersion).Scan(&id)
+	}
+
+	switch {
+	case err == sql.ErrNoRows:
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO computers (
+				machine_uuid, host_name, user_name, os_name, os_version, os_platform,
+				os_architecture, kernel_version, uptime, process_count,
+				boot_time, home_directory, gid, uid
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			RETURNING computer_id`,
+			nullIfEmpty(c.MachineUUID), c.HostName, c.UserName, c.OsName, c.OsVersion, c.OsPlatform,
+			c.OsArchitecture, c.KernelVersion, c.Uptime, c.ProcessCount,
+			c.BootTime, c.HomeDirectory, c.Gid, c.Uid,
+		).Scan(&id)
+		if err != nil {
+			return 0, false, err
+		}
+		created = true
+	case err != nil:
+		return 0, false, err
+	default:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE computers SET
+				machine_uuid = COALESCE($1, machine_uuid),
+				host_name = $2,
+				user_name = $3,
+				os_name = $4,
+				os_version = $5,
+				os_platform = $6,
+				os_architecture = $7,
+				kernel_version = $8,
+				uptime = $9,
+				process_count = $10,
+				boot_time = $11,
+				home_directory = $12,
+				gid = $13,
+				uid = $14
+			WHERE computer_id = $15`,
+			nullIfEmpty(c.MachineUUID), c.HostName, c.UserName, c.OsName, c.OsVersion, c.OsPlatform,
+			c.OsArchitecture, c.KernelVersion, c.Uptime, c.ProcessCount,
+			c.BootTime, c.HomeDirectory, c.Gid, c.Uid, id,
+		)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO computer_hostnames (computer_id, host_name)
+		VALUES ($1, $2)
+		ON CONFLICT (computer_id, host_name) DO UPDATE SET last_seen = CURRENT_TIMESTAMP`,
+		id, c.HostName)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return id, created, nil
+}
@@ -0,0 +1,274 @@
+// Package ingest stores telemetry batches. It backs POST /api/v1/ingest and
+// is also used by clients that write straight to the database, so both paths
+// deduplicate on the same batch IDs.
+package ingest
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/inventory"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Batch limits
+const (
+	MaxBatchIDLength = 64
+	MaxSamples       = 50000
+
+	// PostgreSQL accepts at most 65535 bind parameters per statement
+	maxParams = 65535
+)
+
+// ErrUnknownComputer is returned when the batch refers to a missing computer
+var ErrUnknownComputer = errors.New("computer not found")
+
+// ValidationError describes a malformed batch
+type ValidationError struct {
+	msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.msg
+}
+
+// SampleCount returns the number of rows the batch carries
+func SampleCount(b *models.IngestBatch) int {
+	n := len(b.Processors) + len(b.Memory) + len(b.Disks) + len(b.Network) + len(b.Software)
+	if b.Inventory != nil {
+		n++
+	}
+	return n
+}
+
+// Validate checks the batch before anything is written
+func Validate(b *models.IngestBatch) error {
+	switch {
+	case b.BatchID == "":
+		return &ValidationError{"batch_id is required"}
+	case len(b.BatchID) > MaxBatchIDLength:
+		return &ValidationError{fmt.Sprintf("batch_id is longer than %d characters", MaxBatchIDLength)}
+	case b.ComputerID <= 0:
+		return &ValidationError{"computer_id is required"}
+	case SampleCount(b) == 0:
+		return &ValidationError{"batch has no samples"}
+	case SampleCount(b) > MaxSamples:
+		return &ValidationError{fmt.Sprintf("batch has more than %d samples", MaxSamples)}
+	}
+	return nil
+}
+
+// Write stores the batch in one transaction with multi-row inserts. A batch
+// whose ID has already been stored is skipped and reported as Duplicate.
+func Write(ctx context.Context, db *sql.DB, b *models.IngestBatch) (*models.IngestResult, error) {
+	if err := Validate(b); err != nil {
+		return nil, err
+	}
+	result := &models.IngestResult{BatchID: b.BatchID}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// A concurrent retry of the same batch blocks here until the first one
+	// commits and then sees the conflict
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO ingest_batches (batch_id, computer_id, sample_count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (batch_id) DO NOTHING`,
+		b.BatchID, b.ComputerID, SampleCount(b))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return nil, ErrUnknownComputer
+		}
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		result.Duplicate = true
+		return result, nil
+	}
+
+	now := time.Now()
+	id := b.ComputerID
+
+	var processors [][]interface{}
+	for _, p := range b.Processors {
+		processors = append(processors, []interface{}{
+			id, p.Model, p.Manufacturer, p.Architecture, p.ClockSpeed,
+			p.CoreCount, p.ThreadCount, p.UsagePercent, sampleTime(p.Timestamp, now),
+		})
+	}
+	if err := insertRows(ctx, tx, "processors", []string{
+		"computer_id", "model", "manufacturer", "architecture", "clock_speed",
+		"core_count", "thread_count", "usage_percent", "timestamp",
+	}, processors); err != nil {
+		return nil, err
+	}
+
+	var memory [][]interface{}
+	for _, m := range b.Memory {
+		memory = append(memory, []interface{}{
+			id, m.TotalMemoryGB, m.UsedMemoryGB, m.FreeMemoryGB,
+			m.UsagePercent, m.MemoryType, sampleTime(m.Timestamp, now),
+		})
+	}
+	if err := insertRows(ctx, tx, "memory", []string{
+		"computer_id", "total_memory_gb", "used_memory_gb", "free_memory_gb",
+		"usage_percent", "memory_type", "timestamp",
+	}, memory); err != nil {
+		return nil, err
+	}
+
+	var disks [][]interface{}
+	for _, d := range b.Disks {
+		disks = append(disks, []interface{}{
+			id, d.DriveLetter, d.TotalSpaceGB, d.UsedSpaceGB,
+			d.FreeSpaceGB, d.UsagePercent, sampleTime(d.Timestamp, now),
+		})
+	}
+	if err := insertRows(ctx, tx, "disks", []string{
+		"computer_id", "drive_letter", "total_space_gb", "used_space_gb",
+		"free_space_gb", "usage_percent", "timestamp",
+	}, disks); err != nil {
+		return nil, err
+	}
+
+	var adapters [][]interface{}
+	for _, n := range b.Network {
+		adapters = append(adapters, []interface{}{
+			id, n.AdapterName, n.MacAddress, n.UploadSpeed, n.DownloadSpeed,
+			n.SentMB, n.ReceivedMB, n.SentPackets, n.ReceivedPackets,
+			n.IsActive, sampleTime(n.Timestamp, now),
+		})
+	}
+	if err := insertRows(ctx, tx, "network_adapters", []string{
+		"computer_id", "adapter_name", "mac_address", "upload_speed_mbps", "download_speed_mbps",
+		"sent_mb", "received_mb", "sent_packets", "received_packets",
+		"is_active", "timestamp",
+	}, adapters); err != nil {
+		return nil, err
+	}
+
+	if err := writeSoftware(ctx, tx, id, b.Software); err != nil {
+		return nil, err
+	}
+
+	if b.Inventory != nil {
+		if _, err := inventory.Record(ctx, tx, id, b.Inventory, sampleTime(b.Inventory.CollectedAt, now)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	result.Inserted = SampleCount(b)
+	return result, nil
+}
+
+// writeSoftware applies package changes to the computer's rows in
+// software_on_computer. host_name records the name the package was reported
+// under.
+func writeSoftware(ctx context.Context, tx *sql.Tx, computerID int, changes []models.SoftwareChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var hostName string
+	if err := tx.QueryRowContext(ctx, "SELECT host_name FROM computers WHERE computer_id = $1", computerID).Scan(&hostName); err != nil {
+		return err
+	}
+
+	var installed [][]interface{}
+	for _, s := range changes {
+		if s.Removed {
+			_, err := tx.ExecContext(ctx, `
+				DELETE FROM software_on_computer
+				WHERE computer_id = $1
+				AND name IS NOT DISTINCT FROM $2
+				AND version IS NOT DISTINCT FROM $3
+				AND publisher IS NOT DISTINCT FROM $4`,
+				computerID, nullIfEmpty(s.Name), nullIfEmpty(s.Version), nullIfEmpty(s.Publisher))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		metadata, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		installed = append(installed, []interface{}{
+			nullIfEmpty(s.Name), nullIfEmpty(s.Version), nullIfEmpty(s.Publisher),
+			nullIfEmpty(s.Installed), metadata, hostName, computerID,
+		})
+	}
+
+	return insertRows(ctx, tx, "software_on_computer", []string{
+		"name", "version", "publisher", "installed", "metadata", "host_name", "computer_id",
+	}, installed)
+}
+
+// insertRows writes rows with as few multi-row INSERT statements as the
+// bind parameter limit allows
+func insertRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]interface{}) error {
+	perStatement := maxParams / len(columns)
+
+	for len(rows) > 0 {
+		chunk := rows
+		if len(chunk) > perStatement {
+			chunk = chunk[:perStatement]
+		}
+		rows = rows[len(chunk):]
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+		args := make([]interface{}, 0, len(chunk)*len(columns))
+		for i, row := range chunk {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("(")
+			for j, v := range row {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				args = append(args, v)
+				b.WriteString("$" + strconv.Itoa(len(args)))
+			}
+			b.WriteString(")")
+		}
+
+		if _, err := tx.ExecContext(ctx, b.String(), args...); err != nil {
+			return fmt.Errorf("insert into %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// sampleTime falls back to the receive time for samples without a
+// timestamp. Timestamps are stored as server local time without a zone.
+func sampleTime(t, now time.Time) time.Time {
+	if t.IsZero() {
+		return now
+	}
+	return t.Local()
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   models.IngestBatch
+		wantErr string
+	}{
+		{"processor", models.IngestBatch{BatchID: "b1", ComputerID: 1, Processors: make([]models.Processor, 1)}, ""},
+		{"inventory only", models.IngestBatch{BatchID: "b1", ComputerID: 1, Inventory: &models.HardwareInventory{}}, ""},
+		{"longest id", models.IngestBatch{BatchID: strings.Repeat("a", MaxBatchIDLength), ComputerID: 1, Memory: make([]models.Memory, 1)}, ""},
+		{"at the limit", models.IngestBatch{BatchID: "b1", ComputerID: 1, Disks: make([]models.Disk, MaxSamples)}, ""},
+		{"no id", models.IngestBatch{ComputerID: 1, Processors: make([]models.Processor, 1)}, "batch_id is required"},
+		{"long id", models.IngestBatch{BatchID: strings.Repeat("a", MaxBatchIDLength+1), ComputerID: 1, Processors: make([]models.Processor, 1)}, "batch_id is longer"},
+		{"no computer", models.IngestBatch{BatchID: "b1", Processors: make([]models.Processor, 1)}, "computer_id is required"},
+		{"negative computer", models.IngestBatch{BatchID: "b1", ComputerID: -1, Processors: make([]models.Processor, 1)}, "computer_id is required"},
+		{"empty", models.IngestBatch{BatchID: "b1", ComputerID: 1}, "no samples"},
+		{"over the limit", models.IngestBatch{BatchID: "b1", ComputerID: 1,
+			Disks: make([]models.Disk, MaxSamples), Inventory: &models.HardwareInventory{}}, "more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.batch)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate: %v, want a ValidationError %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSampleCount(t *testing.T) {
+	b := &models.IngestBatch{
+		Processors: make([]models.Processor, 2),
+		Memory:     make([]models.Memory, 2),
+		Disks:      make([]models.Disk, 3),
+		Network:    make([]models.NetworkAdapter, 4),
+		Software:   make([]models.SoftwareChange, 5),
+		Inventory:  &models.HardwareInventory{},
+	}
+	if got := SampleCount(b); got != 17 {
+		t.Errorf("SampleCount = %d, want 17", got)
+	}
+}
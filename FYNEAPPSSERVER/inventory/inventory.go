@@ -0,0 +1,264 @@
+// Package inventory detects hardware changes by comparing each inventory a
+// computer reports with the previous one.
+package inventory
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memoryTolerance ignores differences in usable memory reported by the OS
+const memoryTolerance = 0.5 // GB
+
+// Record compares inv with the stored inventory of the computer, writes the
+// detected changes and makes inv the new baseline. The first inventory only
+// sets the baseline. An inventory older than the stored one is ignored, so
+// replayed batches do not report changes backwards.
+func Record(ctx context.Context, tx *sql.Tx, computerID int, inv *models.HardwareInventory, at time.Time) ([]models.HardwareChange, error) {
+	var (
+		data        []byte
+		collectedAt time.Time
+	)
+	err := tx.QueryRowContext(ctx, `
+		SELECT inventory, collected_at FROM hardware_inventory
+		WHERE computer_id = $1 FOR UPDATE`, computerID).Scan(&data, &collectedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil && at.Before(collectedAt) {
+		return nil, nil
+	}
+
+	var changes []models.HardwareChange
+	if err == nil {
+		var previous models.HardwareInventory
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return nil, fmt.Errorf("stored inventory of computer %d: %v", computerID, err)
+		}
+		changes = Diff(&previous, inv)
+	}
+
+	for i := range changes {
+		changes[i].ComputerID = computerID
+		changes[i].DetectedAt = at
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO hardware_changes (computer_id, detected_at, component, change, old_value, new_value)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING change_id`,
+			computerID, at, changes[i].Component, changes[i].Change,
+			nullIfEmpty(changes[i].OldValue), nullIfEmpty(changes[i].NewValue),
+		).Scan(&changes[i].ChangeID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err = json.Marshal(inv)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO hardware_inventory (computer_id, inventory, collected_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (computer_id) DO UPDATE SET inventory = $2, collected_at = $3`,
+		computerID, data, at)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Diff returns the changes between two inventories of one computer. A
+// category that is empty in either inventory was not collected (for example
+// memory modules without root) and is not compared.
+func Diff(previous, current *models.HardwareInventory) []models.HardwareChange {
+	var changes []models.HardwareChange
+
+	if len(previous.CPUs) > 0 && len(current.CPUs) > 0 {
+		before, after := sortedJoin(previous.CPUs), sortedJoin(current.CPUs)
+		if before != after {
+			changes = append(changes, models.HardwareChange{
+				Component: models.ComponentCPU,
+				Change:    models.ChangeChanged,
+				OldValue:  before,
+				NewValue:  after,
+			})
+		}
+	}
+
+	if len(previous.MemoryModules) > 0 && len(current.MemoryModules) > 0 {
+		changes = append(changes, diffItems(models.ComponentMemory,
+			memoryItems(previous.MemoryModules), memoryItems(current.MemoryModules))...)
+	} else if previous.MemoryTotalGB > 0 && current.MemoryTotalGB > 0 &&
+		math.Abs(previous.MemoryTotalGB-current.MemoryTotalGB) >= memoryTolerance {
+		changes = append(changes, models.HardwareChange{
+			Component: models.ComponentMemory,
+			Change:    models.ChangeChanged,
+			OldValue:  fmt.Sprintf("%.1f GB total", previous.MemoryTotalGB),
+			NewValue:  fmt.Sprintf("%.1f GB total", current.MemoryTotalGB),
+		})
+	}
+
+	if len(previous.Disks) > 0 && len(current.Disks) > 0 {
+		changes = append(changes, diffItems(models.ComponentDisk,
+			diskItems(previous.Disks), diskItems(current.Disks))...)
+	}
+
+	if len(previous.NetworkAdapters) > 0 && len(current.NetworkAdapters) > 0 {
+		changes = append(changes, diffItems(models.ComponentNetwork,
+			networkItems(previous.NetworkAdapters), networkItems(current.NetworkAdapters))...)
+	}
+
+	return changes
+}
+
+// item is a component with identifying keys in order of preference. Two
+// items are compared on the first key both of them have, so a serial number
+// that only one inventory could read does not count as a change.
+type item struct {
+	keys        []string
+	description string
+}
+
+func (a item) matches(b item) bool {
+	for i := range a.keys {
+		if i < len(b.keys) && a.keys[i] != "" && b.keys[i] != "" {
+			return a.keys[i] == b.keys[i]
+		}
+	}
+	return false
+}
+
+// diffItems reports unmatched previous items as removed and unmatched
+// current items as added
+func diffItems(component string, previous, current []item) []models.HardwareChange {
+	var changes []models.HardwareChange
+	matched := make([]bool, len(current))
+
+	for _, p := range previous {
+		found := false
+		for j, c := range current {
+			if !matched[j] && p.matches(c) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			changes = append(changes, models.HardwareChange{
+				Component: component,
+				Change:    models.ChangeRemoved,
+				OldValue:  p.description,
+			})
+		}
+	}
+
+	for j, c := range current {
+		if !matched[j] {
+			changes = append(changes, models.HardwareChange{
+				Component: component,
+				Change:    models.ChangeAdded,
+				NewValue:  c.description,
+			})
+		}
+	}
+	return changes
+}
+
+func memoryItems(modules []models.MemoryModule) []item {
+	items := make([]item, 0, len(modules))
+	for _, m := range modules {
+		items = append(items, item{
+			keys: []string{
+				serial(m.SerialNumber),
+				strings.Join([]string{m.Slot, m.Size, m.Manufacturer}, "|"),
+			},
+			description: describe(strings.Join(nonEmpty(m.Manufacturer, m.Size, m.Speed), " "),
+				labeled("slot", m.Slot), labeled("S/N", serial(m.SerialNumber))),
+		})
+	}
+	return items
+}
+
+func diskItems(disks []models.DiskDevice) []item {
+	items := make([]item, 0, len(disks))
+	for _, d := range disks {
+		size := fmt.Sprintf("%.0f GB", d.SizeGB)
+		items = append(items, item{
+			keys:        []string{serial(d.SerialNumber), d.Model + "|" + size},
+			description: describe(strings.Join(nonEmpty(d.Model, size), " "), labeled("S/N", serial(d.SerialNumber)), d.Name),
+		})
+	}
+	return items
+}
+
+func networkItems(adapters []models.NetworkInterface) []item {
+	items := make([]item, 0, len(adapters))
+	for _, a := range adapters {
+		mac := strings.ToLower(a.MacAddress)
+		if mac == "" {
+			continue
+		}
+		items = append(items, item{
+			keys:        []string{mac},
+			description: describe(mac, a.Name),
+		})
+	}
+	return items
+}
+
+// serial drops placeholders that firmware reports instead of a serial number
+func serial(s string) string {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "", "unknown", "not specified", "none", "n/a", "0", "00000000", "0000000000000000", "serialnum":
+		return ""
+	}
+	return s
+}
+
+// describe formats "main (detail, detail)" skipping empty details
+func describe(main string, details ...string) string {
+	details = nonEmpty(details...)
+	if len(details) == 0 {
+		return main
+	}
+	return main + " (" + strings.Join(details, ", ") + ")"
+}
+
+func labeled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + " " + value
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sortedJoin(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "; ")
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
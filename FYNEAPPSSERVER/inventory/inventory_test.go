@@ -0,0 +1,121 @@
+package inventory
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	base := func() *models.HardwareInventory {
+		return &models.HardwareInventory{
+			CPUs:          []string{"Intel Core i5-10400"},
+			MemoryTotalGB: 15.8,
+			MemoryModules: []models.MemoryModule{
+				{Slot: "DIMM0", Manufacturer: "Kingston", Size: "8 GB", Speed: "2666 MT/s", SerialNumber: "A1"},
+				{Slot: "DIMM1", Manufacturer: "Kingston", Size: "8 GB", Speed: "2666 MT/s", SerialNumber: "A2"},
+			},
+			Disks: []models.DiskDevice{
+				{Name: "sda", Model: "Samsung SSD 870", SerialNumber: "S1", SizeGB: 500},
+			},
+			NetworkAdapters: []models.NetworkInterface{
+				{Name: "eth0", MacAddress: "00:1a:2b:3c:4d:5e"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		edit func(*models.HardwareInventory)
+		want []models.HardwareChange
+	}{
+		{"unchanged", func(inv *models.HardwareInventory) {}, nil},
+		{
+			"cpu replaced",
+			func(inv *models.HardwareInventory) { inv.CPUs = []string{"Intel Core i7-10700"} },
+			[]models.HardwareChange{{Component: models.ComponentCPU, Change: models.ChangeChanged,
+				OldValue: "Intel Core i5-10400", NewValue: "Intel Core i7-10700"}},
+		},
+		{
+			"memory module removed",
+			func(inv *models.HardwareInventory) { inv.MemoryModules = inv.MemoryModules[:1] },
+			[]models.HardwareChange{{Component: models.ComponentMemory, Change: models.ChangeRemoved,
+				OldValue: "Kingston 8 GB 2666 MT/s (slot DIMM1, S/N A2)"}},
+		},
+		{
+			"memory module swapped",
+			func(inv *models.HardwareInventory) { inv.MemoryModules[1].SerialNumber = "B7" },
+			[]models.HardwareChange{
+				{Component: models.ComponentMemory, Change: models.ChangeRemoved, OldValue: "Kingston 8 GB 2666 MT/s (slot DIMM1, S/N A2)"},
+				{Component: models.ComponentMemory, Change: models.ChangeAdded, NewValue: "Kingston 8 GB 2666 MT/s (slot DIMM1, S/N B7)"},
+			},
+		},
+		{
+			"serial placeholder matches on slot",
+			func(inv *models.HardwareInventory) { inv.MemoryModules[0].SerialNumber = "Not Specified" },
+			nil,
+		},
+		{
+			"modules not collected, total within tolerance",
+			func(inv *models.HardwareInventory) { inv.MemoryModules, inv.MemoryTotalGB = nil, 15.6 },
+			nil,
+		},
+		{
+			"modules not collected, total changed",
+			func(inv *models.HardwareInventory) { inv.MemoryModules, inv.MemoryTotalGB = nil, 31.8 },
+			[]models.HardwareChange{{Component: models.ComponentMemory, Change: models.ChangeChanged,
+				OldValue: "15.8 GB total", NewValue: "31.8 GB total"}},
+		},
+		{
+			"disk added",
+			func(inv *models.HardwareInventory) {
+				inv.Disks = append(inv.Disks, models.DiskDevice{Name: "sdb", Model: "WDC WD10EZEX", SerialNumber: "W9", SizeGB: 1000})
+			},
+			[]models.HardwareChange{{Component: models.ComponentDisk, Change: models.ChangeAdded,
+				NewValue: "WDC WD10EZEX 1000 GB (S/N W9, sdb)"}},
+		},
+		{
+			"disk renamed",
+			func(inv *models.HardwareInventory) { inv.Disks[0].Name = "nvme0n1" },
+			nil,
+		},
+		{
+			"mac case ignored",
+			func(inv *models.HardwareInventory) { inv.NetworkAdapters[0].MacAddress = "00:1A:2B:3C:4D:5E" },
+			nil,
+		},
+		{
+			"adapter replaced",
+			func(inv *models.HardwareInventory) {
+				inv.NetworkAdapters = []models.NetworkInterface{{Name: "eth0", MacAddress: "AA:BB:CC:DD:EE:FF"}}
+			},
+			[]models.HardwareChange{
+				{Component: models.ComponentNetwork, Change: models.ChangeRemoved, OldValue: "00:1a:2b:3c:4d:5e (eth0)"},
+				{Component: models.ComponentNetwork, Change: models.ChangeAdded, NewValue: "aa:bb:cc:dd:ee:ff (eth0)"},
+			},
+		},
+		{
+			"category not collected",
+			func(inv *models.HardwareInventory) { inv.CPUs, inv.Disks, inv.NetworkAdapters = nil, nil, nil },
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := base()
+			tt.edit(current)
+			got := Diff(base(), current)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff:\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffCPUOrder(t *testing.T) {
+	previous := &models.HardwareInventory{CPUs: []string{"Xeon E5-2620", "Xeon E5-2630"}}
+	current := &models.HardwareInventory{CPUs: []string{"Xeon E5-2630", "Xeon E5-2620"}}
+	if changes := Diff(previous, current); len(changes) != 0 {
+		t.Errorf("Diff reported %+v for reordered CPUs", changes)
+	}
+}
@@ -0,0 +1,129 @@
+// Package maintenance keeps the maintenance windows of monitoring targets
+// and tells whether a target is in one. Checks that fail during a window
+// are stored as maintenance instead of down or degraded, so that planned
+// downtime raises no alarms and does not spoil uptime.
+package maintenance
+
+import (
+	"FYNEAPPSSERVER/api/database"
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MaxDurationMin bounds the length of a recurring window: a week
+const MaxDurationMin = 7 * 24 * 60
+
+// Columns are the maintenance_windows columns read by Scan
+const Columns = `window_id, target_id, group_name, starts_at, ends_at, schedule, duration_min, reason, created_at`
+
+// Scan reads a row of Columns
+func Scan(row interface{ Scan(...interface{}) error }) (models.MaintenanceWindow, error) {
+	var (
+		w                  models.MaintenanceWindow
+		targetID, duration sql.NullInt64
+		group, schedule    sql.NullString
+		startsAt, endsAt   sql.NullTime
+	)
+	err := row.Scan(
+		&w.WindowID,
+		&targetID,
+		&group,
+		&startsAt,
+		&endsAt,
+		&schedule,
+		&duration,
+		&w.Reason,
+		&w.CreatedAt,
+	)
+	if targetID.Valid {
+		id := int(targetID.Int64)
+		w.TargetID = &id
+	}
+	if startsAt.Valid {
+		t := database.WallClock(startsAt.Time)
+		w.StartsAt = &t
+	}
+	if endsAt.Valid {
+		t := database.WallClock(endsAt.Time)
+		w.EndsAt = &t
+	}
+	w.Group, w.Schedule, w.DurationMin = group.String, schedule.String, int(duration.Int64)
+	return w, err
+}
+
+// Windows returns every maintenance window
+func Windows(ctx context.Context, db *sql.DB) ([]models.MaintenanceWindow, error) {
+	return query(ctx, db, "SELECT "+Columns+" FROM maintenance_windows ORDER BY window_id")
+}
+
+// ForTarget returns the window the target is in at now, or nil
+func ForTarget(ctx context.Context, db *sql.DB, targetID int, now time.Time) (*models.MaintenanceWindow, error) {
+	windows, err := query(ctx, db, `
+		SELECT `+Columns+` FROM maintenance_windows
+		WHERE target_id = $1
+			OR group_name = (SELECT group_name FROM monitoring_targets WHERE target_id = $1 AND group_name <> '')
+		ORDER BY window_id`, targetID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range windows {
+		if Active(windows[i], now) {
+			return &windows[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func query(ctx context.Context, db *sql.DB, q string, args ...interface{}) ([]models.MaintenanceWindow, error) {
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []models.MaintenanceWindow
+	for rows.Next() {
+		w, err := Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// Find returns the first of windows that covers the target with targetID
+// in group at now, or nil
+func Find(windows []models.MaintenanceWindow, targetID int, group string, now time.Time) *models.MaintenanceWindow {
+	for i, w := range windows {
+		applies := w.TargetID != nil && *w.TargetID == targetID
+		if w.TargetID == nil && group != "" && w.Group == group {
+			applies = true
+		}
+		if applies && Active(w, now) {
+			return &windows[i]
+		}
+	}
+	return nil
+}
+
+// Active reports whether w covers now. A recurring window is active when
+// its schedule matched in one of the last DurationMin minutes.
+func Active(w models.MaintenanceWindow, now time.Time) bool {
+	if w.Schedule == "" {
+		return w.StartsAt != nil && w.EndsAt != nil && !now.Before(*w.StartsAt) && now.Before(*w.EndsAt)
+	}
+	schedule, err := ParseSchedule(w.Schedule)
+	if err != nil {
+		return false
+	}
+	minute := now.Truncate(time.Minute)
+	for i := 0; i < min(w.DurationMin, MaxDurationMin); i++ {
+		if schedule.Matches(minute.Add(-time.Duration(i) * time.Minute)) {
+			return true
+		}
+	}
+	return false
+}
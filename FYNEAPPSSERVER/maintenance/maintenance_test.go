@@ -0,0 +1,79 @@
+package maintenance
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"testing"
+	"time"
+)
+
+func TestActive(t *testing.T) {
+	// 17.10.2026 - суббота
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+	oneOff := func(from, to time.Time) models.MaintenanceWindow {
+		return models.MaintenanceWindow{StartsAt: &from, EndsAt: &to}
+	}
+	recurring := func(schedule string, minutes int) models.MaintenanceWindow {
+		return models.MaintenanceWindow{Schedule: schedule, DurationMin: minutes}
+	}
+
+	tests := []struct {
+		name string
+		w    models.MaintenanceWindow
+		now  time.Time
+		want bool
+	}{
+		{"one-off before", oneOff(at(17, 22, 0), at(18, 2, 0)), at(17, 21, 59), false},
+		{"one-off at start", oneOff(at(17, 22, 0), at(18, 2, 0)), at(17, 22, 0), true},
+		{"one-off inside", oneOff(at(17, 22, 0), at(18, 2, 0)), at(18, 1, 0), true},
+		{"one-off at end", oneOff(at(17, 22, 0), at(18, 2, 0)), at(18, 2, 0), false},
+		{"one-off without end", models.MaintenanceWindow{StartsAt: &time.Time{}}, at(17, 22, 0), false},
+		{"recurring at start", recurring("0 2 * * 6", 120), at(17, 2, 0), true},
+		{"recurring inside", recurring("0 2 * * 6", 120), at(17, 3, 59).Add(30 * time.Second), true},
+		{"recurring at end", recurring("0 2 * * 6", 120), at(17, 4, 0), false},
+		{"recurring other day", recurring("0 2 * * 6", 120), at(18, 3, 0), false},
+		{"recurring past midnight", recurring("0 23 * * 6", 180), at(18, 1, 30), true},
+		{"recurring invalid schedule", recurring("0 2 * *", 120), at(17, 2, 30), false},
+		{"recurring without duration", recurring("0 2 * * *", 0), at(17, 2, 0), false},
+	}
+	for _, tt := range tests {
+		if got := Active(tt.w, tt.now); got != tt.want {
+			t.Errorf("%s: Active at %s = %v, want %v", tt.name, tt.now.Format("Mon 02.01 15:04:05"), got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	now := time.Date(2026, 10, 17, 2, 30, 0, 0, time.UTC)
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+	target, other := 4, 5
+	windows := []models.MaintenanceWindow{
+		{WindowID: 1, TargetID: &other, StartsAt: &from, EndsAt: &to},
+		{WindowID: 2, TargetID: &target, StartsAt: &to, EndsAt: &to},
+		{WindowID: 3, Group: "Портал", Schedule: "0 2 * * *", DurationMin: 60},
+		{WindowID: 4, TargetID: &target, Schedule: "0 2 * * *", DurationMin: 60},
+	}
+
+	tests := []struct {
+		name     string
+		targetID int
+		group    string
+		want     int
+	}{
+		{"group window first", target, "Портал", 3},
+		{"own window", target, "", 4},
+		{"other target", other, "", 1},
+		{"group only", 9, "Портал", 3},
+		{"nothing", 9, "Сайты", 0},
+	}
+	for _, tt := range tests {
+		got := Find(windows, tt.targetID, tt.group, now)
+		switch {
+		case tt.want == 0 && got != nil:
+			t.Errorf("%s: Find = window %d, want nil", tt.name, got.WindowID)
+		case tt.want != 0 && (got == nil || got.WindowID != tt.want):
+			t.Errorf("%s: Find = %+v, want window %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 2 * *",
+		"0 2 * * * *",
+		"60 2 * * *",
+		"0 24 * * *",
+		"0 2 0 * *",
+		"0 2 32 * *",
+		"0 2 * 13 *",
+		"0 2 * * 8",
+		"0 2 * * mon",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"1,,2 * * * *",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	// 17.10.2026 - суббота
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(17, 13, 47), true},
+		{"30 1 * * 6", at(17, 1, 30), true},
+		{"30 1 * * 6", at(17, 1, 31), false},
+		{"30 1 * * 6", at(19, 1, 30), false},
+		{"0 22 1 * *", at(1, 22, 0), true},
+		{"0 22 1 * *", at(2, 22, 0), false},
+		{"0 2 * 10 *", at(17, 2, 0), true},
+		{"0 2 * 11 *", at(17, 2, 0), false},
+		// Воскресенье - 0 или 7
+		{"0 3 * * 0", at(18, 3, 0), true},
+		{"0 3 * * 7", at(18, 3, 0), true},
+		{"0 3 * * 1-5", at(18, 3, 0), false},
+		{"0 3 * * 1-5", at(19, 3, 0), true},
+		// Списки, диапазоны и шаги
+		{"0,15,30,45 * * * *", at(17, 9, 45), true},
+		{"0,15,30,45 * * * *", at(17, 9, 50), false},
+		{"*/20 * * * *", at(17, 9, 40), true},
+		{"*/20 * * * *", at(17, 9, 50), false},
+		{"10-30/10 * * * *", at(17, 9, 20), true},
+		{"10-30/10 * * * *", at(17, 9, 40), false},
+		{"5/15 * * * *", at(17, 9, 50), true},
+		{"5/15 * * * *", at(17, 9, 0), false},
+		// Оба дня ограничены: подходит любой из них, как в cron
+		{"0 2 1 * 1", at(1, 2, 0), true},
+		{"0 2 1 * 1", at(19, 2, 0), true},
+		{"0 2 1 * 1", at(17, 2, 0), false},
+		// */n в дне не ограничивает его, поэтому решает день недели
+		{"0 2 */1 * 1", at(19, 2, 0), true},
+		{"0 2 */1 * 1", at(17, 2, 0), false},
+		{"0 2 1 * */1", at(1, 2, 0), true},
+		{"0 2 1 * */1", at(17, 2, 0), false},
+		{"0 2 */2 * *", at(17, 2, 0), true},
+		{"0 2 */2 * *", at(18, 2, 0), false},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Matches(tt.t); got != tt.want {
+			t.Errorf("%q.Matches(%s) = %v, want %v", tt.expr, tt.t.Format("Mon 02.01 15:04"), got, tt.want)
+		}
+	}
+}
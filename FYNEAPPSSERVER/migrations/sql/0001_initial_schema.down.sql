@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS tickets_statuses;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS software_on_computer;
+DROP TABLE IF EXISTS computer_software;
+DROP TABLE IF EXISTS software_dependencies;
+DROP TABLE IF EXISTS software_updates;
+DROP TABLE IF EXISTS software;
+DROP TABLE IF EXISTS disks;
+DROP TABLE IF EXISTS network_adapters;
+DROP TABLE IF EXISTS memory;
+DROP TABLE IF EXISTS processors;
+DROP TABLE IF EXISTS computers;
@@ -0,0 +1,189 @@
+-- Baseline schema. Every statement is idempotent so the migration can also
+-- be applied to databases created earlier from the loose db/*.sql scripts.
+
+CREATE TABLE IF NOT EXISTS computers (
+    computer_id SERIAL PRIMARY KEY,
+    host_name VARCHAR(100) NOT NULL,
+    user_name VARCHAR(100),
+    os_name VARCHAR(50) NOT NULL,
+    os_version VARCHAR(100) NOT NULL,
+    os_platform VARCHAR(50),
+    os_architecture VARCHAR(20),
+    kernel_version VARCHAR(100),
+    uptime TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    process_count INTEGER,
+    boot_time TIMESTAMP,
+    home_directory VARCHAR(255),
+    gid VARCHAR(100),
+    uid VARCHAR(100)
+);
+
+CREATE TABLE IF NOT EXISTS processors (
+    processor_id SERIAL PRIMARY KEY,
+    computer_id INTEGER REFERENCES computers(computer_id),
+    model VARCHAR(100) NOT NULL,
+    manufacturer VARCHAR(100),
+    architecture VARCHAR(20),
+    clock_speed DECIMAL(8,2), -- in GHz
+    core_count INTEGER,
+    thread_count INTEGER,
+    usage_percent DECIMAL(5,2),
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS memory (
+    memory_id SERIAL PRIMARY KEY,
+    computer_id INTEGER REFERENCES computers(computer_id),
+    total_memory_gb DECIMAL(8,2),
+    used_memory_gb DECIMAL(8,2),
+    free_memory_gb DECIMAL(8,2),
+    usage_percent DECIMAL(5,2),
+    memory_type VARCHAR(50),
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS network_adapters (
+    adapter_id SERIAL PRIMARY KEY,
+    computer_id INTEGER REFERENCES computers(computer_id),
+    adapter_name VARCHAR(100) NOT NULL,
+    mac_address VARCHAR(50),
+    upload_speed_mbps DECIMAL(10,2),
+    download_speed_mbps DECIMAL(10,2),
+    sent_mb DECIMAL(12,2),
+    received_mb DECIMAL(12,2),
+    sent_packets BIGINT,
+    received_packets BIGINT,
+    is_active BOOLEAN DEFAULT TRUE,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS disks (
+    disk_id SERIAL PRIMARY KEY,
+    computer_id INTEGER REFERENCES computers(computer_id),
+    drive_letter VARCHAR(255) NOT NULL,
+    total_space_gb DECIMAL(12,2),
+    used_space_gb DECIMAL(12,2),
+    free_space_gb DECIMAL(12,2),
+    usage_percent DECIMAL(5,2),
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Clients on Linux store device paths such as /dev/nvme0n1p1 here
+ALTER TABLE disks ALTER COLUMN drive_letter TYPE VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS processors_computer_time_idx ON processors (computer_id, timestamp);
+CREATE INDEX IF NOT EXISTS memory_computer_time_idx ON memory (computer_id, timestamp);
+CREATE INDEX IF NOT EXISTS network_adapters_computer_time_idx ON network_adapters (computer_id, timestamp);
+CREATE INDEX IF NOT EXISTS disks_computer_time_idx ON disks (computer_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS software (
+    software_id SERIAL PRIMARY KEY,
+    computer_id INTEGER REFERENCES computers(computer_id),
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(100),
+    publisher VARCHAR(255),
+    install_date DATE,
+    install_location VARCHAR(512),
+    size_mb DECIMAL(10,2),
+    is_system_component BOOLEAN DEFAULT FALSE,
+    is_update BOOLEAN DEFAULT FALSE,
+    architecture VARCHAR(20), -- x86, x64, ARM и т.д.
+    last_used_date TIMESTAMP,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Columns read by the client's application library
+ALTER TABLE software ADD COLUMN IF NOT EXISTS picture BYTEA;
+ALTER TABLE software ADD COLUMN IF NOT EXISTS download_url TEXT;
+ALTER TABLE software ADD COLUMN IF NOT EXISTS target_platform VARCHAR(50);
+ALTER TABLE software ADD COLUMN IF NOT EXISTS install_command TEXT;
+
+CREATE TABLE IF NOT EXISTS software_updates (
+    update_id SERIAL PRIMARY KEY,
+    software_id INTEGER REFERENCES software(software_id),
+    update_name VARCHAR(255),
+    update_version VARCHAR(100),
+    kb_article VARCHAR(50), -- для Windows-обновлений
+    install_date TIMESTAMP,
+    size_mb DECIMAL(10,2),
+    is_uninstalled BOOLEAN DEFAULT FALSE,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS software_dependencies (
+    dependency_id SERIAL PRIMARY KEY,
+    software_id INTEGER REFERENCES software(software_id),
+    required_software_id INTEGER REFERENCES software(software_id),
+    min_version VARCHAR(100),
+    max_version VARCHAR(100),
+    is_optional BOOLEAN DEFAULT FALSE,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS computer_software (
+    computer_software_id SERIAL PRIMARY KEY,
+    computer_id INTEGER NOT NULL REFERENCES computers(computer_id),
+    software_id INTEGER NOT NULL REFERENCES software(software_id),
+    is_installed BOOLEAN DEFAULT TRUE,
+    install_date TIMESTAMP,
+    uninstall_date TIMESTAMP,
+    last_used TIMESTAMP,
+    usage_frequency VARCHAR(50),
+    is_required BOOLEAN DEFAULT FALSE,
+    notes TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (computer_id, software_id)
+);
+
+-- Inventory of installed packages reported by the client
+CREATE TABLE IF NOT EXISTS software_on_computer (
+    id SERIAL PRIMARY KEY,
+    name TEXT,
+    version TEXT,
+    publisher TEXT,
+    installed TEXT,
+    metadata JSONB,
+    host_name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS software_on_computer_host_idx ON software_on_computer (host_name);
+
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    password TEXT,
+    full_name VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tickets_statuses (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+INSERT INTO tickets_statuses (id, name) VALUES
+    (1, 'Новый'),
+    (2, 'В процессе'),
+    (3, 'Завершен')
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS tickets (
+    id SERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    description TEXT,
+    user_id TEXT,
+    computer_name TEXT,
+    status_id INTEGER NOT NULL DEFAULT 1 REFERENCES tickets_statuses(id),
+    cabinet INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    update_at TIMESTAMP
+);
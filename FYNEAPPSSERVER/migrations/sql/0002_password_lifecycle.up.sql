@@ -0,0 +1,4 @@
+-- Existing plaintext passwords stay in place and are replaced with a hash
+-- by the client on the user's next successful login.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP;
@@ -0,0 +1,9 @@
+-- Accounts that existed before roles keep full ticket and inventory access;
+-- new accounts start as read-only viewers.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'technician';
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'viewer';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'technician', 'viewer'));
+
+-- The conventional admin account, if present, becomes the first administrator
+UPDATE users SET role = 'admin' WHERE username = 'admin';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS api_keys;
+DROP INDEX IF EXISTS user_sessions_expires_idx;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS last_seen_at;
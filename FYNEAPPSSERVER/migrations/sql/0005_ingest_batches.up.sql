@@ -0,0 +1,10 @@
+-- Batches accepted by POST /api/v1/ingest. The client-generated batch_id makes
+-- retries after a timeout or a lost response idempotent.
+CREATE TABLE IF NOT EXISTS ingest_batches (
+    batch_id VARCHAR(64) PRIMARY KEY,
+    computer_id INTEGER NOT NULL REFERENCES computers(computer_id) ON DELETE CASCADE,
+    sample_count INTEGER NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ingest_batches_received_idx ON ingest_batches (received_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS software_on_computer_computer_idx;
+ALTER TABLE software_on_computer DROP COLUMN IF EXISTS computer_id;
+DROP TABLE IF EXISTS computer_hostnames;
+DROP INDEX IF EXISTS computers_machine_uuid_idx;
+ALTER TABLE computers DROP COLUMN IF EXISTS machine_uuid;
@@ -0,0 +1,31 @@
+-- Computers are identified by a UUID derived from /etc/machine-id or the
+-- SMBIOS UUID instead of the host name. Rows created by older clients keep
+-- NULL until the machine registers again and claims its row by host name.
+ALTER TABLE computers ADD COLUMN IF NOT EXISTS machine_uuid UUID;
+CREATE UNIQUE INDEX IF NOT EXISTS computers_machine_uuid_idx ON computers (machine_uuid);
+
+-- Host names a computer has reported; a rename adds a row instead of a new
+-- computer
+CREATE TABLE IF NOT EXISTS computer_hostnames (
+    id SERIAL PRIMARY KEY,
+    computer_id INTEGER NOT NULL REFERENCES computers(computer_id) ON DELETE CASCADE,
+    host_name VARCHAR(100) NOT NULL,
+    first_seen TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (computer_id, host_name)
+);
+
+INSERT INTO computer_hostnames (computer_id, host_name)
+SELECT computer_id, host_name FROM computers
+ON CONFLICT DO NOTHING;
+
+-- Installed software is tied to the computer; host_name stays as the name
+-- the package was reported under
+ALTER TABLE software_on_computer
+    ADD COLUMN IF NOT EXISTS computer_id INTEGER REFERENCES computers(computer_id) ON DELETE CASCADE;
+
+UPDATE software_on_computer s
+SET computer_id = (SELECT MIN(c.computer_id) FROM computers c WHERE c.host_name = s.host_name)
+WHERE s.computer_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS software_on_computer_computer_idx ON software_on_computer (computer_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS hardware_changes;
+DROP TABLE IF EXISTS hardware_inventory;
@@ -0,0 +1,20 @@
+-- Last hardware inventory reported by each computer, the baseline for change
+-- detection
+CREATE TABLE IF NOT EXISTS hardware_inventory (
+    computer_id INTEGER PRIMARY KEY REFERENCES computers(computer_id) ON DELETE CASCADE,
+    inventory JSONB NOT NULL,
+    collected_at TIMESTAMP NOT NULL
+);
+
+-- Components that appeared, disappeared or changed between two inventories
+CREATE TABLE IF NOT EXISTS hardware_changes (
+    change_id SERIAL PRIMARY KEY,
+    computer_id INTEGER NOT NULL REFERENCES computers(computer_id) ON DELETE CASCADE,
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    component VARCHAR(20) NOT NULL,
+    change VARCHAR(20) NOT NULL,
+    old_value TEXT,
+    new_value TEXT
+);
+
+CREATE INDEX IF NOT EXISTS hardware_changes_computer_idx ON hardware_changes (computer_id, detected_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS disks_time_idx;
+DROP INDEX IF EXISTS network_adapters_time_idx;
+DROP INDEX IF EXISTS memory_time_idx;
+DROP INDEX IF EXISTS processors_time_idx;
+DROP TABLE IF EXISTS metric_rollups_daily;
+DROP TABLE IF EXISTS metric_rollups_hourly;
@@ -0,0 +1,39 @@
+-- Hourly and daily aggregates of the telemetry tables. The retention job moves
+-- raw samples older than RETENTION_RAW_DAYS into metric_rollups_hourly and
+-- hourly rows older than RETENTION_HOURLY_DAYS into metric_rollups_daily, so
+-- every period is stored at exactly one resolution. sum and count are kept
+-- instead of the average so that buckets can be merged.
+CREATE TABLE IF NOT EXISTS metric_rollups_hourly (
+    computer_id INTEGER NOT NULL REFERENCES computers(computer_id) ON DELETE CASCADE,
+    metric VARCHAR(50) NOT NULL,
+    series VARCHAR(255) NOT NULL DEFAULT '',
+    bucket TIMESTAMP NOT NULL,
+    sum DOUBLE PRECISION NOT NULL,
+    count INTEGER NOT NULL,
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    p95 DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (computer_id, metric, series, bucket)
+);
+
+CREATE TABLE IF NOT EXISTS metric_rollups_daily (
+    computer_id INTEGER NOT NULL REFERENCES computers(computer_id) ON DELETE CASCADE,
+    metric VARCHAR(50) NOT NULL,
+    series VARCHAR(255) NOT NULL DEFAULT '',
+    bucket TIMESTAMP NOT NULL,
+    sum DOUBLE PRECISION NOT NULL,
+    count INTEGER NOT NULL,
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    p95 DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (computer_id, metric, series, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS metric_rollups_hourly_bucket_idx ON metric_rollups_hourly (bucket);
+CREATE INDEX IF NOT EXISTS metric_rollups_daily_bucket_idx ON metric_rollups_daily (bucket);
+
+-- The retention job selects raw samples by age across all computers
+CREATE INDEX IF NOT EXISTS processors_time_idx ON processors (timestamp);
+CREATE INDEX IF NOT EXISTS memory_time_idx ON memory (timestamp);
+CREATE INDEX IF NOT EXISTS network_adapters_time_idx ON network_adapters (timestamp);
+CREATE INDEX IF NOT EXISTS disks_time_idx ON disks (timestamp);
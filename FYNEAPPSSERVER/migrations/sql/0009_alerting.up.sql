@@ -0,0 +1,37 @@
+-- Alert rules evaluated periodically by the alerting engine. A threshold rule
+-- fires when metric <operator> threshold holds for duration_sec; a no_data
+-- rule fires when a computer has sent no telemetry for duration_sec. A NULL
+-- computer_id applies the rule to every computer. Empty sinks notify every
+-- configured sink.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    rule_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    metric VARCHAR(50),
+    operator VARCHAR(2),
+    threshold DOUBLE PRECISION,
+    duration_sec INTEGER NOT NULL DEFAULT 0,
+    computer_id INTEGER REFERENCES computers(computer_id) ON DELETE CASCADE,
+    severity VARCHAR(20) NOT NULL DEFAULT 'warning',
+    sinks TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per firing episode of a rule on a computer and series (disk or
+-- adapter). The row is kept after it resolves as the alert history.
+CREATE TABLE IF NOT EXISTS alerts (
+    alert_id SERIAL PRIMARY KEY,
+    rule_id INTEGER NOT NULL REFERENCES alert_rules(rule_id) ON DELETE CASCADE,
+    computer_id INTEGER NOT NULL REFERENCES computers(computer_id) ON DELETE CASCADE,
+    series VARCHAR(255) NOT NULL DEFAULT '',
+    state VARCHAR(20) NOT NULL,
+    value DOUBLE PRECISION,
+    message TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    ticket_id INTEGER REFERENCES tickets(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS alerts_firing_idx ON alerts (rule_id, computer_id, series) WHERE state = 'firing';
+CREATE INDEX IF NOT EXISTS alerts_started_idx ON alerts (started_at);
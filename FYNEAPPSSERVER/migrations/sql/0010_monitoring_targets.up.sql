@@ -0,0 +1,25 @@
+-- Servers checked on the client's server status tab. Every client reads the
+-- same list and admins edit it through the API. check_types lists the
+-- checks run against host; interval_sec is how often a client repeats them
+-- while auto-refresh is on.
+CREATE TABLE IF NOT EXISTS monitoring_targets (
+    target_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    host VARCHAR(255) NOT NULL,
+    group_name VARCHAR(100) NOT NULL DEFAULT '',
+    check_types TEXT[] NOT NULL DEFAULT '{ping,http,trace}',
+    interval_sec INTEGER NOT NULL DEFAULT 30,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS monitoring_targets_group_idx ON monitoring_targets (group_name, name);
+
+-- The servers that used to be built into the client
+INSERT INTO monitoring_targets (name, host, group_name)
+SELECT name, host, group_name FROM (VALUES
+    ('Сайт ПГАТУ', '91.203.238.2', 'ПГАТУ'),
+    ('Корпоративный Портал ПГАТУ', '91.203.238.4', 'ПГАТУ'),
+    ('Мой хост', '83.166.245.249', '')
+) AS seed (name, host, group_name)
+WHERE NOT EXISTS (SELECT 1 FROM monitoring_targets);
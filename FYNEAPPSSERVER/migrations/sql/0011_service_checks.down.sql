@@ -0,0 +1,9 @@
+ALTER TABLE monitoring_targets
+    DROP COLUMN IF EXISTS http_url,
+    DROP COLUMN IF EXISTS http_expect_status,
+    DROP COLUMN IF EXISTS http_expect_body,
+    DROP COLUMN IF EXISTS http_max_latency_ms,
+    DROP COLUMN IF EXISTS tcp_ports,
+    DROP COLUMN IF EXISTS tls_port,
+    DROP COLUMN IF EXISTS tls_warn_days,
+    DROP COLUMN IF EXISTS dns_resolver;
@@ -0,0 +1,12 @@
+-- Settings of the TCP, TLS, DNS and HTTP checks of a monitoring target.
+-- Zero and empty values select the defaults described in
+-- models.MonitoringTarget.
+ALTER TABLE monitoring_targets
+    ADD COLUMN IF NOT EXISTS http_url VARCHAR(1000) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS http_expect_status INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS http_expect_body VARCHAR(1000) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS http_max_latency_ms INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tcp_ports INTEGER[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS tls_port INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tls_warn_days INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_resolver VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Every check of a monitoring target, kept for uptime reports. status is
+-- up, degraded or down; reachable is false only for down. results holds
+-- the individual check results as JSON.
+CREATE TABLE IF NOT EXISTS server_checks (
+    check_id BIGSERIAL PRIMARY KEY,
+    target_id INTEGER NOT NULL REFERENCES monitoring_targets(target_id) ON DELETE CASCADE,
+    checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reachable BOOLEAN NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    latency_ms INTEGER,
+    http_code INTEGER,
+    results JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS server_checks_target_idx ON server_checks (target_id, checked_at);
+CREATE INDEX IF NOT EXISTS server_checks_checked_at_idx ON server_checks (checked_at);
@@ -0,0 +1,24 @@
+-- Planned downtime of a monitoring target, or of every target of a group
+-- when target_id is null. A one-off window lasts from starts_at to ends_at;
+-- a recurring one starts whenever the five-field cron schedule matches in
+-- server local time and lasts duration_min minutes. Failed checks during a
+-- window are stored with status maintenance and left out of uptime.
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    window_id SERIAL PRIMARY KEY,
+    target_id INTEGER REFERENCES monitoring_targets(target_id) ON DELETE CASCADE,
+    group_name VARCHAR(100),
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    schedule VARCHAR(100),
+    duration_min INTEGER,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((target_id IS NULL) <> (group_name IS NULL)),
+    CHECK (
+        (schedule IS NULL AND duration_min IS NULL AND starts_at IS NOT NULL AND ends_at > starts_at)
+        OR (schedule IS NOT NULL AND duration_min > 0 AND starts_at IS NULL AND ends_at IS NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS maintenance_windows_target_idx ON maintenance_windows (target_id);
+CREATE INDEX IF NOT EXISTS maintenance_windows_group_idx ON maintenance_windows (group_name);
@@ -0,0 +1 @@
+ALTER TABLE monitoring_targets DROP CONSTRAINT IF EXISTS monitoring_targets_interval_check;
@@ -0,0 +1,9 @@
+-- Check intervals outside the bounds accepted by the API stop the checker.
+-- Rows written around the API are clamped before the constraint is added.
+UPDATE monitoring_targets
+SET interval_sec = LEAST(GREATEST(interval_sec, 5), 86400)
+WHERE interval_sec NOT BETWEEN 5 AND 86400;
+
+ALTER TABLE monitoring_targets
+    DROP CONSTRAINT IF EXISTS monitoring_targets_interval_check,
+    ADD CONSTRAINT monitoring_targets_interval_check CHECK (interval_sec BETWEEN 5 AND 86400);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS tickets_assignee_status_idx;
+ALTER TABLE tickets DROP COLUMN IF EXISTS assignee;
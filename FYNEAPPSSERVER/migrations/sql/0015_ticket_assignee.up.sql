@@ -0,0 +1,6 @@
+-- The technician a ticket is assigned to; user_id is the author of the ticket.
+-- Renaming or deleting the account keeps the ticket.
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS assignee VARCHAR(100)
+    REFERENCES users(username) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS tickets_assignee_status_idx ON tickets (assignee, status_id);
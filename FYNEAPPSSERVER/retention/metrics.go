@@ -0,0 +1,53 @@
+package retention
+
+import (
+	"sort"
+	"strings"
+)
+
+// Metric maps a public metric name to a telemetry column. Tables that hold
+// several devices per computer (disks, adapters) are split into series by
+// SeriesColumn.
+type Metric struct {
+	Table        string
+	Column       string
+	SeriesColumn string
+	Unit         string
+}
+
+// Metrics are the columns served by the metrics endpoint and kept in the
+// rollup tables once raw samples expire
+var Metrics = map[string]Metric{
+	"cpu_usage":        {Table: "processors", Column: "usage_percent", Unit: "%"},
+	"cpu_clock_speed":  {Table: "processors", Column: "clock_speed", Unit: "GHz"},
+	"memory_usage":     {Table: "memory", Column: "usage_percent", Unit: "%"},
+	"memory_used_gb":   {Table: "memory", Column: "used_memory_gb", Unit: "GB"},
+	"memory_free_gb":   {Table: "memory", Column: "free_memory_gb", Unit: "GB"},
+	"disk_usage":       {Table: "disks", Column: "usage_percent", SeriesColumn: "drive_letter", Unit: "%"},
+	"disk_used_gb":     {Table: "disks", Column: "used_space_gb", SeriesColumn: "drive_letter", Unit: "GB"},
+	"disk_free_gb":     {Table: "disks", Column: "free_space_gb", SeriesColumn: "drive_letter", Unit: "GB"},
+	"network_upload":   {Table: "network_adapters", Column: "upload_speed_mbps", SeriesColumn: "adapter_name", Unit: "MB/s"},
+	"network_download": {Table: "network_adapters", Column: "download_speed_mbps", SeriesColumn: "adapter_name", Unit: "MB/s"},
+}
+
+// MetricNames returns the metric names sorted and comma-separated
+func MetricNames() string {
+	names := make([]string, 0, len(Metrics))
+	for name := range Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// tableMetrics groups the metrics by raw table, in a stable order
+func tableMetrics() map[string][]string {
+	tables := make(map[string][]string)
+	for name, m := range Metrics {
+		tables[m.Table] = append(tables[m.Table], name)
+	}
+	for _, names := range tables {
+		sort.Strings(names)
+	}
+	return tables
+}
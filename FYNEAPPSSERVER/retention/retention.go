@@ -0,0 +1,288 @@
+// Package retention keeps the telemetry tables from growing without bound.
+// Raw samples older than RawDays are rolled up into hourly aggregates and
+// deleted; hourly aggregates older than HourlyDays are rolled up into daily
+// ones, and daily aggregates older than DailyDays are deleted. Server checks
+// older than ChecksDays are deleted.
+package retention
+
+import (
+	"FYNEAPPSSERVER/api/database"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chunk is the span of samples moved by one statement, so that a large
+// backlog is processed in short transactions
+const chunk = 24 * time.Hour
+
+// Config sets how long each resolution is kept. Zero RawDays disables the
+// job; zero DailyDays keeps daily aggregates forever and zero ChecksDays
+// keeps server checks forever.
+type Config struct {
+	RawDays    int
+	HourlyDays int
+	DailyDays  int
+	ChecksDays int
+	Interval   time.Duration
+}
+
+// DefaultConfig keeps raw samples for a month, hourly aggregates for half
+// a year and server checks for a year
+func DefaultConfig() Config {
+	return Config{RawDays: 30, HourlyDays: 180, ChecksDays: 365, Interval: time.Hour}
+}
+
+// ConfigFromEnv reads RETENTION_RAW_DAYS, RETENTION_HOURLY_DAYS,
+// RETENTION_DAILY_DAYS, RETENTION_CHECKS_DAYS and RETENTION_INTERVAL over
+// the defaults
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	for _, v := range []struct {
+		env  string
+		days *int
+	}{
+		{"RETENTION_RAW_DAYS", &cfg.RawDays},
+		{"RETENTION_HOURLY_DAYS", &cfg.HourlyDays},
+		{"RETENTION_DAILY_DAYS", &cfg.DailyDays},
+		{"RETENTION_CHECKS_DAYS", &cfg.ChecksDays},
+	} {
+		s := os.Getenv(v.env)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return Config{}, fmt.Errorf("invalid %s %q: expected a number of days", v.env, s)
+		}
+		*v.days = n
+	}
+	if s := os.Getenv("RETENTION_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < time.Minute {
+			return Config{}, fmt.Errorf("invalid RETENTION_INTERVAL %q: expected a duration of at least 1m", s)
+		}
+		cfg.Interval = d
+	}
+	if cfg.RawDays > 0 && cfg.HourlyDays > 0 && cfg.HourlyDays < cfg.RawDays {
+		return Config{}, fmt.Errorf("RETENTION_HOURLY_DAYS (%d) must not be less than RETENTION_RAW_DAYS (%d)", cfg.HourlyDays, cfg.RawDays)
+	}
+	if cfg.DailyDays > 0 && cfg.DailyDays < cfg.HourlyDays {
+		return Config{}, fmt.Errorf("RETENTION_DAILY_DAYS (%d) must not be less than RETENTION_HOURLY_DAYS (%d)", cfg.DailyDays, cfg.HourlyDays)
+	}
+	// Uptime reports cover the last 30 days
+	if cfg.ChecksDays > 0 && cfg.ChecksDays < 30 {
+		return Config{}, fmt.Errorf("RETENTION_CHECKS_DAYS (%d) must be at least 30", cfg.ChecksDays)
+	}
+	return cfg, nil
+}
+
+// Result counts the rows handled by one pass
+type Result struct {
+	RawRolledUp    int64
+	HourlyRolledUp int64
+	DailyDeleted   int64
+	ChecksDeleted  int64
+}
+
+// Run applies the policy immediately and then every cfg.Interval until ctx
+// is done. Errors are logged and retried on the next tick.
+func Run(ctx context.Context, db *sql.DB, cfg Config) {
+	if cfg.RawDays == 0 {
+		log.Println("retention: disabled, raw telemetry is kept forever")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		res, err := Apply(ctx, db, cfg, time.Now())
+		if err != nil {
+			log.Printf("retention: %v", err)
+		} else if res != (Result{}) {
+			log.Printf("retention: rolled up %d raw and %d hourly rows, deleted %d daily rows and %d server checks",
+				res.RawRolledUp, res.HourlyRolledUp, res.DailyDeleted, res.ChecksDeleted)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Apply runs one pass of the policy relative to now. Cutoffs are aligned to
+// bucket boundaries so that a bucket is never rolled up half at a time.
+func Apply(ctx context.Context, db *sql.DB, cfg Config, now time.Time) (Result, error) {
+	var res Result
+	if cfg.RawDays == 0 {
+		return res, nil
+	}
+
+	rawCutoff := startOfDay(now.AddDate(0, 0, -cfg.RawDays))
+	tables := tableMetrics()
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+	for _, table := range names {
+		n, err := rollUpRaw(ctx, db, table, tables[table], rawCutoff)
+		res.RawRolledUp += n
+		if err != nil {
+			return res, fmt.Errorf("roll up %s: %v", table, err)
+		}
+	}
+
+	if cfg.HourlyDays > 0 {
+		n, err := rollUpHourly(ctx, db, startOfDay(now.AddDate(0, 0, -cfg.HourlyDays)))
+		res.HourlyRolledUp = n
+		if err != nil {
+			return res, fmt.Errorf("roll up hourly aggregates: %v", err)
+		}
+	}
+
+	if cfg.DailyDays > 0 {
+		r, err := db.ExecContext(ctx, "DELETE FROM metric_rollups_daily WHERE bucket < $1",
+			startOfDay(now.AddDate(0, 0, -cfg.DailyDays)))
+		if err != nil {
+			return res, fmt.Errorf("delete daily aggregates: %v", err)
+		}
+		res.DailyDeleted, _ = r.RowsAffected()
+	}
+
+	if cfg.ChecksDays > 0 {
+		r, err := db.ExecContext(ctx, "DELETE FROM server_checks WHERE checked_at < $1",
+			startOfDay(now.AddDate(0, 0, -cfg.ChecksDays)))
+		if err != nil {
+			return res, fmt.Errorf("delete server checks: %v", err)
+		}
+		res.ChecksDeleted, _ = r.RowsAffected()
+	}
+	return res, nil
+}
+
+// rollUpRaw moves samples of one table older than cutoff into
+// metric_rollups_hourly, one chunk per statement. Samples without a
+// computer cannot be aggregated and are only deleted.
+func rollUpRaw(ctx context.Context, db *sql.DB, table string, metrics []string, cutoff time.Time) (int64, error) {
+	columns := []string{"computer_id", "timestamp"}
+	var inserts []string
+	for i, name := range metrics {
+		m := Metrics[name]
+		columns = append(columns, m.Column)
+		series := "''"
+		if m.SeriesColumn != "" {
+			columns = append(columns, m.SeriesColumn)
+			series = "COALESCE(" + m.SeriesColumn + ", '')"
+		}
+		inserts = append(inserts, fmt.Sprintf(`
+			rollup_%[1]d AS (
+				INSERT INTO metric_rollups_hourly (computer_id, metric, series, bucket, sum, count, min, max, p95)
+				SELECT computer_id, '%[2]s', %[3]s, date_trunc('hour', timestamp),
+					sum(%[4]s), count(*), min(%[4]s), max(%[4]s),
+					percentile_cont(0.95) WITHIN GROUP (ORDER BY %[4]s)
+				FROM moved
+				WHERE computer_id IS NOT NULL AND %[4]s IS NOT NULL
+				GROUP BY 1, 2, 3, 4
+				%[5]s
+			)`, i, name, series, m.Column, onConflict("metric_rollups_hourly")))
+	}
+
+	query := fmt.Sprintf(`
+		WITH moved AS (
+			DELETE FROM %s
+			WHERE timestamp >= $1 AND timestamp < $2
+			RETURNING %s
+		),%s
+		SELECT count(*) FROM moved`,
+		table, strings.Join(unique(columns), ", "), strings.Join(inserts, ","))
+
+	var oldest sql.NullTime
+	err := db.QueryRowContext(ctx, "SELECT min(timestamp) FROM "+table+" WHERE timestamp < $1", cutoff).Scan(&oldest)
+	if err != nil || !oldest.Valid {
+		return 0, err
+	}
+	return moveChunks(ctx, db, query, startOfDay(database.WallClock(oldest.Time)), cutoff)
+}
+
+// rollUpHourly moves hourly aggregates older than cutoff into daily ones
+func rollUpHourly(ctx context.Context, db *sql.DB, cutoff time.Time) (int64, error) {
+	query := `
+		WITH moved AS (
+			DELETE FROM metric_rollups_hourly
+			WHERE bucket >= $1 AND bucket < $2
+			RETURNING *
+		),
+		daily AS (
+			INSERT INTO metric_rollups_daily (computer_id, metric, series, bucket, sum, count, min, max, p95)
+			SELECT computer_id, metric, series, date_trunc('day', bucket),
+				sum(sum), sum(count), min(min), max(max),
+				percentile_cont(0.95) WITHIN GROUP (ORDER BY p95)
+			FROM moved
+			GROUP BY 1, 2, 3, 4
+			` + onConflict("metric_rollups_daily") + `
+		)
+		SELECT count(*) FROM moved`
+
+	var oldest sql.NullTime
+	err := db.QueryRowContext(ctx, "SELECT min(bucket) FROM metric_rollups_hourly WHERE bucket < $1", cutoff).Scan(&oldest)
+	if err != nil || !oldest.Valid {
+		return 0, err
+	}
+	return moveChunks(ctx, db, query, startOfDay(database.WallClock(oldest.Time)), cutoff)
+}
+
+// onConflict merges late samples into a bucket that was already rolled up,
+// for example a batch replayed from a client's offline queue. The merged
+// p95 is an upper estimate.
+func onConflict(table string) string {
+	return fmt.Sprintf(`ON CONFLICT (computer_id, metric, series, bucket) DO UPDATE SET
+					sum = %[1]s.sum + excluded.sum,
+					count = %[1]s.count + excluded.count,
+					min = LEAST(%[1]s.min, excluded.min),
+					max = GREATEST(%[1]s.max, excluded.max),
+					p95 = GREATEST(%[1]s.p95, excluded.p95)`, table)
+}
+
+// moveChunks runs query for each chunk between from and to
+func moveChunks(ctx context.Context, db *sql.DB, query string, from, to time.Time) (int64, error) {
+	var total int64
+	for start := from; start.Before(to); start = start.Add(chunk) {
+		end := start.Add(chunk)
+		if end.After(to) {
+			end = to
+		}
+		var n int64
+		if err := db.QueryRowContext(ctx, query, start, end).Scan(&n); err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// startOfDay returns local midnight; timestamps are stored as local time
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,281 @@
+// Package uptime stores the checks of monitoring targets, publishes them
+// to live subscribers and reports the availability of the targets: uptime
+// percentages, latency and outages.
+package uptime
+
+import (
+	"FYNEAPPSSERVER/api/database"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/maintenance"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LatencyStep is the bucket width of UptimeReport.Latency
+const LatencyStep = 15 * time.Minute
+
+// Statuses lists the statuses a recorded check may have; Record turns a
+// failure during maintenance into models.CheckStatusMaintenance
+var Statuses = []string{models.CheckStatusUp, models.CheckStatusDegraded, models.CheckStatusDown}
+
+// Record stores check at the current server time and fills in its ID,
+// CheckedAt and Reachable, then publishes it to the subscribers. A down or
+// degraded check of a target in a maintenance window is stored as
+// maintenance. The caller validates Status.
+func Record(ctx context.Context, db *sql.DB, check *models.ServerCheck) error {
+	if check.Results == nil {
+		check.Results = []models.CheckResult{}
+	}
+	results, err := json.Marshal(check.Results)
+	if err != nil {
+		return err
+	}
+	if check.Status != models.CheckStatusUp {
+		window, err := maintenance.ForTarget(ctx, db, check.TargetID, time.Now())
+		if err != nil {
+			return fmt.Errorf("maintenance windows: %v", err)
+		}
+		if window != nil {
+			check.Status = models.CheckStatusMaintenance
+		}
+	}
+	// A maintenance check counts as reachable, so it never opens or extends
+	// an incident
+	check.Reachable = check.Status != models.CheckStatusDown
+
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO server_checks (target_id, reachable, status, latency_ms, http_code, results)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING check_id, checked_at`,
+		check.TargetID, check.Reachable, check.Status, check.LatencyMs, check.HTTPCode, results,
+	).Scan(&check.CheckID, &check.CheckedAt)
+	if err != nil {
+		return err
+	}
+	publish(*check)
+	return nil
+}
+
+// subscriberBuffer is how far a subscriber may fall behind before checks
+// are dropped for it
+const subscriberBuffer = 64
+
+// feed holds the channels of the subscribers
+var feed = struct {
+	sync.Mutex
+	subscribers map[chan models.ServerCheck]struct{}
+}{subscribers: map[chan models.ServerCheck]struct{}{}}
+
+// Subscribe returns a channel receiving every check stored by Record in
+// this process until ctx is done; the channel is closed then. Checks are
+// dropped for a subscriber that does not keep up.
+func Subscribe(ctx context.Context) <-chan models.ServerCheck {
+	ch := make(chan models.ServerCheck, subscriberBuffer)
+	feed.Lock()
+	feed.subscribers[ch] = struct{}{}
+	feed.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		feed.Lock()
+		delete(feed.subscribers, ch)
+		close(ch)
+		feed.Unlock()
+	}()
+	return ch
+}
+
+func publish(check models.ServerCheck) {
+	feed.Lock()
+	defer feed.Unlock()
+	for ch := range feed.subscribers {
+		select {
+		case ch <- check:
+		default:
+		}
+	}
+}
+
+// Latest returns every monitoring target with its latest check, ordered
+// by group and name
+func Latest(ctx context.Context, db *sql.DB) ([]models.TargetStatus, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.target_id, t.name, t.host, t.group_name, t.enabled,
+			c.check_id, c.checked_at, c.reachable, c.status, c.latency_ms, c.http_code, c.results
+		FROM monitoring_targets t
+		LEFT JOIN LATERAL (
+			SELECT * FROM server_checks
+			WHERE target_id = t.target_id
+			ORDER BY checked_at DESC
+			LIMIT 1
+		) c ON true
+		ORDER BY t.group_name, t.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := []models.TargetStatus{}
+	for rows.Next() {
+		var (
+			status    models.TargetStatus
+			checkID   sql.NullInt64
+			checkedAt sql.NullTime
+			reachable sql.NullBool
+			state     sql.NullString
+			latencyMs sql.NullInt64
+			httpCode  sql.NullInt64
+			results   []byte
+		)
+		err := rows.Scan(&status.TargetID, &status.Name, &status.Host, &status.Group, &status.Enabled,
+			&checkID, &checkedAt, &reachable, &state, &latencyMs, &httpCode, &results)
+		if err != nil {
+			return nil, err
+		}
+		if checkID.Valid {
+			check := &models.ServerCheck{
+				CheckID:   checkID.Int64,
+				TargetID:  status.TargetID,
+				CheckedAt: checkedAt.Time,
+				Reachable: reachable.Bool,
+				Status:    state.String,
+			}
+			if latencyMs.Valid {
+				v := int(latencyMs.Int64)
+				check.LatencyMs = &v
+			}
+			if httpCode.Valid {
+				v := int(httpCode.Int64)
+				check.HTTPCode = &v
+			}
+			if err := json.Unmarshal(results, &check.Results); err != nil {
+				return nil, err
+			}
+			status.Check = check
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+// Report computes the availability of the target relative to now. Checks
+// during maintenance count neither for nor against uptime and end an
+// outage.
+func Report(ctx context.Context, db *sql.DB, targetID int, now time.Time) (*models.UptimeReport, error) {
+	report := &models.UptimeReport{
+		TargetID:       targetID,
+		Latency:        []models.LatencyPoint{},
+		LatencyStepSec: int64(LatencyStep / time.Second),
+		Incidents:      []models.Incident{},
+	}
+	day, week, month := now.Add(-24*time.Hour), now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)
+
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			(100.0 * count(*) FILTER (WHERE reachable AND checked_at >= $2) / NULLIF(count(*) FILTER (WHERE checked_at >= $2), 0))::float8,
+			(100.0 * count(*) FILTER (WHERE reachable AND checked_at >= $3) / NULLIF(count(*) FILTER (WHERE checked_at >= $3), 0))::float8,
+			(100.0 * count(*) FILTER (WHERE reachable) / NULLIF(count(*), 0))::float8
+		FROM server_checks
+		WHERE target_id = $1 AND checked_at >= $4 AND status <> $5`,
+		targetID, day, week, month, models.CheckStatusMaintenance,
+	).Scan(&report.Uptime24h, &report.Uptime7d, &report.Uptime30d)
+	if err != nil {
+		return nil, fmt.Errorf("uptime: %v", err)
+	}
+
+	if report.Latency, err = latency(ctx, db, targetID, day); err != nil {
+		return nil, fmt.Errorf("latency: %v", err)
+	}
+	if report.Incidents, err = incidents(ctx, db, targetID, month, now); err != nil {
+		return nil, fmt.Errorf("incidents: %v", err)
+	}
+	return report, nil
+}
+
+func latency(ctx context.Context, db *sql.DB, targetID int, from time.Time) ([]models.LatencyPoint, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			to_timestamp(floor(extract(epoch FROM checked_at) / $3) * $3) AT TIME ZONE 'UTC' AS bucket,
+			avg(latency_ms)::float8
+		FROM server_checks
+		WHERE target_id = $1 AND checked_at >= $2 AND latency_ms IS NOT NULL
+		GROUP BY bucket
+		ORDER BY bucket`,
+		targetID, from, int64(LatencyStep/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []models.LatencyPoint{}
+	for rows.Next() {
+		var p models.LatencyPoint
+		if err := rows.Scan(&p.Time, &p.AvgMs); err != nil {
+			return nil, err
+		}
+		p.Time = database.WallClock(p.Time)
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// incidents walks the checks since from in order and collects the runs of
+// down checks. An outage still open at now lasts until now.
+func incidents(ctx context.Context, db *sql.DB, targetID int, from, now time.Time) ([]models.Incident, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT checked_at, reachable
+		FROM server_checks
+		WHERE target_id = $1 AND checked_at >= $2
+		ORDER BY checked_at, check_id`,
+		targetID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		found   []models.Incident
+		current *models.Incident
+	)
+	for rows.Next() {
+		var (
+			at        time.Time
+			reachable bool
+		)
+		if err := rows.Scan(&at, &reachable); err != nil {
+			return nil, err
+		}
+		at = database.WallClock(at)
+
+		switch {
+		case !reachable && current == nil:
+			current = &models.Incident{Start: at, Checks: 1}
+		case !reachable:
+			current.Checks++
+		case current != nil:
+			end := at
+			current.End = &end
+			current.DurationSec = int64(end.Sub(current.Start) / time.Second)
+			found = append(found, *current)
+			current = nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		current.DurationSec = int64(now.Sub(current.Start) / time.Second)
+		found = append(found, *current)
+	}
+
+	// Newest first
+	result := make([]models.Incident, 0, len(found))
+	for i := len(found) - 1; i >= 0; i-- {
+		result = append(result, found[i])
+	}
+	return result, nil
+}
@@ -0,0 +1,362 @@
+// Package apiclient - клиент REST API сервера FYNEAPPSSERVER. Хранит токен,
+// выданный при входе, и подставляет его во все запросы.
+package apiclient
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestTimeout ограничивает время одного запроса к API
+const requestTimeout = 15 * time.Second
+
+// Client выполняет запросы к /api/v1 от имени пользователя или агента
+type Client struct {
+	baseURL string
+	http    *http.Client
+
+	mu     sync.RWMutex
+	token  string
+	apiKey string
+}
+
+// Error описывает ответ API с кодом ошибки
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API вернул статус %d", e.StatusCode)
+	}
+	return fmt.Sprintf("API вернул статус %d: %s", e.StatusCode, e.Message)
+}
+
+// New создает клиент для сервера по адресу baseURL (без /api/v1)
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1",
+		http:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// IsUnauthorized сообщает, что токен недействителен или истек
+func IsUnauthorized(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// StatusCode возвращает HTTP-код ошибки API или 0 для сетевых ошибок
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// SetToken задает токен сессии, например восстановленный из файла
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// Token возвращает текущий токен сессии
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetAPIKey задает ключ агента; используется вместо токена пользователя
+func (c *Client) SetAPIKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiKey = key
+}
+
+// Login проверяет логин и пароль на сервере и запоминает выданный токен
+func (c *Client) Login(ctx context.Context, username, password string) (*models.LoginResponse, error) {
+	var resp models.LoginResponse
+	req := models.LoginRequest{Username: username, Password: password}
+	if err := c.Do(ctx, http.MethodPost, "/auth/login", req, &resp); err != nil {
+		return nil, err
+	}
+
+	c.SetToken(resp.Token)
+	return &resp, nil
+}
+
+// Me возвращает пользователя, которому принадлежит текущий токен
+func (c *Client) Me(ctx context.Context) (*models.User, error) {
+	var user models.User
+	if err := c.Do(ctx, http.MethodGet, "/auth/me", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Logout отзывает токен на сервере и забывает его локально
+func (c *Client) Logout(ctx context.Context) error {
+	err := c.Do(ctx, http.MethodPost, "/auth/logout", nil, nil)
+	c.SetToken("")
+	return err
+}
+
+// ChangePassword меняет пароль текущего пользователя
+func (c *Client) ChangePassword(ctx context.Context, current, next string) error {
+	req := models.ChangePasswordRequest{CurrentPassword: current, NewPassword: next}
+	return c.Do(ctx, http.MethodPost, "/auth/password", req, nil)
+}
+
+// Metrics возвращает метрику компьютера, усредненную сервером по интервалам
+// длиной step (см. GET /computers/:id/metrics)
+func (c *Client) Metrics(ctx context.Context, computerID int, metric string, from, to time.Time, step time.Duration) (*models.MetricSeries, error) {
+	q := url.Values{}
+	q.Set("metric", metric)
+	q.Set("from", from.Format(time.RFC3339))
+	q.Set("to", to.Format(time.RFC3339))
+	q.Set("step", step.String())
+
+	var series models.MetricSeries
+	path := fmt.Sprintf("/computers/%d/metrics?%s", computerID, q.Encode())
+	if err := c.Do(ctx, http.MethodGet, path, nil, &series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// HardwareChanges возвращает последние limit изменений оборудования
+// компьютера, новые первыми
+func (c *Client) HardwareChanges(ctx context.Context, computerID, limit int) ([]models.HardwareChange, error) {
+	var page struct {
+		Data []models.HardwareChange `json:"data"`
+	}
+	path := fmt.Sprintf("/computers/%d/changes?limit=%d", computerID, limit)
+	if err := c.Do(ctx, http.MethodGet, path, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Data, nil
+}
+
+// Ingest отправляет пачку телеметрии. Повторная отправка пачки с тем же
+// BatchID возвращает Duplicate и ничего не записывает.
+func (c *Client) Ingest(ctx context.Context, batch *models.IngestBatch) (*models.IngestResult, error) {
+	var result models.IngestResult
+	if err := c.Do(ctx, http.MethodPost, "/ingest", batch, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MonitoringTargets возвращает общий список серверов вкладки статуса,
+// упорядоченный по группам
+func (c *Client) MonitoringTargets(ctx context.Context) ([]models.MonitoringTarget, error) {
+	var page struct {
+		Data []models.MonitoringTarget `json:"data"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/monitoring-targets?limit=1000", nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Data, nil
+}
+
+// CreateMonitoringTarget добавляет сервер в общий список; нужно право
+// targets:manage
+func (c *Client) CreateMonitoringTarget(ctx context.Context, target models.MonitoringTarget) (*models.MonitoringTarget, error) {
+	var created models.MonitoringTarget
+	if err := c.Do(ctx, http.MethodPost, "/monitoring-targets", target, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateMonitoringTarget заменяет сервер с target.TargetID
+func (c *Client) UpdateMonitoringTarget(ctx context.Context, target models.MonitoringTarget) (*models.MonitoringTarget, error) {
+	var updated models.MonitoringTarget
+	path := fmt.Sprintf("/monitoring-targets/%d", target.TargetID)
+	if err := c.Do(ctx, http.MethodPut, path, target, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteMonitoringTarget удаляет сервер из общего списка
+func (c *Client) DeleteMonitoringTarget(ctx context.Context, targetID int) error {
+	return c.Do(ctx, http.MethodDelete, fmt.Sprintf("/monitoring-targets/%d", targetID), nil, nil)
+}
+
+// RecordServerCheck сохраняет результат проверки сервера общего списка;
+// время проверки ставит сервер API
+func (c *Client) RecordServerCheck(ctx context.Context, check models.ServerCheck) error {
+	return c.Do(ctx, http.MethodPost, fmt.Sprintf("/monitoring-targets/%d/checks", check.TargetID), check, nil)
+}
+
+// Uptime возвращает доступность сервера за 24 часа, 7 и 30 дней, задержку
+// за сутки и простои за 30 дней
+func (c *Client) Uptime(ctx context.Context, targetID int) (*models.UptimeReport, error) {
+	var report models.UptimeReport
+	if err := c.Do(ctx, http.MethodGet, fmt.Sprintf("/monitoring-targets/%d/uptime", targetID), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// MaintenanceWindows возвращает окна обслуживания серверов и групп
+func (c *Client) MaintenanceWindows(ctx context.Context) ([]models.MaintenanceWindow, error) {
+	var page struct {
+		Data []models.MaintenanceWindow `json:"data"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/maintenance-windows?limit=1000", nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Data, nil
+}
+
+// CreateMaintenanceWindow добавляет окно обслуживания; нужно право
+// targets:manage
+func (c *Client) CreateMaintenanceWindow(ctx context.Context, w models.MaintenanceWindow) (*models.MaintenanceWindow, error) {
+	var created models.MaintenanceWindow
+	if err := c.Do(ctx, http.MethodPost, "/maintenance-windows", w, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// DeleteMaintenanceWindow удаляет окно обслуживания
+func (c *Client) DeleteMaintenanceWindow(ctx context.Context, windowID int) error {
+	return c.Do(ctx, http.MethodDelete, fmt.Sprintf("/maintenance-windows/%d", windowID), nil, nil)
+}
+
+// Status возвращает последнюю проверку каждого сервера общего списка и
+// признак того, что сервер API проверяет их сам
+func (c *Client) Status(ctx context.Context) (*models.StatusReport, error) {
+	var report models.StatusReport
+	if err := c.Do(ctx, http.MethodGet, "/status", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// StreamStatus подписывается на поток новых проверок серверов и вызывает
+// onCheck для каждой, пока не отменен ctx или не оборвалось соединение.
+// Возвращает причину завершения.
+func (c *Client) StreamStatus(ctx context.Context, onCheck func(models.ServerCheck)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/status/stream", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.authorize(req)
+
+	// Поток открыт долго, поэтому requestTimeout к нему не применяется
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("сервер API недоступен: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &Error{StatusCode: resp.StatusCode}
+	}
+
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event == "check" && data != "" {
+				var check models.ServerCheck
+				if err := json.Unmarshal([]byte(data), &check); err != nil {
+					return fmt.Errorf("ошибка разбора события: %v", err)
+				}
+				onCheck(check)
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("поток проверок прерван: %v", err)
+	}
+	return errors.New("сервер API закрыл поток проверок")
+}
+
+// authorize подставляет в запрос токен пользователя или ключ агента
+func (c *Client) authorize(req *http.Request) {
+	c.mu.RLock()
+	token, apiKey := c.token, c.apiKey
+	c.mu.RUnlock()
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case apiKey != "":
+		req.Header.Set("X-API-Key", apiKey)
+	}
+}
+
+// Do отправляет запрос к path (относительно /api/v1). body кодируется в
+// JSON, ответ декодируется в out, если он не nil.
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("ошибка кодирования запроса: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("сервер API недоступен: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		// Сервер возвращает ошибки как JSON-строку
+		if json.Unmarshal(data, &apiErr.Message) != nil {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("ошибка разбора ответа API: %v", err)
+	}
+	return nil
+}
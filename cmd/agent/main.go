@@ -0,0 +1,184 @@
+// Command agent - фоновый агент телеметрии без графического интерфейса.
+// Собирает те же показатели, что вкладка "Мониторинг системы", копит их в
+// памяти и с заданным периодом отправляет пачкой в REST API (POST /ingest по
+// ключу агента) или напрямую в БД. Неотправленное при остановке сохраняется
+// в очередь на диске рядом с файлом состояния и уходит после перезапуска.
+// Предназначен для запуска службой systemd, см. deploy/pgatu-agent.service.
+//
+//	agent -agent-interval 15s -agent-flush-interval 1m -agent-sink api -agent-api-key pgk_...
+//
+// Остальные параметры берутся из того же config.json и переменных PGATU_*,
+// что и у основного приложения.
+package main
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPS/config"
+	"FYNEAPPS/database"
+	"FYNEAPPS/spool"
+	"FYNEAPPS/telemetry"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Ограничения отправки
+const (
+	// sendTimeout ограничивает одну отправку накопленных пачек
+	sendTimeout = time.Minute
+	// maxBuffered замеров хранится в памяти, пока сервер недоступен
+	maxBuffered = 10000
+
+	// spoolFile очередь неотправленных пачек, лежит рядом с файлом состояния
+	spoolFile = "agent-spool.jsonl"
+	// spoolBatch вид записи очереди
+	spoolBatch = "batch"
+)
+
+func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+	config.Set(cfg)
+
+	interval, flushInterval, err := cfg.Agent.Durations()
+	if err != nil {
+		log.Fatal(err)
+	}
+	filter, err := telemetry.FilterFromConfig(cfg.Telemetry)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sink, closeSink, err := newSink(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeSink()
+
+	queue, err := openSpool(cfg, sink)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer queue.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Агент телеметрии запущен: сбор каждые %s, отправка в %s каждые %s", interval, cfg.Agent.Sink, flushInterval)
+	run(ctx, telemetry.NewCollector(), filter, sink, queue, interval, flushInterval)
+	log.Println("Агент телеметрии остановлен")
+}
+
+// newSink создает получатель замеров по настройкам агента
+func newSink(cfg *config.Config) (telemetry.Sink, func(), error) {
+	if cfg.Agent.Sink == config.AgentSinkDB {
+		pool := database.New()
+		// Недоступная при старте БД не мешает запуску: пул переподключится сам
+		if err := pool.Connect(cfg.Database.Options()); err != nil {
+			log.Printf("БД недоступна, замеры будут отправлены после восстановления связи: %v", err)
+		}
+		pool.Watch(15 * time.Second)
+		return telemetry.NewDBSink(pool.DB()), func() { pool.Disconnect() }, nil
+	}
+
+	if cfg.Agent.APIKey == "" {
+		return nil, nil, errors.New("для отправки в API нужен ключ агента: agent.api_key, PGATU_AGENT_API_KEY или -agent-api-key")
+	}
+	api := apiclient.New(cfg.API.URL)
+	api.SetAPIKey(cfg.Agent.APIKey)
+
+	sink, err := telemetry.NewAPISink(api, cfg.Agent.StatePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sink, func() {}, nil
+}
+
+// openSpool открывает очередь пачек, не отправленных до прошлой остановки
+func openSpool(cfg *config.Config, sink telemetry.Sink) (*spool.Spool, error) {
+	queue, err := spool.Open(filepath.Join(filepath.Dir(cfg.Agent.StatePath), spoolFile))
+	if err != nil {
+		return nil, err
+	}
+	queue.Handle(spoolBatch, func(ctx context.Context, data json.RawMessage) error {
+		var batch telemetry.Batch
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return err
+		}
+		return sink.Send(ctx, &batch)
+	})
+	if n := queue.Len(); n > 0 {
+		log.Printf("В очереди с прошлого запуска пачек: %d", n)
+	}
+	return queue, nil
+}
+
+// run снимает замер сразу и затем раз в interval, а раз в flushInterval
+// отправляет накопленное. Неудачная отправка только логируется: замеры
+// остаются в буфере и уйдут со следующей попыткой, а при остановке
+// сохраняются в очередь на диске.
+func run(ctx context.Context, collector *telemetry.Collector, filter *telemetry.ChangeFilter, sink telemetry.Sink, queue *spool.Spool, interval, flushInterval time.Duration) {
+	buffer := telemetry.NewBuffer(maxBuffered)
+
+	collectTicker := time.NewTicker(interval)
+	defer collectTicker.Stop()
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+
+	collect(collector, filter, buffer)
+	for {
+		select {
+		case <-collectTicker.C:
+			collect(collector, filter, buffer)
+		case <-flushTicker.C:
+			flush(ctx, buffer, sink, queue)
+		case <-ctx.Done():
+			// Последняя попытка отправить накопленное перед остановкой;
+			// то, что не ушло, переживет перезапуск в очереди
+			if !flush(context.Background(), buffer, sink, queue) {
+				for _, batch := range buffer.Drain() {
+					if err := queue.Enqueue(spoolBatch, batch); err != nil {
+						log.Printf("Не удалось сохранить пачку в очередь, потеряно замеров: %d: %v", len(batch.Snapshots), err)
+					}
+				}
+			}
+			return
+		}
+	}
+}
+
+// collect снимает замер и кладет в буфер то, что изменилось с прошлой записи
+func collect(collector *telemetry.Collector, filter *telemetry.ChangeFilter, buffer *telemetry.Buffer) {
+	snapshot, err := collector.Collect()
+	if err != nil {
+		log.Printf("Ошибка сбора телеметрии: %v", err)
+		return
+	}
+	if filter.Apply(snapshot) {
+		buffer.Add(snapshot)
+	}
+}
+
+// flush отправляет сначала пачки из очереди на диске, затем буфер, чтобы
+// сохранить порядок. Возвращает false, если отправить все не удалось.
+func flush(ctx context.Context, buffer *telemetry.Buffer, sink telemetry.Sink, queue *spool.Spool) bool {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := queue.Replay(ctx, telemetry.Rejected); err != nil {
+		log.Printf("Ошибка отправки сохраненных пачек (осталось: %d): %v", queue.Len(), err)
+		return false
+	}
+	if err := buffer.Flush(ctx, sink); err != nil {
+		log.Printf("Ошибка отправки телеметрии (в очереди замеров: %d): %v", buffer.Len(), err)
+		return false
+	}
+	return true
+}
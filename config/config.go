@@ -0,0 +1,397 @@
+// Package config загружает настройки приложения из нескольких слоёв.
+//
+// Слои применяются по порядку, каждый следующий перекрывает предыдущий:
+// встроенные значения по умолчанию, файл config.json в каталоге настроек
+// пользователя, переменные окружения PGATU_*, флаги командной строки.
+package config
+
+import (
+	"FYNEAPPS/database"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AppDirName имя каталога приложения внутри os.UserConfigDir()
+const AppDirName = "pgatu-infrastructure"
+
+// Config содержит все настройки, которые раньше были зашиты в код
+type Config struct {
+	// Profile имя выбранного профиля (например, "staging" или "production")
+	Profile  string   `json:"profile,omitempty"`
+	Database Database `json:"database"`
+	API      API      `json:"api"`
+	Agent    Agent    `json:"agent"`
+	// Telemetry правила записи замеров, общие для приложения и агента
+	Telemetry Telemetry `json:"telemetry"`
+	// Profiles именованные наборы параметров БД, перекрывающие Database
+	Profiles map[string]Database `json:"profiles,omitempty"`
+	// Args позиционные аргументы после флагов (например, "migrate up")
+	Args []string `json:"-"`
+}
+
+// Database параметры подключения к PostgreSQL
+type Database struct {
+	Host     string `json:"host,omitempty"`
+	Port     string `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	Name     string `json:"name,omitempty"`
+	SSLMode  string `json:"sslmode,omitempty"`
+}
+
+// API параметры REST API сервера FYNEAPPSSERVER
+type API struct {
+	// URL адрес сервера без /api/v1, например http://localhost:8081
+	URL string `json:"url,omitempty"`
+}
+
+// Agent параметры фонового агента телеметрии (cmd/agent)
+type Agent struct {
+	// Interval период сбора в формате Go, например "15s" или "1m"
+	Interval string `json:"interval,omitempty"`
+	// FlushInterval период отправки накопленных замеров одной пачкой
+	FlushInterval string `json:"flush_interval,omitempty"`
+	// Sink куда отправлять замеры: "api" (по ключу агента) или "db"
+	Sink string `json:"sink,omitempty"`
+	// APIKey ключ со scope telemetry:write для режима "api"
+	APIKey string `json:"api_key,omitempty"`
+	// StatePath файл, где агент хранит ID компьютера, выданный сервером
+	StatePath string `json:"state_path,omitempty"`
+}
+
+// Telemetry определяет, какие замеры стоит записывать. Показатель
+// записывается, только если он изменился больше чем на порог с прошлой
+// записи или с нее прошел Heartbeat. Порог "0" записывает все замеры.
+type Telemetry struct {
+	// ChangeDelta порог изменения загрузки ЦП, памяти и дисков в процентных
+	// пунктах, например "2"
+	ChangeDelta string `json:"change_delta,omitempty"`
+	// NetworkDelta порог изменения скорости сети в МБ/с, например "0.1"
+	NetworkDelta string `json:"network_delta,omitempty"`
+	// Heartbeat период, после которого показатель записывается даже без
+	// изменений, например "5m"
+	Heartbeat string `json:"heartbeat,omitempty"`
+}
+
+// Режимы отправки замеров агентом
+const (
+	AgentSinkAPI = "api"
+	AgentSinkDB  = "db"
+)
+
+// Durations разбирает Interval и FlushInterval; периоды короче секунды
+// не допускаются
+func (a Agent) Durations() (interval, flush time.Duration, err error) {
+	if interval, err = parseInterval("интервал сбора", a.Interval); err != nil {
+		return 0, 0, err
+	}
+	if flush, err = parseInterval("интервал отправки", a.FlushInterval); err != nil {
+		return 0, 0, err
+	}
+	return interval, flush, nil
+}
+
+// Thresholds разбирает пороги и период записи без изменений
+func (t Telemetry) Thresholds() (delta, networkDelta float64, heartbeat time.Duration, err error) {
+	if delta, err = parseDelta("порог изменения", t.ChangeDelta); err != nil {
+		return 0, 0, 0, err
+	}
+	if networkDelta, err = parseDelta("порог изменения скорости сети", t.NetworkDelta); err != nil {
+		return 0, 0, 0, err
+	}
+	heartbeat, err = time.ParseDuration(t.Heartbeat)
+	if err != nil || heartbeat < time.Second {
+		return 0, 0, 0, fmt.Errorf("некорректный период записи без изменений %q: ожидается, например, 5m", t.Heartbeat)
+	}
+	return delta, networkDelta, heartbeat, nil
+}
+
+func parseDelta(name, v string) (float64, error) {
+	d, err := strconv.ParseFloat(v, 64)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("некорректный %s телеметрии %q: ожидается неотрицательное число", name, v)
+	}
+	return d, nil
+}
+
+func parseInterval(name, v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("некорректный %s агента %q: ожидается, например, 30s или 5m", name, v)
+	}
+	return d, nil
+}
+
+var (
+	currentMu sync.RWMutex
+	current   = Default()
+)
+
+// Default возвращает встроенные значения по умолчанию
+func Default() *Config {
+	return &Config{
+		Database: Database{
+			Host:     "83.166.245.249",
+			Port:     "5432",
+			User:     "user",
+			Password: "user",
+			Name:     "grafana_db",
+			SSLMode:  "disable",
+		},
+		API: API{
+			URL: "http://83.166.245.249:8081",
+		},
+		Agent: Agent{
+			Interval:      "15s",
+			FlushInterval: "60s",
+			Sink:          AgentSinkAPI,
+		},
+		Telemetry: Telemetry{
+			ChangeDelta:  "2",
+			NetworkDelta: "0.1",
+			Heartbeat:    "5m",
+		},
+	}
+}
+
+// Current возвращает активную конфигурацию
+func Current() *Config {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// Set делает cfg активной конфигурацией
+func Set(cfg *Config) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = cfg
+}
+
+// DefaultPath возвращает путь к файлу конфигурации по умолчанию
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("не удалось определить каталог настроек: %v", err)
+	}
+	return filepath.Join(dir, AppDirName, "config.json"), nil
+}
+
+// Load собирает конфигурацию из всех слоёв. args - аргументы командной
+// строки без имени программы.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("FYNEAPPS", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var (
+		configPath = fs.String("config", "", "путь к файлу конфигурации")
+		profile    = fs.String("profile", "", "имя профиля из файла конфигурации")
+		flagDB     Database
+		apiURL     = fs.String("api-url", "", "адрес REST API сервера")
+		flagAgent  Agent
+		flagTel    Telemetry
+	)
+	fs.StringVar(&flagAgent.Interval, "agent-interval", "", "период сбора телеметрии агентом")
+	fs.StringVar(&flagAgent.FlushInterval, "agent-flush-interval", "", "период отправки накопленной телеметрии")
+	fs.StringVar(&flagAgent.Sink, "agent-sink", "", "куда агент отправляет замеры (api, db)")
+	fs.StringVar(&flagAgent.APIKey, "agent-api-key", "", "ключ API агента")
+	fs.StringVar(&flagAgent.StatePath, "agent-state", "", "файл состояния агента")
+	fs.StringVar(&flagTel.ChangeDelta, "telemetry-delta", "", "порог изменения загрузки для записи замера, п.п.")
+	fs.StringVar(&flagTel.NetworkDelta, "telemetry-network-delta", "", "порог изменения скорости сети для записи замера, МБ/с")
+	fs.StringVar(&flagTel.Heartbeat, "telemetry-heartbeat", "", "период записи замера без изменений")
+	fs.StringVar(&flagDB.Host, "db-host", "", "адрес сервера PostgreSQL")
+	fs.StringVar(&flagDB.Port, "db-port", "", "порт сервера PostgreSQL")
+	fs.StringVar(&flagDB.User, "db-user", "", "пользователь PostgreSQL")
+	fs.StringVar(&flagDB.Password, "db-password", "", "пароль PostgreSQL")
+	fs.StringVar(&flagDB.Name, "db-name", "", "имя базы данных")
+	fs.StringVar(&flagDB.SSLMode, "db-sslmode", "", "режим SSL (disable, require, verify-full)")
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("ошибка разбора флагов: %v", err)
+	}
+
+	// Файл конфигурации: флаг > переменная окружения > путь по умолчанию
+	path, explicit := *configPath, true
+	if path == "" {
+		path = os.Getenv("PGATU_CONFIG")
+	}
+	if path == "" {
+		explicit = false
+		var err error
+		if path, err = DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.mergeFile(path, explicit); err != nil {
+		return nil, err
+	}
+
+	// Профиль выбирается до применения env/флагов, чтобы они его перекрывали
+	if env := os.Getenv("PGATU_PROFILE"); env != "" {
+		cfg.Profile = env
+	}
+	if *profile != "" {
+		cfg.Profile = *profile
+	}
+	if cfg.Profile != "" {
+		p, ok := cfg.Profiles[cfg.Profile]
+		if !ok {
+			return nil, fmt.Errorf("профиль %q не найден в %s", cfg.Profile, path)
+		}
+		cfg.Database.merge(p)
+	}
+
+	cfg.Database.merge(Database{
+		Host:     os.Getenv("PGATU_DB_HOST"),
+		Port:     os.Getenv("PGATU_DB_PORT"),
+		User:     os.Getenv("PGATU_DB_USER"),
+		Password: os.Getenv("PGATU_DB_PASSWORD"),
+		Name:     os.Getenv("PGATU_DB_NAME"),
+		SSLMode:  os.Getenv("PGATU_DB_SSLMODE"),
+	})
+	cfg.Database.merge(flagDB)
+
+	if env := os.Getenv("PGATU_API_URL"); env != "" {
+		cfg.API.URL = env
+	}
+	if *apiURL != "" {
+		cfg.API.URL = *apiURL
+	}
+
+	cfg.Agent.merge(Agent{
+		Interval:      os.Getenv("PGATU_AGENT_INTERVAL"),
+		FlushInterval: os.Getenv("PGATU_AGENT_FLUSH_INTERVAL"),
+		Sink:          os.Getenv("PGATU_AGENT_SINK"),
+		APIKey:        os.Getenv("PGATU_AGENT_API_KEY"),
+		StatePath:     os.Getenv("PGATU_AGENT_STATE"),
+	})
+	cfg.Agent.merge(flagAgent)
+	if cfg.Agent.Sink != AgentSinkAPI && cfg.Agent.Sink != AgentSinkDB {
+		return nil, fmt.Errorf("некорректный режим агента %q: ожидается %s или %s", cfg.Agent.Sink, AgentSinkAPI, AgentSinkDB)
+	}
+	if cfg.Agent.StatePath == "" {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			cfg.Agent.StatePath = filepath.Join(dir, AppDirName, "agent-state.json")
+		}
+	}
+	cfg.Telemetry.merge(Telemetry{
+		ChangeDelta:  os.Getenv("PGATU_TELEMETRY_DELTA"),
+		NetworkDelta: os.Getenv("PGATU_TELEMETRY_NETWORK_DELTA"),
+		Heartbeat:    os.Getenv("PGATU_TELEMETRY_HEARTBEAT"),
+	})
+	cfg.Telemetry.merge(flagTel)
+	cfg.Args = fs.Args()
+
+	return cfg, nil
+}
+
+// mergeFile накладывает на cfg содержимое JSON-файла. Отсутствие файла
+// по умолчанию не является ошибкой, явно указанного - является.
+func (cfg *Config) mergeFile(path string, explicit bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("ошибка чтения файла конфигурации: %v", err)
+	}
+
+	var fileCfg Config
+	if err := json.Unmarshal(data, &fileCfg); err != nil {
+		return fmt.Errorf("ошибка разбора %s: %v", path, err)
+	}
+
+	cfg.Database.merge(fileCfg.Database)
+	if fileCfg.API.URL != "" {
+		cfg.API.URL = fileCfg.API.URL
+	}
+	cfg.Agent.merge(fileCfg.Agent)
+	cfg.Telemetry.merge(fileCfg.Telemetry)
+	if fileCfg.Profile != "" {
+		cfg.Profile = fileCfg.Profile
+	}
+	if len(fileCfg.Profiles) > 0 {
+		cfg.Profiles = fileCfg.Profiles
+	}
+	return nil
+}
+
+// merge копирует в d только непустые поля other
+func (d *Database) merge(other Database) {
+	if other.Host != "" {
+		d.Host = other.Host
+	}
+	if other.Port != "" {
+		d.Port = other.Port
+	}
+	if other.User != "" {
+		d.User = other.User
+	}
+	if other.Password != "" {
+		d.Password = other.Password
+	}
+	if other.Name != "" {
+		d.Name = other.Name
+	}
+	if other.SSLMode != "" {
+		d.SSLMode = other.SSLMode
+	}
+}
+
+// merge копирует в a только непустые поля other
+func (a *Agent) merge(other Agent) {
+	if other.Interval != "" {
+		a.Interval = other.Interval
+	}
+	if other.FlushInterval != "" {
+		a.FlushInterval = other.FlushInterval
+	}
+	if other.Sink != "" {
+		a.Sink = other.Sink
+	}
+	if other.APIKey != "" {
+		a.APIKey = other.APIKey
+	}
+	if other.StatePath != "" {
+		a.StatePath = other.StatePath
+	}
+}
+
+// merge копирует в t только непустые поля other
+func (t *Telemetry) merge(other Telemetry) {
+	if other.ChangeDelta != "" {
+		t.ChangeDelta = other.ChangeDelta
+	}
+	if other.NetworkDelta != "" {
+		t.NetworkDelta = other.NetworkDelta
+	}
+	if other.Heartbeat != "" {
+		t.Heartbeat = other.Heartbeat
+	}
+}
+
+// Options преобразует настройки в параметры пакета database
+func (d Database) Options() database.ConnectionOptions {
+	return database.ConnectionOptions{
+		Host:     d.Host,
+		Port:     d.Port,
+		User:     d.User,
+		Password: d.Password,
+		DBName:   d.Name,
+		SSLMode:  d.SSLMode,
+	}
+}
+
+// ConnString возвращает строку подключения для sql.Open("postgres", ...)
+func (d Database) ConnString() string {
+	return d.Options().ConnString()
+}
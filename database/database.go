@@ -2,16 +2,35 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	_ "github.com/lib/pq" // Драйвер PostgreSQL
 )
 
-// PGConnection представляет соединение с базой данных
+// Параметры общего пула соединений
+const (
+	maxOpenConns    = 20
+	maxIdleConns    = 5
+	connMaxLifetime = 5 * time.Minute
+	pingTimeout     = 5 * time.Second
+)
+
+// PGConnection представляет соединение с базой данных. Один экземпляр
+// создается при запуске приложения и передается во все вкладки.
 type PGConnection struct {
-	db *sql.DB
+	mu        sync.RWMutex
+	db        *sql.DB
+	opts      ConnectionOptions
+	healthy   bool
+	lastErr   error
+	listeners []func(bool)
+	stopWatch chan struct{}
 }
 
 // ConnectionOptions параметры для подключения
@@ -24,36 +43,67 @@ type ConnectionOptions struct {
 	SSLMode  string
 }
 
+// ConnString возвращает строку подключения в формате lib/pq. Значения
+// берутся в кавычки, поэтому пароль может содержать пробелы, кавычки и
+// обратную косую черту; пустые параметры пропускаются, и для них действуют
+// значения по умолчанию.
+func (opts ConnectionOptions) ConnString() string {
+	params := []struct{ key, value string }{
+		{"host", opts.Host},
+		{"port", opts.Port},
+		{"user", opts.User},
+		{"password", opts.Password},
+		{"dbname", opts.DBName},
+		{"sslmode", opts.SSLMode},
+	}
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value != "" {
+			parts = append(parts, p.key+"="+quoteConnValue(p.value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteConnValue заключает значение в одинарные кавычки и экранирует
+// кавычки и обратную косую черту по правилам libpq
+func quoteConnValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
 // New создает новое подключение к PostgreSQL
 func New() *PGConnection {
 	return &PGConnection{}
 }
 
-// Connect устанавливает соединение с базой данных
+// Connect устанавливает соединение с базой данных. Если сервер недоступен,
+// пул остается открытым и возвращается ошибка проверки: database/sql сам
+// переподключится, когда сеть вернется, а Watch отследит это.
 func (pg *PGConnection) Connect(opts ConnectionOptions) error {
-	// opts.Host = "83.166.245.249"
-	// opts.Port = "5432"
-	// opts.User = "user"
-	// opts.Password = "user"
-	// opts.DBName = "grafana_db"
-	// opts.SSLMode = "default"
+	pg.mu.Lock()
 	if pg.db != nil {
+		pg.mu.Unlock()
 		return fmt.Errorf("соединение уже установлено")
 	}
 
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		opts.Host, opts.Port, opts.User, opts.Password, opts.DBName, opts.SSLMode)
-
-	var err error
-	pg.db, err = sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", opts.ConnString())
 	if err != nil {
+		pg.mu.Unlock()
 		return fmt.Errorf("не удалось подключиться: %v", err)
 	}
 
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+
+	pg.db = db
+	pg.opts = opts
+	pg.mu.Unlock()
+
 	// Проверяем соединение
-	if err = pg.db.Ping(); err != nil {
-		pg.db.Close()
-		pg.db = nil
+	if err := pg.ping(); err != nil {
 		return fmt.Errorf("проверка соединения не удалась: %v", err)
 	}
 
@@ -61,16 +111,62 @@ func (pg *PGConnection) Connect(opts ConnectionOptions) error {
 	return nil
 }
 
+// Watch запускает фоновую проверку соединения с заданным интервалом.
+// При потере связи сбрасывает простаивающие соединения, чтобы после
+// восстановления сети пул открыл новые.
+func (pg *PGConnection) Watch(interval time.Duration) {
+	pg.mu.Lock()
+	if pg.stopWatch != nil {
+		pg.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	pg.stopWatch = stop
+	pg.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := pg.ping(); err != nil {
+					pg.resetIdle()
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// OnStatusChange регистрирует callback, вызываемый при смене состояния
+// соединения (true - связь есть, false - потеряна)
+func (pg *PGConnection) OnStatusChange(callback func(connected bool)) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	pg.listeners = append(pg.listeners, callback)
+}
+
 // Disconnect закрывает соединение с базой данных
 func (pg *PGConnection) Disconnect() error {
-	if pg.db == nil {
+	pg.mu.Lock()
+	if pg.stopWatch != nil {
+		close(pg.stopWatch)
+		pg.stopWatch = nil
+	}
+	db := pg.db
+	pg.db = nil
+	pg.mu.Unlock()
+
+	if db == nil {
 		return fmt.Errorf("соединение не установлено")
 	}
 
-	err := pg.db.Close()
-	pg.db = nil
+	pg.setHealthy(false, nil)
 
-	if err != nil {
+	if err := db.Close(); err != nil {
 		return fmt.Errorf("ошибка при закрытии соединения: %v", err)
 	}
 
@@ -80,15 +176,74 @@ func (pg *PGConnection) Disconnect() error {
 
 // DB возвращает объект базы данных для выполнения запросов
 func (pg *PGConnection) DB() *sql.DB {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
 	return pg.db
 }
 
 // IsConnected проверяет активность соединения
 func (pg *PGConnection) IsConnected() bool {
-	if pg.db == nil {
-		return false
+	return pg.ping() == nil
+}
+
+// Healthy возвращает результат последней проверки без обращения к серверу
+func (pg *PGConnection) Healthy() bool {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+	return pg.healthy
+}
+
+// LastError возвращает ошибку последней неудачной проверки
+func (pg *PGConnection) LastError() error {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+	return pg.lastErr
+}
+
+// ping проверяет соединение и обновляет состояние
+func (pg *PGConnection) ping() error {
+	db := pg.DB()
+	if db == nil {
+		return fmt.Errorf("соединение не установлено")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	err := db.PingContext(ctx)
+	pg.setHealthy(err == nil, err)
+	return err
+}
+
+// resetIdle закрывает простаивающие соединения, оставшиеся от прежней сессии
+func (pg *PGConnection) resetIdle() {
+	db := pg.DB()
+	if db == nil {
+		return
+	}
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(maxIdleConns)
+}
+
+func (pg *PGConnection) setHealthy(healthy bool, err error) {
+	pg.mu.Lock()
+	changed := pg.healthy != healthy
+	pg.healthy = healthy
+	pg.lastErr = err
+	listeners := append([]func(bool){}, pg.listeners...)
+	pg.mu.Unlock()
+
+	if !changed {
+		return
 	}
 
-	err := pg.db.Ping()
-	return err == nil
+	if healthy {
+		log.Println("Соединение с PostgreSQL восстановлено")
+	} else if err != nil {
+		log.Printf("Соединение с PostgreSQL потеряно: %v", err)
+	}
+
+	for _, listener := range listeners {
+		listener(healthy)
+	}
 }
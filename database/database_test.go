@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestConnString(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ConnectionOptions
+		want string
+	}{
+		{
+			"plain",
+			ConnectionOptions{Host: "db.local", Port: "5432", User: "app", Password: "secret", DBName: "grafana_db", SSLMode: "disable"},
+			`host='db.local' port='5432' user='app' password='secret' dbname='grafana_db' sslmode='disable'`,
+		},
+		{
+			"special characters",
+			ConnectionOptions{Host: "db.local", User: "app", Password: `p a'ss\word sslmode=disable`, DBName: "db"},
+			`host='db.local' user='app' password='p a\'ss\\word sslmode=disable' dbname='db'`,
+		},
+		{
+			"empty values skipped",
+			ConnectionOptions{Host: "db.local"},
+			`host='db.local'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.opts.ConnString()
+			if got != tt.want {
+				t.Fatalf("ConnString:\n got %s\nwant %s", got, tt.want)
+			}
+			if _, err := pq.NewConnector(got); err != nil {
+				t.Errorf("lib/pq rejected %s: %v", got, err)
+			}
+		})
+	}
+}
@@ -1,49 +1,75 @@
 package main
 
 import (
-	"FYNEAPPS/resources"
+	"FYNEAPPS/apiclient"
+	"FYNEAPPS/config"
+	"FYNEAPPS/database"
+	"FYNEAPPS/spool"
+	"FYNEAPPS/telemetry"
 	"FYNEAPPS/ui"
-	setting "FYNEAPPS/ui/setting_tab"
+	"FYNEAPPS/ui/tabs"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/auth"
+	"FYNEAPPSSERVER/migrations"
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"github.com/getlantern/systray"
-	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var (
-	db          *sql.DB
+	pool        *database.PGConnection
+	queue       *spool.Spool
+	api         *apiclient.Client
 	myApp       fyne.App
-	mainWindow  fyne.Window
-	loginWindow fyne.Window
+	windows     *windowManager
 	currentUser *User
 )
 
+// Состояние трея: наблюдатель работает, пока открыто главное окно, а пункты
+// меню появляются, когда systray готов, поэтому последнее состояние
+// сохраняется и применяется в setupTray
+var (
+	trayMu    sync.Mutex
+	trayReady bool
+	trayState ui.TrayStatus
+
+	mStatus, mTickets, mServers, mLocal *systray.MenuItem
+)
+
 const (
-	dbHost      = "83.166.245.249"
-	dbPort      = 5432
-	dbUser      = "user"
-	dbPassword  = "user"
-	dbName      = "grafana_db"
 	sessionFile = "session.json" // Файл для хранения сессии
+	spoolFile   = "spool.jsonl"  // Очередь операций, отложенных без связи с БД
+
+	// spoolRetryInterval период повторного разбора очереди, если прошлый
+	// разбор прервался при формально живом соединении
+	spoolRetryInterval = time.Minute
 )
 
 type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	FullName  string    `json:"full_name"`
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	// SessionID токен REST API, выданный при входе
 	SessionID string    `json:"session_id"`
 	ExpiresAt time.Time `json:"expires_at"`
 	LoggedIn  bool      `json:"logged_in"`
+	Role      auth.Role `json:"role"`
+	// MustChangePassword выставляется после сброса пароля администратором
+	MustChangePassword bool `json:"must_change_password"`
 }
 
 type SessionData struct {
@@ -51,73 +77,199 @@ type SessionData struct {
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
+// initDB создает общий пул соединений, который затем передается во все вкладки
 func initDB() error {
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName)
-
-	var err error
-	db, err = sql.Open("postgres", connStr)
+	pool = database.New()
+	err := pool.Connect(config.Current().Database.Options())
+	for i := 1; i < 3 && err != nil; i++ {
+		time.Sleep(2 * time.Second)
+		if pool.IsConnected() {
+			err = nil
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("ошибка подключения к БД: %v", err)
 	}
+	return nil
+}
 
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+// openSpool открывает очередь отложенных операций в каталоге пользователя
+// и запускает ее разбор при каждом восстановлении связи с БД
+func openSpool() error {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("не удалось определить каталог настроек: %v", err)
+	}
+	queue, err = spool.Open(filepath.Join(dir, config.AppDirName, spoolFile))
+	if err != nil {
+		return err
+	}
+	tabs.SetupSpool(queue, pool)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	pool.OnStatusChange(func(connected bool) {
+		if connected {
+			go replaySpool()
+		}
+	})
+	go func() {
+		ticker := time.NewTicker(spoolRetryInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			replaySpool()
+		}
+	}()
+	go replaySpool()
+	return nil
+}
+
+// replaySpool выполняет отложенные операции, если есть связь с БД. Запись,
+// которую БД отвергла (см. spoolRejected), отбрасывается; при остальных
+// ошибках она остается в очереди до следующей попытки.
+func replaySpool() {
+	if queue == nil || queue.Len() == 0 || !pool.Healthy() {
+		return
+	}
+	n := queue.Len()
+	err := queue.Replay(context.Background(), spoolRejected)
+	if err != nil {
+		log.Printf("Разбор очереди прерван (осталось записей: %d): %v", queue.Len(), err)
+		return
+	}
+	log.Printf("Очередь разобрана, выполнено записей: %d", n)
+}
+
+// spoolRejected сообщает, что повтор записи очереди ничего не даст: данные
+// неверны (класс ошибок PostgreSQL 22), нарушают ограничение (класс 23),
+// не прошли проверку пакета ingest или сама запись повреждена. Таймауты и
+// обрыв связи временные.
+func spoolRejected(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		class := pqErr.Code.Class()
+		return class == "22" || class == "23"
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return telemetry.Rejected(err) || errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
 
-	return db.PingContext(ctx)
+// runMigrate выполняет команду миграции и возвращает код завершения
+func runMigrate(args []string) int {
+	if err := initDB(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer pool.Disconnect()
+
+	if err := migrations.Run(context.Background(), pool.DB(), args, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка миграции: %v\n", err)
+		return 1
+	}
+	return 0
 }
 
+// authenticate получает токен у REST API. Проверка пароля, обновление
+// устаревших хешей и запись в user_sessions выполняются на сервере.
 func authenticate(username, password string) (*User, error) {
-	query := `SELECT id, username, full_name FROM users WHERE username = $1 AND password = $2`
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
 
-	var user User
-	err := db.QueryRow(query, username, password).Scan(&user.ID, &user.Username, &user.FullName)
+	resp, err := api.Login(ctx, username, password)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if apiclient.IsUnauthorized(err) {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("ошибка аутентификации: %v", err)
 	}
 
-	user.SessionID = uuid.New().String()
-	user.ExpiresAt = time.Now().Add(24 * time.Hour)
-	user.LoggedIn = true
-
-	_, err = db.Exec(
-		"INSERT INTO user_sessions (user_id, session_id, expires_at) VALUES ($1, $2, $3)",
-		user.ID, user.SessionID, user.ExpiresAt,
-	)
+	user, err := userFromAPI(&resp.User)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка создания сессии: %v", err)
+		return nil, fmt.Errorf("ошибка аутентификации: %v", err)
 	}
+	user.SessionID = resp.Token
+	user.ExpiresAt = resp.ExpiresAt
 
 	// Сохраняем сессию в JSON
 	if err := saveSessionToFile(user.SessionID, user.ExpiresAt); err != nil {
 		return nil, fmt.Errorf("ошибка сохранения сессии: %v", err)
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+// userFromAPI преобразует пользователя из ответа API
+func userFromAPI(u *models.User) (*User, error) {
+	role, err := auth.ParseRole(u.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &User{
+		ID:                 u.ID,
+		Username:           u.Username,
+		FullName:           u.FullName,
+		Role:               role,
+		MustChangePassword: u.MustChangePassword,
+		LoggedIn:           true,
+	}, nil
 }
 
-func loadSessionFromDB(sessionID string) (*User, error) {
-	query := `
-		SELECT u.id, u.username, u.full_name, s.expires_at 
-		FROM users u JOIN user_sessions s ON u.id = s.user_id
-		WHERE s.session_id = $1 AND s.expires_at > NOW()`
+// changePassword меняет пароль через API; сервер проверяет текущий пароль
+func changePassword(user *User, current, next string) error {
+	if current == next {
+		return fmt.Errorf("Новый пароль должен отличаться от текущего")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := api.ChangePassword(ctx, current, next); err != nil {
+		if apiclient.StatusCode(err) == http.StatusBadRequest {
+			return fmt.Errorf("Пароль не изменен: неверный текущий пароль или пароль не соответствует требованиям")
+		}
+		return fmt.Errorf("ошибка смены пароля: %v", err)
+	}
+
+	user.MustChangePassword = false
+	return nil
+}
+
+// showChangePassword открывает диалог смены пароля в главном окне. При
+// обязательной смене отказ завершает сессию.
+func showChangePassword(forced bool) {
+	if windows.Main() == nil || currentUser == nil {
+		return
+	}
+
+	user := currentUser
+	var onCancel func()
+	if forced {
+		onCancel = func() { go logout() }
+	}
 
-	var user User
-	err := db.QueryRow(query, sessionID).Scan(&user.ID, &user.Username, &user.FullName, &user.ExpiresAt)
+	ui.ShowChangePasswordDialog(windows.Main(), forced, func(current, next string) error {
+		return changePassword(user, current, next)
+	}, onCancel)
+}
+
+// loadSessionFromAPI восстанавливает пользователя по сохраненному токену
+func loadSessionFromAPI(session *SessionData) (*User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	api.SetToken(session.SessionID)
+	me, err := api.Me(ctx)
 	if err != nil {
+		api.SetToken("")
 		return nil, err
 	}
 
-	user.SessionID = sessionID
-	user.LoggedIn = true
-	return &user, nil
+	user, err := userFromAPI(me)
+	if err != nil {
+		return nil, err
+	}
+	user.SessionID = session.SessionID
+	user.ExpiresAt = session.ExpiresAt
+	return user, nil
 }
 
 func saveSessionToFile(sessionID string, expiresAt time.Time) error {
@@ -173,148 +325,132 @@ func loadSavedSession() (*User, error) {
 		return nil, err
 	}
 
-	return loadSessionFromDB(session.SessionID)
-}
-
-func closeWindowSafely(w *fyne.Window) {
-	if w != nil && *w != nil {
-		(*w).Close()
-		*w = nil
-	}
+	return loadSessionFromAPI(session)
 }
 
+// logout завершает сессию и возвращает окно входа, после чего можно войти
+// под другим пользователем. Запрос к API выполняется в фоне, поэтому
+// вызывается вне потока интерфейса.
 func logout() {
 	log.Println("Начало выхода из системы")
 
 	if currentUser != nil && currentUser.SessionID != "" {
-		if _, err := db.Exec("DELETE FROM user_sessions WHERE session_id = $1", currentUser.SessionID); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		if err := api.Logout(ctx); err != nil {
 			log.Printf("Ошибка удаления сессии: %v", err)
 		}
+		cancel()
 	}
 
 	if err := clearSessionFile(); err != nil {
 		log.Printf("Ошибка очистки сессии: %v", err)
 	}
 
-	currentUser = nil
-
 	fyne.Do(func() {
-		closeWindowSafely(&mainWindow)
-		showLoginWindow()
+		currentUser = nil
+		windows.ShowLogin()
+		log.Println("Выход из системы завершен")
 	})
-
-	log.Println("Выход из системы завершен")
 }
 
-func showLoginWindow() {
-	if loginWindow != nil {
-		loginWindow.Show()
-		loginWindow.RequestFocus()
-		return
-	}
-
-	loginWindow = ui.CreateLoginWindow(myApp, func(username, password string) bool {
-		if username == "" || password == "" {
-			return false
+// startTrayWatcher запускает фоновую проверку для текущего пользователя до
+// отмены ctx, то есть до закрытия главного окна
+func startTrayWatcher(ctx context.Context) {
+	ui.StartWatcher(ctx, pool, api, currentUser.Username, currentUser.Role, func(status ui.TrayStatus) {
+		if ctx.Err() == nil {
+			setTrayStatus(status)
 		}
-
-		user, err := authenticate(username, password)
-		if err != nil {
-			return false
-		}
-
-		if user != nil {
-			currentUser = user
-			closeWindowSafely(&loginWindow)
-			showMainWindow()
-			return true
-		}
-
-		return false
-	})
-
-	loginWindow.SetCloseIntercept(func() {
-		loginWindow.Hide()
 	})
-
-	loginWindow.Show()
-	loginWindow.CenterOnScreen()
 }
 
-func showMainWindow() {
-	if mainWindow != nil {
-		mainWindow.Close()
+func setTrayStatus(status ui.TrayStatus) {
+	trayMu.Lock()
+	defer trayMu.Unlock()
+	trayState = status
+	if trayReady {
+		applyTrayStatus()
 	}
+}
 
-	mainWindow = myApp.NewWindow("ПГАТУ Инфраструктура")
-	mainWindow.CenterOnScreen()
-
-	if icon := resources.ResourcePgatulogosmallPng; icon != nil {
-		mainWindow.SetIcon(icon)
+// applyTrayStatus обновляет значок и пункты меню; вызывается под trayMu
+func applyTrayStatus() {
+	if iconData := ui.TrayIcon(trayState.HasProblems()); len(iconData) > 0 {
+		systray.SetIcon(iconData)
 	}
+	systray.SetTooltip("ПГАТУ Инфраструктура: " + trayState.Summary())
+	mStatus.SetTitle(trayState.Summary())
 
-	appSettings := setting.LoadSettings(myApp, mainWindow)
-	mainWindow.Resize(fyne.NewSize(float32(appSettings.Width), float32(appSettings.Height)))
-
-	mainWindow.SetCloseIntercept(func() {
-		fyne.Do(func() {
-			mainWindow.Hide()
-		})
-	})
+	setTrayItem(mTickets, trayState.NewTickets > 0, fmt.Sprintf("Назначено новых тикетов: %d", trayState.NewTickets))
+	setTrayItem(mServers, len(trayState.ServersDown) > 0, "Недоступны: "+strings.Join(trayState.ServersDown, ", "))
+	setTrayItem(mLocal, len(trayState.Local) > 0, strings.Join(trayState.Local, ", "))
+}
 
-	appTabs := ui.CreateAppTabs(myApp, mainWindow)
-	mainWindow.SetContent(appTabs)
+func setTrayItem(item *systray.MenuItem, visible bool, title string) {
+	if !visible {
+		item.Hide()
+		return
+	}
+	item.SetTitle(title)
+	item.Show()
+}
 
-	mainWindow.SetOnClosed(func() {
-		mainWindow = nil
+// openTab разворачивает главное окно на вкладке id
+func openTab(id string) {
+	fyne.Do(func() {
+		if windows.Main() == nil {
+			return
+		}
+		windows.Show()
+		ui.ShowTab(id)
 	})
-
-	mainWindow.Show()
 }
 
 func setupTray() {
-	if iconData := resources.ResourcePgatulogosmallicoIco.StaticContent; len(iconData) > 0 {
-		systray.SetIcon(iconData)
-	}
-	systray.SetTooltip("ПГАТУ Инфраструктура")
+	mStatus = systray.AddMenuItem("", "Состояние по данным фоновой проверки")
+	mStatus.Disable()
+	mTickets = systray.AddMenuItem("", "Открыть вкладку тикетов")
+	mServers = systray.AddMenuItem("", "Открыть статус серверов")
+	mLocal = systray.AddMenuItem("", "Открыть сведения о компьютере")
+	systray.AddSeparator()
+
+	trayMu.Lock()
+	trayReady = true
+	applyTrayStatus()
+	trayMu.Unlock()
 
 	mShow := systray.AddMenuItem("Развернуть", "Показать окно")
 	mHide := systray.AddMenuItem("Свернуть", "Скрыть окно")
 	systray.AddSeparator()
-	mQuit := systray.AddMenuItem("Выйти", "Завершить сеанс")
-	mLogout := systray.AddMenuItem("Закрыть сессию (КРАШИТ ПРИЛОЖЕНИЕ)", "Выйти из приложения")
+	mPassword := systray.AddMenuItem("Сменить пароль", "Сменить пароль текущего пользователя")
+	mLogout := systray.AddMenuItem("Сменить пользователя", "Завершить сеанс и войти под другой учетной записью")
+	mQuit := systray.AddMenuItem("Выйти", "Закрыть приложение")
 
 	go func() {
 		for {
 			select {
 			case <-mShow.ClickedCh:
-				fyne.Do(func() {
-					if mainWindow != nil {
-						mainWindow.Show()
-					} else if loginWindow != nil {
-						loginWindow.Show()
-					}
-				})
+				fyne.Do(windows.Show)
 			case <-mHide.ClickedCh:
+				fyne.Do(windows.Hide)
+			case <-mTickets.ClickedCh:
+				openTab(ui.TabTickets)
+			case <-mServers.ClickedCh:
+				openTab(ui.TabServers)
+			case <-mLocal.ClickedCh:
+				openTab(ui.TabHardware)
+			case <-mPassword.ClickedCh:
 				fyne.Do(func() {
-					if mainWindow != nil {
-						mainWindow.Hide()
-					}
-					if loginWindow != nil {
-						loginWindow.Hide()
+					if windows.Main() != nil {
+						windows.Show()
+						showChangePassword(false)
 					}
 				})
 			case <-mLogout.ClickedCh:
-				fyne.Do(func() {
-					logout()
-				})
+				// logout обращается к API, поэтому не занимает поток интерфейса
+				go logout()
 			case <-mQuit.ClickedCh:
-				fyne.Do(func() {
-					closeWindowSafely(&mainWindow)
-					closeWindowSafely(&loginWindow)
-					fyne.CurrentApp().Quit()
-					systray.Quit()
-				})
+				fyne.Do(windows.Quit)
+				systray.Quit()
 				return
 			}
 		}
@@ -323,30 +459,47 @@ func setupTray() {
 
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+	config.Set(cfg)
+
+	// FYNEAPPS migrate up|down [n]|status - управление схемой БД без запуска GUI
+	if len(cfg.Args) > 0 && cfg.Args[0] == "migrate" {
+		os.Exit(runMigrate(cfg.Args[1:]))
+	}
+
+	api = apiclient.New(cfg.API.URL)
 	myApp = app.NewWithID("ru.pgatu.infrastructure")
 
-	var dbErr error
-	for i := 0; i < 3; i++ {
-		if dbErr = initDB(); dbErr == nil {
-			break
-		}
-		time.Sleep(2 * time.Second)
+	// Без БД приложение запускается в автономном режиме: данные копятся в
+	// очереди на диске, а Watch отследит восстановление связи
+	if err := initDB(); err != nil {
+		log.Printf("Нет связи с БД, работа в автономном режиме: %v", err)
 	}
-	if dbErr != nil {
-		log.Fatalf("Не удалось подключиться к БД: %v", dbErr)
+	pool.Watch(15 * time.Second)
+	defer pool.Disconnect()
+
+	if err := openSpool(); err != nil {
+		log.Printf("Очередь отложенных операций недоступна: %v", err)
+	} else {
+		defer queue.Close()
+		defer tabs.SpoolPendingTelemetry()
 	}
-	defer db.Close()
 
+	windows = newWindowManager(myApp)
 	go systray.Run(setupTray, func() {
 		log.Println("Трей завершил работу")
 	})
 
 	if user, err := loadSavedSession(); err == nil {
 		currentUser = user
-		showMainWindow()
+		windows.ShowMain()
 	} else {
 		log.Printf("Не удалось загрузить сессию: %v", err)
-		showLoginWindow()
+		windows.ShowLogin()
 	}
 
 	myApp.Run()
@@ -0,0 +1,294 @@
+// Package spool - надежная локальная очередь операций, которые не удалось
+// выполнить без связи с сервером: замеров телеметрии, списков ПО, новых
+// тикетов. Очередь хранится в файле JSON Lines в каталоге настроек
+// пользователя, переживает перезапуск и воспроизводится строго по порядку,
+// когда связь вернется.
+//
+// Файл только дописывается: каждая запись - строка {"seq":..,"kind":..},
+// а выполненная запись отмечается строкой {"ack":seq}. При открытии
+// выполненные записи отбрасываются и файл переписывается заново.
+package spool
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Handler выполняет запись из очереди. data - JSON, переданный в Enqueue.
+type Handler func(ctx context.Context, data json.RawMessage) error
+
+// Item запись очереди
+type Item struct {
+	Seq  uint64          `json:"seq"`
+	Kind string          `json:"kind"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// line строка файла: либо запись, либо отметка о ее выполнении
+type line struct {
+	Item
+	Ack uint64 `json:"ack,omitempty"`
+}
+
+// Spool очередь с хранением в файле. Безопасна для одновременного
+// использования; Replay выполняется не более чем в одной горутине.
+type Spool struct {
+	path string
+
+	mu        sync.Mutex
+	file      *os.File
+	items     []Item
+	nextSeq   uint64
+	handlers  map[string]Handler
+	listeners []func(int)
+
+	replayMu sync.Mutex
+}
+
+// Open открывает очередь в файле path, создавая его при необходимости
+func Open(path string) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога очереди: %v", err)
+	}
+
+	s := &Spool{path: path, nextSeq: 1, handlers: make(map[string]Handler)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.rewrite(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close закрывает файл очереди
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Handle задает обработчик записей вида kind
+func (s *Spool) Handle(kind string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = h
+}
+
+// OnChange регистрирует callback, вызываемый с новой длиной очереди
+func (s *Spool) OnChange(callback func(n int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, callback)
+}
+
+// Len возвращает число невыполненных записей
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Enqueue сохраняет v (в JSON) в конец очереди. Запись попадает на диск
+// до возврата из функции.
+func (s *Spool) Enqueue(kind string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("ошибка кодирования записи очереди: %v", err)
+	}
+
+	s.mu.Lock()
+	item := Item{Seq: s.nextSeq, Kind: kind, Time: time.Now(), Data: data}
+	if err := s.append(line{Item: item}); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.nextSeq++
+	s.items = append(s.items, item)
+	n, listeners := len(s.items), s.listeners
+	s.mu.Unlock()
+
+	notify(listeners, n)
+	return nil
+}
+
+// Replay выполняет записи по порядку. Если обработчик вернул ошибку,
+// permanent решает ее судьбу: временная ошибка (нет связи) останавливает
+// воспроизведение, и запись будет повторена позже; постоянная (сервер
+// доступен, но отверг данные) записывается в лог, а запись отбрасывается,
+// чтобы не блокировать остальную очередь.
+func (s *Spool) Replay(ctx context.Context, permanent func(error) bool) error {
+	if !s.replayMu.TryLock() {
+		return nil
+	}
+	defer s.replayMu.Unlock()
+
+	for {
+		s.mu.Lock()
+		if len(s.items) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		item := s.items[0]
+		handler := s.handlers[item.Kind]
+		s.mu.Unlock()
+
+		if handler == nil {
+			log.Printf("Очередь: нет обработчика для записи %d (%s), запись отброшена", item.Seq, item.Kind)
+		} else if err := handler(ctx, item.Data); err != nil {
+			if ctx.Err() != nil || !permanent(err) {
+				return err
+			}
+			log.Printf("Очередь: запись %d (%s) отклонена и отброшена: %v", item.Seq, item.Kind, err)
+		}
+
+		if err := s.ack(item.Seq); err != nil {
+			return err
+		}
+	}
+}
+
+// ack отмечает первую запись очереди выполненной
+func (s *Spool) ack(seq uint64) error {
+	s.mu.Lock()
+	if len(s.items) == 0 || s.items[0].Seq != seq {
+		s.mu.Unlock()
+		return nil
+	}
+
+	var err error
+	if len(s.items) == 1 {
+		// Очередь опустела: файл можно просто обрезать
+		err = s.file.Truncate(0)
+	} else {
+		err = s.append(line{Ack: seq})
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("ошибка записи очереди: %v", err)
+	}
+
+	s.items = s.items[1:]
+	n, listeners := len(s.items), s.listeners
+	s.mu.Unlock()
+
+	notify(listeners, n)
+	return nil
+}
+
+// append дописывает строку в файл и сбрасывает ее на диск. Вызывается под mu.
+func (s *Spool) append(l line) error {
+	if s.file == nil {
+		return errors.New("очередь закрыта")
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("ошибка записи очереди: %v", err)
+	}
+	return s.file.Sync()
+}
+
+// load читает файл очереди. Оборванная последняя строка (сбой во время
+// записи) пропускается.
+func (s *Spool) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения очереди: %v", err)
+	}
+	defer f.Close()
+
+	acked := make(map[uint64]bool)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var l line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			log.Printf("Очередь: пропущена поврежденная строка в %s: %v", s.path, err)
+			continue
+		}
+		if l.Ack != 0 {
+			acked[l.Ack] = true
+			continue
+		}
+		if l.Seq >= s.nextSeq {
+			s.nextSeq = l.Seq + 1
+		}
+		s.items = append(s.items, l.Item)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения очереди: %v", err)
+	}
+
+	pending := s.items[:0]
+	for _, item := range s.items {
+		if !acked[item.Seq] {
+			pending = append(pending, item)
+		}
+	}
+	s.items = pending
+	return nil
+}
+
+// rewrite записывает невыполненные записи в новый файл и открывает его
+// на дозапись
+func (s *Spool) rewrite() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("ошибка записи очереди: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, item := range s.items {
+		data, err := json.Marshal(line{Item: item})
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(append(data, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("ошибка записи очереди: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("ошибка записи очереди: %v", err)
+	}
+	f.Close()
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("ошибка записи очереди: %v", err)
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия очереди: %v", err)
+	}
+	return nil
+}
+
+func notify(listeners []func(int), n int) {
+	for _, listener := range listeners {
+		listener(n)
+	}
+}
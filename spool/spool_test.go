@@ -0,0 +1,160 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// errRejected постоянная ошибка обработчика в тестах
+var errRejected = errors.New("rejected")
+
+func permanent(err error) bool { return errors.Is(err, errRejected) }
+
+func openTemp(t *testing.T) (*Spool, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spool.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+// collect регистрирует обработчик вида kind, который запоминает числа из
+// записей и возвращает ошибку errs[n] для n-го вызова
+func collect(s *Spool, kind string, got *[]int, errs map[int]error) {
+	calls := 0
+	s.Handle(kind, func(ctx context.Context, data json.RawMessage) error {
+		calls++
+		if err := errs[calls]; err != nil {
+			return err
+		}
+		var n int
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*got = append(*got, n)
+		return nil
+	})
+}
+
+func TestReplayInOrder(t *testing.T) {
+	s, _ := openTemp(t)
+	for i := 1; i <= 3; i++ {
+		if err := s.Enqueue("n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []int
+	collect(s, "n", &got, nil)
+	if err := s.Replay(context.Background(), permanent); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) || s.Len() != 0 {
+		t.Errorf("replayed %v, %d left; want [1 2 3] and 0", got, s.Len())
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		errs map[int]error
+		want []int
+		left int
+	}{
+		{"transient error stops", map[int]error{2: errors.New("timeout")}, []int{1}, 2},
+		{"permanent error drops", map[int]error{2: errRejected}, []int{1, 3}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := openTemp(t)
+			for i := 1; i <= 3; i++ {
+				s.Enqueue("n", i)
+			}
+			var got []int
+			collect(s, "n", &got, tt.errs)
+			err := s.Replay(context.Background(), permanent)
+			if (err != nil) != (tt.left > 0) {
+				t.Fatalf("Replay: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || s.Len() != tt.left {
+				t.Errorf("replayed %v, %d left; want %v and %d", got, s.Len(), tt.want, tt.left)
+			}
+		})
+	}
+}
+
+func TestReplayWithoutHandler(t *testing.T) {
+	s, _ := openTemp(t)
+	s.Enqueue("unknown", 1)
+	s.Enqueue("n", 2)
+	var got []int
+	collect(s, "n", &got, nil)
+	if err := s.Replay(context.Background(), permanent); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{2}) || s.Len() != 0 {
+		t.Errorf("replayed %v, %d left; want [2] and 0", got, s.Len())
+	}
+}
+
+func TestReopen(t *testing.T) {
+	s, path := openTemp(t)
+	for i := 1; i <= 3; i++ {
+		s.Enqueue("n", i)
+	}
+	var got []int
+	collect(s, "n", &got, map[int]error{2: errors.New("timeout")})
+	s.Replay(context.Background(), permanent)
+	s.Close()
+
+	// Сбой во время записи оставляет оборванную строку
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":9,"kind":"n","da`)
+	f.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 2 {
+		t.Fatalf("%d items after reopening, want 2", s.Len())
+	}
+
+	if err := s.Enqueue("n", 4); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	collect(s, "n", &got, nil)
+	if err := s.Replay(context.Background(), permanent); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("replayed %v after reopening, want [2 3 4]", got)
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	s, _ := openTemp(t)
+	var lengths []int
+	s.OnChange(func(n int) { lengths = append(lengths, n) })
+	s.Enqueue("n", 1)
+	s.Enqueue("n", 2)
+	var got []int
+	collect(s, "n", &got, nil)
+	s.Replay(context.Background(), permanent)
+	if !reflect.DeepEqual(lengths, []int{1, 2, 1, 0}) {
+		t.Errorf("OnChange received %v, want [1 2 1 0]", lengths)
+	}
+}
@@ -0,0 +1,131 @@
+package telemetry
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// APISink отправляет замеры в REST API сервера, обычно с ключом агента
+// (scope telemetry:write). ID компьютера, выданный сервером при первой
+// отправке, сохраняется в statePath. Компьютер узнается сервером по
+// MachineUUID, поэтому повторная регистрация после перезапуска или
+// переименования возвращает тот же ID.
+type APISink struct {
+	api       *apiclient.Client
+	statePath string
+
+	mu         sync.Mutex
+	computerID int
+	hostName   string
+}
+
+// agentState содержимое файла состояния агента
+type agentState struct {
+	ComputerID int `json:"computer_id"`
+}
+
+// NewAPISink создает получатель и читает сохраненное состояние
+func NewAPISink(api *apiclient.Client, statePath string) (*APISink, error) {
+	s := &APISink{api: api, statePath: statePath}
+
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения состояния агента: %v", err)
+	}
+	var state agentState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("ошибка разбора %s: %v", statePath, err)
+	}
+	s.computerID = state.ComputerID
+	return s, nil
+}
+
+// ComputerID возвращает ID компьютера на сервере или 0 до регистрации
+func (s *APISink) ComputerID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.computerID
+}
+
+// Send отправляет пачку одним запросом POST /ingest. Если компьютер
+// удален на сервере, сохраненный ID сбрасывается и при следующей отправке
+// компьютер регистрируется заново.
+func (s *APISink) Send(ctx context.Context, batch *Batch) error {
+	id, err := s.ensureComputer(ctx, batch.Computer())
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.api.Ingest(ctx, batch.Ingest(id)); err != nil {
+		if apiclient.StatusCode(err) == http.StatusNotFound {
+			s.forgetComputer(id)
+		}
+		return fmt.Errorf("ошибка отправки телеметрии: %v", err)
+	}
+	return nil
+}
+
+// forgetComputer сбрасывает ID, отвергнутый сервером
+func (s *APISink) forgetComputer(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.computerID != id {
+		return
+	}
+	s.computerID = 0
+	if err := os.Remove(s.statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Не удалось удалить состояние агента: %v", err)
+	}
+}
+
+// ensureComputer регистрирует компьютер на сервере при первой отправке
+func (s *APISink) ensureComputer(ctx context.Context, c models.Computer) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Регистрация идемпотентна по MachineUUID, поэтому агент повторяет ее
+	// после запуска и при смене имени хоста: сервер вернет тот же ID и
+	// запишет новое имя в историю
+	if s.computerID != 0 && s.hostName == c.HostName {
+		return s.computerID, nil
+	}
+
+	var created models.Computer
+	if err := s.api.Do(ctx, http.MethodPost, "/computers", c, &created); err != nil {
+		return 0, fmt.Errorf("ошибка регистрации компьютера: %v", err)
+	}
+	s.computerID = created.ComputerID
+	s.hostName = c.HostName
+
+	// Без файла состояния агент просто зарегистрируется заново после
+	// перезапуска, поэтому замер не отбрасывается
+	if err := s.saveState(); err != nil {
+		log.Printf("Не удалось сохранить состояние агента: %v", err)
+	}
+	return s.computerID, nil
+}
+
+func (s *APISink) saveState() error {
+	data, err := json.Marshal(agentState{ComputerID: s.computerID})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0o755); err != nil {
+		return fmt.Errorf("ошибка создания каталога состояния: %v", err)
+	}
+	if err := os.WriteFile(s.statePath, data, 0o644); err != nil {
+		return fmt.Errorf("ошибка сохранения состояния агента: %v", err)
+	}
+	return nil
+}
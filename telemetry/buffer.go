@@ -0,0 +1,206 @@
+package telemetry
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/ingest"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Batch пачка замеров одного компьютера. ID сохраняется между попытками
+// отправки, поэтому повтор после обрыва связи не создает дублей.
+type Batch struct {
+	ID        string
+	Snapshots []*Snapshot
+}
+
+// Ingest преобразует пачку в тело POST /ingest
+func (b *Batch) Ingest(computerID int) *models.IngestBatch {
+	in := &models.IngestBatch{BatchID: b.ID, ComputerID: computerID}
+	for _, s := range b.Snapshots {
+		if s.Processor != nil {
+			p := *s.Processor
+			p.ComputerID = computerID
+			in.Processors = append(in.Processors, p)
+		}
+		if s.Memory != nil {
+			m := *s.Memory
+			m.ComputerID = computerID
+			in.Memory = append(in.Memory, m)
+		}
+
+		for _, d := range s.Disks {
+			d.ComputerID = computerID
+			in.Disks = append(in.Disks, d)
+		}
+		for _, n := range s.Network {
+			n.ComputerID = computerID
+			in.Network = append(in.Network, n)
+		}
+		// Сравнивать с прошлым составом имеет смысл только последний
+		if s.Inventory != nil {
+			in.Inventory = s.Inventory
+		}
+	}
+	return in
+}
+
+// Computer возвращает сведения о компьютере из последнего замера пачки
+func (b *Batch) Computer() models.Computer {
+	return b.Snapshots[len(b.Snapshots)-1].Computer
+}
+
+// Rejected сообщает, что получатель отверг пачку и повтор ничего не даст:
+// пачка не прошла проверку при записи в БД или сервер ответил 400
+func Rejected(err error) bool {
+	var invalid *ingest.ValidationError
+	return errors.As(err, &invalid) || apiclient.StatusCode(err) == http.StatusBadRequest
+}
+
+// samples число строк, которые замер добавит в пачку (см. ingest.SampleCount)
+func (s *Snapshot) samples() int {
+	n := len(s.Disks) + len(s.Network)
+	if s.Processor != nil {
+		n++
+	}
+	if s.Memory != nil {
+		n++
+	}
+	if s.Inventory != nil {
+		n++
+	}
+	return n
+}
+
+// Buffer копит замеры между отправками. Flush запечатывает накопленное в
+// пачку и отправляет неотправленные пачки по порядку. Безопасен для
+// одновременного использования; отправки выполняются по одной.
+type Buffer struct {
+	max int
+
+	mu      sync.Mutex
+	current []*Snapshot
+	pending []*Batch
+
+	flushMu sync.Mutex
+}
+
+// NewBuffer создает буфер, хранящий не больше max замеров. При
+// переполнении отбрасываются самые старые.
+func NewBuffer(max int) *Buffer {
+	return &Buffer{max: max}
+}
+
+// Add добавляет замер в текущую пачку
+func (b *Buffer) Add(s *Snapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current = append(b.current, s)
+	b.trim()
+}
+
+// Len возвращает число замеров, ожидающих отправки
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.len()
+}
+
+// Flush отправляет все накопленные замеры. При ошибке неотправленные пачки
+// остаются в буфере и уйдут со следующей попыткой с теми же ID. Пачка,
+// которую получатель отверг (см. Rejected), записывается в лог и
+// отбрасывается, чтобы не задерживать следующие.
+func (b *Buffer) Flush(ctx context.Context, sink Sink) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	b.seal()
+	b.mu.Unlock()
+
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.mu.Unlock()
+			return nil
+		}
+		batch := b.pending[0]
+		b.mu.Unlock()
+
+		if err := sink.Send(ctx, batch); err != nil {
+			if ctx.Err() != nil || !Rejected(err) {
+				return err
+			}
+			log.Printf("Пачка телеметрии %s отклонена и отброшена, замеров: %d: %v", batch.ID, len(batch.Snapshots), err)
+		}
+
+		b.mu.Lock()
+		// Пачка могла быть вытеснена при переполнении во время отправки
+		if len(b.pending) > 0 && b.pending[0] == batch {
+			b.pending = b.pending[1:]
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Drain запечатывает накопленные замеры и забирает из буфера все
+// неотправленные пачки, например чтобы сохранить их в очередь на диске
+func (b *Buffer) Drain() []*Batch {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seal()
+	batches := b.pending
+	b.pending = nil
+	return batches
+}
+
+// seal переносит текущие замеры в пачки, каждая из которых не больше
+// ingest.MaxSamples строк. Вызывается под mu.
+func (b *Buffer) seal() {
+	var (
+		batch *Batch
+		n     int
+	)
+	for _, s := range b.current {
+		if batch == nil || n+s.samples() > ingest.MaxSamples {
+			batch = &Batch{ID: uuid.NewString()}
+			b.pending = append(b.pending, batch)
+			n = 0
+		}
+		batch.Snapshots = append(batch.Snapshots, s)
+		n += s.samples()
+	}
+	b.current = nil
+}
+
+func (b *Buffer) len() int {
+	n := len(b.current)
+	for _, batch := range b.pending {
+		n += len(batch.Snapshots)
+	}
+	return n
+}
+
+// trim отбрасывает самые старые пачки, а затем замеры, пока буфер не
+// уложится в max. Вызывается под mu.
+func (b *Buffer) trim() {
+	for b.max > 0 && b.len() > b.max {
+		if len(b.pending) > 0 {
+			log.Printf("Буфер телеметрии переполнен, отброшено замеров: %d", len(b.pending[0].Snapshots))
+			b.pending = b.pending[1:]
+			continue
+		}
+		b.current = b.current[1:]
+	}
+}
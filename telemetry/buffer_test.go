@@ -0,0 +1,166 @@
+package telemetry
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/ingest"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// sinkFunc адаптирует функцию к Sink
+type sinkFunc func(ctx context.Context, b *Batch) error
+
+func (f sinkFunc) Send(ctx context.Context, b *Batch) error { return f(ctx, b) }
+
+// snapshot замер с загрузкой ЦП usage и disks дисками
+func snapshot(usage float64, disks int) *Snapshot {
+	s := &Snapshot{Time: time.Now(), Processor: &models.Processor{UsagePercent: usage}}
+	for i := 0; i < disks; i++ {
+		s.Disks = append(s.Disks, models.Disk{DriveLetter: fmt.Sprintf("D%d", i)})
+	}
+	return s
+}
+
+func TestRejected(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&ingest.ValidationError{}, true},
+		{fmt.Errorf("запись: %w", &ingest.ValidationError{}), true},
+		{&apiclient.Error{StatusCode: http.StatusBadRequest}, true},
+		{&apiclient.Error{StatusCode: http.StatusInternalServerError}, false},
+		{&apiclient.Error{StatusCode: http.StatusUnauthorized}, false},
+		{errors.New("connection refused"), false},
+		{context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		if got := Rejected(tt.err); got != tt.want {
+			t.Errorf("Rejected(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFlushDropsRejectedBatch(t *testing.T) {
+	buffer := NewBuffer(100)
+	buffer.Add(snapshot(10, 0))
+	var sent []*Batch
+	err := buffer.Flush(context.Background(), sinkFunc(func(ctx context.Context, b *Batch) error {
+		return errors.New("connection refused")
+	}))
+	if err == nil || buffer.Len() != 1 {
+		t.Fatalf("Flush with no connection: err %v, %d samples left, want the error and 1", err, buffer.Len())
+	}
+
+	buffer.Add(snapshot(20, 0))
+	err = buffer.Flush(context.Background(), sinkFunc(func(ctx context.Context, b *Batch) error {
+		sent = append(sent, b)
+		if len(sent) == 1 {
+			return &apiclient.Error{StatusCode: http.StatusBadRequest, Message: "batch has no samples"}
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(sent) != 2 || buffer.Len() != 0 {
+		t.Fatalf("sent %d batches, %d samples left; want the rejected batch skipped and the next one sent", len(sent), buffer.Len())
+	}
+	if got := sent[1].Snapshots[0].Processor.UsagePercent; got != 20 {
+		t.Errorf("second batch carries usage %v, want 20", got)
+	}
+}
+
+func TestFlushKeepsBatchOnTransientError(t *testing.T) {
+	buffer := NewBuffer(100)
+	buffer.Add(snapshot(10, 0))
+	var ids []string
+	send := sinkFunc(func(ctx context.Context, b *Batch) error {
+		ids = append(ids, b.ID)
+		if len(ids) == 1 {
+			return &apiclient.Error{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	if err := buffer.Flush(context.Background(), send); err == nil {
+		t.Fatal("Flush ignored a 503")
+	}
+	if err := buffer.Flush(context.Background(), send); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != ids[1] {
+		t.Errorf("batch IDs %v, want the same batch retried", ids)
+	}
+}
+
+func TestFlushSplitsLargeBatches(t *testing.T) {
+	// Каждый замер дает 1000 строк: ЦП и 999 дисков
+	const perSnapshot = 1000
+	count := 2*ingest.MaxSamples/perSnapshot + 3
+	buffer := NewBuffer(0)
+	for i := 0; i < count; i++ {
+		buffer.Add(snapshot(float64(i), perSnapshot-1))
+	}
+
+	var batches []*models.IngestBatch
+	err := buffer.Flush(context.Background(), sinkFunc(func(ctx context.Context, b *Batch) error {
+		in := b.Ingest(1)
+		if err := ingest.Validate(in); err != nil {
+			return err
+		}
+		batches = append(batches, in)
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("%d batches, want 3", len(batches))
+	}
+	var processors int
+	for _, in := range batches {
+		processors += len(in.Processors)
+		if n := ingest.SampleCount(in); n > ingest.MaxSamples {
+			t.Errorf("batch %s has %d samples", in.BatchID, n)
+		}
+	}
+	if processors != count {
+		t.Errorf("%d snapshots sent, want %d", processors, count)
+	}
+	if first := batches[0].Processors[0].UsagePercent; first != 0 {
+		t.Errorf("first snapshot sent is %v, want 0", first)
+	}
+}
+
+func TestDrainSplitsLargeBatches(t *testing.T) {
+	buffer := NewBuffer(0)
+	for i := 0; i < ingest.MaxSamples/500+1; i++ {
+		buffer.Add(snapshot(0, 499))
+	}
+	batches := buffer.Drain()
+	if len(batches) != 2 || buffer.Len() != 0 {
+		t.Fatalf("Drain returned %d batches, %d samples left; want 2 and 0", len(batches), buffer.Len())
+	}
+	if n := len(batches[1].Snapshots); n != 1 {
+		t.Errorf("second batch has %d snapshots, want 1", n)
+	}
+}
+
+func TestBufferTrim(t *testing.T) {
+	buffer := NewBuffer(3)
+	for i := 0; i < 5; i++ {
+		buffer.Add(snapshot(float64(i), 0))
+	}
+	batches := buffer.Drain()
+	if len(batches) != 1 || len(batches[0].Snapshots) != 3 {
+		t.Fatalf("Drain returned %d batches, want one with 3 snapshots", len(batches))
+	}
+	if first := batches[0].Snapshots[0].Processor.UsagePercent; first != 2 {
+		t.Errorf("oldest kept snapshot %v, want 2", first)
+	}
+}
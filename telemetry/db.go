@@ -0,0 +1,110 @@
+package telemetry
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/ingest"
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// DBSink пишет замеры напрямую в PostgreSQL. Запись о компьютере ищется
+// по MachineUUID или создается при первой отправке, дальше используется ее
+// ID. После переименования компьютера запись обновляется.
+type DBSink struct {
+	db *sql.DB
+
+	mu         sync.Mutex
+	computerID int
+	hostName   string
+}
+
+// NewDBSink создает получатель, пишущий в db
+func NewDBSink(db *sql.DB) *DBSink {
+	return &DBSink{db: db}
+}
+
+// ComputerID возвращает ID компьютера или 0, если замеры еще не отправлялись
+func (s *DBSink) ComputerID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.computerID
+}
+
+// Send сохраняет пачку одной транзакцией через пакет ingest, как и
+// POST /ingest, поэтому повторная отправка той же пачки ничего не пишет
+func (s *DBSink) Send(ctx context.Context, batch *Batch) error {
+	id, err := s.ensureComputer(ctx, batch.Computer())
+	if err != nil {
+		return err
+	}
+
+	if _, err := ingest.Write(ctx, s.db, batch.Ingest(id)); err != nil {
+		return fmt.Errorf("ошибка сохранения телеметрии: %w", err)
+	}
+	return nil
+}
+
+// ensureComputer находит или создает запись о компьютере и обновляет ее,
+// если изменилось имя хоста
+func (s *DBSink) ensureComputer(ctx context.Context, c models.Computer) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.computerID != 0 && s.hostName == c.HostName {
+		return s.computerID, nil
+	}
+
+	id, _, err := ingest.RegisterComputer(ctx, s.db, c)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сохранения информации о компьютере: %v", err)
+	}
+
+	s.computerID = id
+	s.hostName = c.HostName
+	return id, nil
+}
+
+// RegisterCurrentComputer сохраняет сведения о текущем компьютере и
+// возвращает его ID
+func RegisterCurrentComputer(ctx context.Context, db *sql.DB) (int, error) {
+	c, err := CurrentComputer()
+	if err != nil {
+		return 0, err
+	}
+	id, _, err := ingest.RegisterComputer(ctx, db, c)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка сохранения информации о компьютере: %v", err)
+	}
+	return id, nil
+}
+
+// FindComputerID ищет запись компьютера в таблице computers по MachineUUID,
+// а для записей без него - по имени хоста, пользователю и ОС. Возвращает
+// sql.ErrNoRows, если компьютер еще не сохранялся.
+func FindComputerID(ctx context.Context, db *sql.DB, c models.Computer) (int, error) {
+	var id int
+	if c.MachineUUID != "" {
+		err := db.QueryRowContext(ctx,
+			"SELECT computer_id FROM computers WHERE machine_uuid = $1", c.MachineUUID).Scan(&id)
+		if err != sql.ErrNoRows {
+			return id, err
+		}
+	}
+
+	err := db.QueryRowContext(ctx, `
+        SELECT computer_id
+        FROM computers
+        WHERE host_name = $1
+        AND user_name = $2
+        AND os_name = $3
+        AND os_version = $4
+        AND machine_uuid IS NULL
+        LIMIT 1`,
+		c.HostName,
+		c.UserName,
+		c.OsName,
+		c.OsVersion,
+	).Scan(&id)
+	return id, err
+}
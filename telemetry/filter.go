@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"FYNEAPPS/config"
+	"math"
+	"time"
+)
+
+// ChangeFilter убирает из замеров строки, которые почти не отличаются от
+// последних записанных: загрузка большую часть времени стоит на месте, и
+// хранить ее каждые 15 секунд незачем. Строка остается, если показатель
+// изменился хотя бы на порог или с его прошлой записи прошел Heartbeat,
+// поэтому на графиках нет разрывов дольше Heartbeat. Нулевой порог
+// отключает фильтр. Не безопасен для одновременного использования: у
+// каждого Collector свой фильтр.
+type ChangeFilter struct {
+	// Delta порог для загрузки ЦП, памяти и дисков, процентные пункты
+	Delta float64
+	// NetworkDelta порог для скоростей сети, МБ/с
+	NetworkDelta float64
+	// Heartbeat период, после которого строка пишется без изменений
+	Heartbeat time.Duration
+
+	last map[string]written
+}
+
+// written последние записанные значения показателя
+type written struct {
+	values []float64
+	at     time.Time
+}
+
+// NewChangeFilter создает фильтр с заданными порогами
+func NewChangeFilter(delta, networkDelta float64, heartbeat time.Duration) *ChangeFilter {
+	return &ChangeFilter{
+		Delta:        delta,
+		NetworkDelta: networkDelta,
+		Heartbeat:    heartbeat,
+		last:         make(map[string]written),
+	}
+}
+
+// FilterFromConfig создает фильтр по разделу telemetry настроек
+func FilterFromConfig(cfg config.Telemetry) (*ChangeFilter, error) {
+	delta, networkDelta, heartbeat, err := cfg.Thresholds()
+	if err != nil {
+		return nil, err
+	}
+	return NewChangeFilter(delta, networkDelta, heartbeat), nil
+}
+
+// Apply убирает из s неизменившиеся строки и возвращает false, если писать
+// больше нечего. Оставшиеся строки считаются записанными.
+func (f *ChangeFilter) Apply(s *Snapshot) bool {
+	if f.Delta <= 0 && f.NetworkDelta <= 0 {
+		return true
+	}
+
+	if s.Processor != nil && !f.changed("cpu", s.Time, f.Delta, s.Processor.UsagePercent) {
+		s.Processor = nil
+	}
+	if s.Memory != nil && !f.changed("memory", s.Time, f.Delta, s.Memory.UsagePercent) {
+		s.Memory = nil
+	}
+
+	disks := s.Disks[:0]
+	for _, d := range s.Disks {
+		if f.changed("disk:"+d.DriveLetter, s.Time, f.Delta, d.UsagePercent) {
+			disks = append(disks, d)
+		}
+	}
+	s.Disks = disks
+
+	network := s.Network[:0]
+	for _, n := range s.Network {
+		if f.changed("network:"+n.AdapterName, s.Time, f.NetworkDelta, n.UploadSpeed, n.DownloadSpeed) {
+			network = append(network, n)
+		}
+	}
+	s.Network = network
+
+	return !s.empty()
+}
+
+// changed сравнивает значения показателя key с последними записанными и
+// при изменении запоминает их
+func (f *ChangeFilter) changed(key string, now time.Time, delta float64, values ...float64) bool {
+	if f.last == nil {
+		f.last = make(map[string]written)
+	}
+	prev, ok := f.last[key]
+	if ok && delta > 0 && now.Sub(prev.at) < f.Heartbeat && len(prev.values) == len(values) {
+		same := true
+		for i, v := range values {
+			if math.Abs(v-prev.values[i]) >= delta {
+				same = false
+				break
+			}
+		}
+		if same {
+			return false
+		}
+	}
+	f.last[key] = written{values: values, at: now}
+	return true
+}
+
+func (s *Snapshot) empty() bool {
+	return s.Processor == nil && s.Memory == nil && len(s.Disks) == 0 &&
+		len(s.Network) == 0 && s.Inventory == nil
+}
@@ -0,0 +1,96 @@
+package telemetry
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"testing"
+	"time"
+)
+
+func TestChangeFilter(t *testing.T) {
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+
+	tests := []struct {
+		name  string
+		usage []float64
+		times []int
+		want  []bool
+	}{
+		{"first sample written", []float64{10}, []int{0}, []bool{true}},
+		{"small change skipped", []float64{10, 11.9, 8.1}, []int{0, 15, 30}, []bool{true, false, false}},
+		{"change of delta written", []float64{10, 12, 12.5}, []int{0, 15, 30}, []bool{true, true, false}},
+		{"compared with the last written", []float64{10, 11.5, 13}, []int{0, 15, 30}, []bool{true, false, true}},
+		{"heartbeat", []float64{10, 10, 10, 10}, []int{0, 60, 299, 300}, []bool{true, false, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewChangeFilter(2, 0.5, 5*time.Minute)
+			for i, usage := range tt.usage {
+				s := &Snapshot{Time: at(tt.times[i]), Processor: &models.Processor{UsagePercent: usage}}
+				if got := f.Apply(s); got != tt.want[i] {
+					t.Errorf("sample %d (%v at %ds): Apply = %v, want %v", i, usage, tt.times[i], got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChangeFilterSeries(t *testing.T) {
+	f := NewChangeFilter(2, 0.5, 5*time.Minute)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	snapshot := func(sec int, cpu, diskC, diskD, upload float64) *Snapshot {
+		return &Snapshot{
+			Time:      now.Add(time.Duration(sec) * time.Second),
+			Processor: &models.Processor{UsagePercent: cpu},
+			Memory:    &models.Memory{UsagePercent: 50},
+			Disks: []models.Disk{
+				{DriveLetter: "C:", UsagePercent: diskC},
+				{DriveLetter: "D:", UsagePercent: diskD},
+			},
+			Network: []models.NetworkAdapter{{AdapterName: "eth0", UploadSpeed: upload}},
+		}
+	}
+
+	s := snapshot(0, 10, 40, 70, 1)
+	if !f.Apply(s) || s.Processor == nil || s.Memory == nil || len(s.Disks) != 2 || len(s.Network) != 1 {
+		t.Fatalf("first snapshot filtered: %+v", s)
+	}
+
+	s = snapshot(15, 11, 40, 75, 1.2)
+	if !f.Apply(s) {
+		t.Fatal("Apply dropped a snapshot with a changed disk")
+	}
+	if s.Processor != nil || s.Memory != nil || len(s.Network) != 0 {
+		t.Errorf("unchanged rows kept: processor %v, memory %v, network %v", s.Processor, s.Memory, s.Network)
+	}
+	if len(s.Disks) != 1 || s.Disks[0].DriveLetter != "D:" {
+		t.Errorf("disks %+v, want only D:", s.Disks)
+	}
+
+	s = snapshot(30, 11, 40, 75, 1.6)
+	if !f.Apply(s) || len(s.Network) != 1 || len(s.Disks) != 0 {
+		t.Errorf("network change by the network delta: disks %+v, network %+v", s.Disks, s.Network)
+	}
+
+	s = snapshot(45, 11, 40, 75, 1.6)
+	if f.Apply(s) {
+		t.Errorf("Apply kept an unchanged snapshot: %+v", s)
+	}
+
+	s = snapshot(60, 11, 40, 75, 1.6)
+	s.Inventory = &models.HardwareInventory{}
+	if !f.Apply(s) {
+		t.Error("Apply dropped a snapshot with an inventory")
+	}
+}
+
+func TestChangeFilterDisabled(t *testing.T) {
+	f := NewChangeFilter(0, 0, 5*time.Minute)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		s := &Snapshot{Time: now, Processor: &models.Processor{UsagePercent: 10}}
+		if !f.Apply(s) || s.Processor == nil {
+			t.Fatalf("disabled filter dropped sample %d", i)
+		}
+	}
+}
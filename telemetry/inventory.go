@@ -0,0 +1,255 @@
+package telemetry
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// InventoryInterval период сбора состава оборудования. Состав меняется
+// редко, а его сбор запускает внешние утилиты, поэтому он не входит в
+// каждый замер.
+const InventoryInterval = time.Hour
+
+// virtualAdapterPrefixes имена виртуальных адаптеров, которые появляются и
+// исчезают сами по себе и не относятся к оборудованию
+var virtualAdapterPrefixes = []string{
+	"lo", "veth", "docker", "ifb", "dummy", "br-", "virbr", "vmnet", "vboxnet", "tun", "tap", "wg", "vEthernet",
+}
+
+// CollectInventory собирает состав оборудования: процессоры, модули памяти,
+// физические диски и сетевые адаптеры. Модули памяти и серийные номера
+// дисков доступны не везде (dmidecode требует root), тогда соответствующий
+// список остается пустым и сервер его не сравнивает.
+func CollectInventory() (*models.HardwareInventory, error) {
+	inv := &models.HardwareInventory{CollectedAt: time.Now()}
+
+	cpuInfo, err := cpu.Info()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения данных о процессоре: %v", err)
+	}
+	// На Linux gopsutil возвращает запись на каждое логическое ядро
+	seen := make(map[string]bool)
+	for _, c := range cpuInfo {
+		key := c.PhysicalID + "|" + c.ModelName
+		if !seen[key] {
+			seen[key] = true
+			inv.CPUs = append(inv.CPUs, strings.TrimSpace(c.ModelName))
+		}
+	}
+
+	memInfo, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения данных о памяти: %v", err)
+	}
+	inv.MemoryTotalGB = float64(memInfo.Total) / bytesInGB
+	inv.MemoryModules = memoryModules()
+
+	inv.Disks = diskDevices()
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка сетевых адаптеров: %v", err)
+	}
+	for _, iface := range interfaces {
+		if iface.HardwareAddr == "" || isVirtualAdapter(iface) {
+			continue
+		}
+		inv.NetworkAdapters = append(inv.NetworkAdapters, models.NetworkInterface{
+			Name:       iface.Name,
+			MacAddress: strings.ToLower(iface.HardwareAddr),
+		})
+	}
+
+	return inv, nil
+}
+
+func isVirtualAdapter(iface net.InterfaceStat) bool {
+	for _, flag := range iface.Flags {
+		if flag == "loopback" {
+			return true
+		}
+	}
+	for _, prefix := range virtualAdapterPrefixes {
+		if strings.HasPrefix(iface.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryModules возвращает установленные модули памяти или nil, если их
+// не удалось получить
+func memoryModules() []models.MemoryModule {
+	switch runtime.GOOS {
+	case "linux":
+		out, err := exec.Command("dmidecode", "-t", "17").Output()
+		if err != nil {
+			return nil
+		}
+		return ParseMemoryModules(string(out))
+	case "windows":
+		rows, err := wmicRows("memorychip", "BankLabel,Capacity,DeviceLocator,Manufacturer,SerialNumber,Speed")
+		if err != nil {
+			return nil
+		}
+		var modules []models.MemoryModule
+		for _, row := range rows {
+			module := models.MemoryModule{
+				Slot:         strings.TrimSpace(row["BankLabel"] + " " + row["DeviceLocator"]),
+				Manufacturer: row["Manufacturer"],
+				SerialNumber: row["SerialNumber"],
+			}
+			if capacity, err := strconv.ParseFloat(row["Capacity"], 64); err == nil {
+				module.Size = fmt.Sprintf("%.0f GB", capacity/bytesInGB)
+			}
+			if row["Speed"] != "" {
+				module.Speed = row["Speed"] + " MT/s"
+			}
+			modules = append(modules, module)
+		}
+		return modules
+	}
+	return nil
+}
+
+// ParseMemoryModules разбирает вывод `dmidecode -t 17`. Пустые слоты
+// пропускаются.
+func ParseMemoryModules(dmidecodeOutput string) []models.MemoryModule {
+	var modules []models.MemoryModule
+	parts := strings.Split(dmidecodeOutput, "Memory Device")
+
+	for _, part := range parts[1:] {
+		fields := make(map[string]string)
+		for _, line := range strings.Split(part, "\n") {
+			key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+			if ok {
+				fields[key] = strings.TrimSpace(value)
+			}
+		}
+
+		size := fields["Size"]
+		if size == "" || size == "No Module Installed" {
+			continue
+		}
+		modules = append(modules, models.MemoryModule{
+			Slot:         fields["Locator"],
+			Manufacturer: fields["Manufacturer"],
+			Size:         size,
+			Speed:        fields["Speed"],
+			SerialNumber: fields["Serial Number"],
+		})
+	}
+	return modules
+}
+
+// diskDevices возвращает физические диски или nil, если их не удалось получить
+func diskDevices() []models.DiskDevice {
+	switch runtime.GOOS {
+	case "linux":
+		out, err := exec.Command("lsblk", "-d", "-J", "-b", "-o", "NAME,MODEL,SERIAL,SIZE,TYPE").Output()
+		if err != nil {
+			return nil
+		}
+		var parsed struct {
+			Blockdevices []struct {
+				Name   string          `json:"name"`
+				Model  *string         `json:"model"`
+				Serial *string         `json:"serial"`
+				Size   json.RawMessage `json:"size"`
+				Type   string          `json:"type"`
+			} `json:"blockdevices"`
+		}
+		if err := json.Unmarshal(out, &parsed); err != nil {
+			return nil
+		}
+		var disks []models.DiskDevice
+		for _, d := range parsed.Blockdevices {
+			if d.Type != "disk" || strings.HasPrefix(d.Name, "zram") {
+				continue
+			}
+			// Старые версии lsblk отдают размер строкой
+			size, _ := strconv.ParseFloat(strings.Trim(string(d.Size), `"`), 64)
+			disks = append(disks, models.DiskDevice{
+				Name:         d.Name,
+				Model:        strings.TrimSpace(deref(d.Model)),
+				SerialNumber: strings.TrimSpace(deref(d.Serial)),
+				SizeGB:       size / bytesInGB,
+			})
+		}
+		return disks
+	case "windows":
+		rows, err := wmicRows("diskdrive", "DeviceID,Model,SerialNumber,Size")
+		if err != nil {
+			return nil
+		}
+		var disks []models.DiskDevice
+		for _, row := range rows {
+			size, _ := strconv.ParseFloat(row["Size"], 64)
+			disks = append(disks, models.DiskDevice{
+				Name:         row["DeviceID"],
+				Model:        row["Model"],
+				SerialNumber: row["SerialNumber"],
+				SizeGB:       size / bytesInGB,
+			})
+		}
+		return disks
+	}
+	return nil
+}
+
+// wmicRows выполняет `wmic <alias> get <fields> /format:csv` и возвращает
+// строки как словари по именам колонок
+func wmicRows(alias, fields string) ([]map[string]string, error) {
+	out, err := exec.Command("wmic", alias, "get", fields, "/format:csv").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) < 2 {
+		return nil, nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	header := records[0]
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
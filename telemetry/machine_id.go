@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"FYNEAPPS/config"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// machineNamespace пространство имен UUID v5 для идентификаторов
+// компьютеров. Сам machine-id наружу не передается: systemd просит не
+// раскрывать его, поэтому отправляется только производный UUID.
+var machineNamespace = uuid.MustParse("5b0c1e4e-8d0f-4c43-9f59-6a0d4f2f7a21")
+
+// machineIDFile имя файла с идентификатором в каталоге настроек
+const machineIDFile = "machine-id"
+
+var (
+	machineUUID     string
+	machineUUIDOnce sync.Once
+)
+
+// MachineUUID возвращает постоянный идентификатор компьютера. Он выводится
+// из SMBIOS UUID (Windows) или /etc/machine-id (Linux), поэтому совпадает у
+// приложения и агента, запущенных разными пользователями, и не меняется при
+// переименовании компьютера. Если источника нет, UUID генерируется при
+// первом запуске. Значение сохраняется в каталоге настроек.
+func MachineUUID() string {
+	machineUUIDOnce.Do(func() {
+		machineUUID = loadMachineUUID()
+	})
+	return machineUUID
+}
+
+func loadMachineUUID() string {
+	path := ""
+	if dir, err := os.UserConfigDir(); err == nil {
+		path = filepath.Join(dir, config.AppDirName, machineIDFile)
+		if data, err := os.ReadFile(path); err == nil {
+			if id, err := uuid.Parse(strings.TrimSpace(string(data))); err == nil {
+				return id.String()
+			}
+		}
+	}
+
+	var id string
+	if seed := machineSeed(); seed != "" {
+		id = uuid.NewSHA1(machineNamespace, []byte(seed)).String()
+	} else {
+		id = uuid.NewString()
+		log.Printf("Не найден идентификатор оборудования, создан новый ID компьютера: %s", id)
+	}
+
+	if path != "" {
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(id+"\n"), 0o644)
+		}
+		if err != nil {
+			log.Printf("Не удалось сохранить ID компьютера: %v", err)
+		}
+	}
+	return id
+}
+
+// machineSeed возвращает идентификатор, выданный системой или прошивкой
+func machineSeed() string {
+	switch runtime.GOOS {
+	case "windows":
+		// SMBIOS UUID переживает переустановку системы, MachineGuid - нет
+		if out, err := exec.Command("wmic", "csproduct", "get", "UUID").Output(); err == nil {
+			for _, field := range strings.Fields(string(out)) {
+				if validSMBIOSUUID(field) {
+					return "smbios:" + strings.ToLower(field)
+				}
+			}
+		}
+		out, err := exec.Command("reg", "query", `HKLM\SOFTWARE\Microsoft\Cryptography`, "/v", "MachineGuid").Output()
+		if err == nil {
+			fields := strings.Fields(string(out))
+			if len(fields) > 0 {
+				if _, err := uuid.Parse(fields[len(fields)-1]); err == nil {
+					return "machine-guid:" + strings.ToLower(fields[len(fields)-1])
+				}
+			}
+		}
+	case "linux":
+		// product_uuid читает только root, а machine-id доступен всем, поэтому
+		// он первый: иначе приложение и агент получили бы разные ID
+		for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+			if data, err := os.ReadFile(path); err == nil {
+				if id := strings.TrimSpace(string(data)); machineIDPattern.MatchString(id) {
+					return "machine-id:" + id
+				}
+			}
+		}
+		if data, err := os.ReadFile("/sys/class/dmi/id/product_uuid"); err == nil {
+			if id := strings.TrimSpace(string(data)); validSMBIOSUUID(id) {
+				return "smbios:" + strings.ToLower(id)
+			}
+		}
+	}
+	return ""
+}
+
+var machineIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// validSMBIOSUUID отсеивает заглушки, которые прошивки пишут вместо UUID
+func validSMBIOSUUID(s string) bool {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(id.String()) {
+	case "00000000-0000-0000-0000-000000000000",
+		"ffffffff-ffff-ffff-ffff-ffffffffffff",
+		"03000200-0400-0500-0006-000700080009":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,238 @@
+// Package telemetry собирает показатели компьютера и отправляет их в БД или
+// в REST API сервера. Пакет не зависит от Fyne и используется как вкладкой
+// "Мониторинг системы", так и фоновым агентом cmd/agent.
+package telemetry
+
+import (
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// bytesInGB и bytesInMB переводят счетчики gopsutil в единицы таблиц
+const (
+	bytesInGB = 1024 * 1024 * 1024
+	bytesInMB = 1024 * 1024
+)
+
+// Snapshot один замер: сведения о компьютере и строки для таблиц
+// processors, memory, disks и network_adapters. ComputerID в строках
+// заполняет получатель, когда узнает ID компьютера. Строки, которые
+// ChangeFilter счел неизменившимися, в замере отсутствуют.
+type Snapshot struct {
+	Time      time.Time
+	Computer  models.Computer
+	Processor *models.Processor
+	Memory    *models.Memory
+	Disks     []models.Disk
+	Network   []models.NetworkAdapter
+	// Inventory заполняется раз в InventoryInterval, в остальных замерах nil
+	Inventory *models.HardwareInventory
+}
+
+// Sink получатель пачек замеров, см. Buffer
+type Sink interface {
+	Send(ctx context.Context, b *Batch) error
+}
+
+// Collector снимает замеры. Скорость сети считается по разнице счетчиков
+// с предыдущим замером, поэтому у каждого потребителя свой Collector.
+type Collector struct {
+	prevNet  map[string]net.IOCountersStat
+	prevTime time.Time
+
+	inventoryTime time.Time
+}
+
+// NewCollector создает сборщик
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Collect снимает все показатели. Ошибки отдельных дисков пропускаются,
+// остальные прерывают замер.
+func (c *Collector) Collect() (*Snapshot, error) {
+	now := time.Now()
+	s := &Snapshot{Time: now}
+
+	computer, err := CurrentComputer()
+	if err != nil {
+		return nil, err
+	}
+	s.Computer = computer
+
+	// Процессор
+	cpuInfo, err := cpu.Info()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения данных о процессоре: %v", err)
+	}
+	if len(cpuInfo) == 0 {
+		return nil, fmt.Errorf("процессор не найден")
+	}
+	cpuUsage, err := cpu.Percent(0, false)
+	if err != nil || len(cpuUsage) == 0 {
+		return nil, fmt.Errorf("ошибка получения загрузки процессора: %v", err)
+	}
+	s.Processor = &models.Processor{
+		Model:        cpuInfo[0].ModelName,
+		Manufacturer: cpuInfo[0].VendorID,
+		Architecture: runtime.GOARCH,
+		ClockSpeed:   cpuInfo[0].Mhz / 1000, // GHz
+		CoreCount:    int(cpuInfo[0].Cores),
+		ThreadCount:  runtime.NumCPU(),
+		UsagePercent: cpuUsage[0],
+		Timestamp:    now,
+	}
+
+	// Память
+	memInfo, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения данных о памяти: %v", err)
+	}
+	s.Memory = &models.Memory{
+		TotalMemoryGB: float64(memInfo.Total) / bytesInGB,
+		UsedMemoryGB:  float64(memInfo.Used) / bytesInGB,
+		FreeMemoryGB:  float64(memInfo.Free) / bytesInGB,
+		UsagePercent:  memInfo.UsedPercent,
+		MemoryType:    MemoryType(),
+		Timestamp:     now,
+	}
+
+	// Диски
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка дисков: %v", err)
+	}
+	for _, part := range partitions {
+		usage, err := disk.Usage(part.Mountpoint)
+		if err != nil {
+			continue
+		}
+		s.Disks = append(s.Disks, models.Disk{
+			DriveLetter:  part.Device,
+			TotalSpaceGB: float64(usage.Total) / bytesInGB,
+			UsedSpaceGB:  float64(usage.Used) / bytesInGB,
+			FreeSpaceGB:  float64(usage.Free) / bytesInGB,
+			UsagePercent: usage.UsedPercent,
+			Timestamp:    now,
+		})
+	}
+
+	// Сеть
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения сетевой статистики: %v", err)
+	}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		// Без MAC-адресов замер все равно полезен
+		interfaces = nil
+	}
+	s.Network = c.networkAdapters(counters, interfaces, now)
+
+	// Состав оборудования; при ошибке он будет собран со следующим замером
+	if now.Sub(c.inventoryTime) >= InventoryInterval {
+		if inv, err := CollectInventory(); err == nil {
+			s.Inventory = inv
+			c.inventoryTime = now
+		} else {
+			log.Printf("Ошибка сбора состава оборудования: %v", err)
+		}
+	}
+
+	return s, nil
+}
+
+// networkAdapters считает скорости в МБ/с относительно прошлого замера
+func (c *Collector) networkAdapters(counters []net.IOCountersStat, interfaces []net.InterfaceStat, now time.Time) []models.NetworkAdapter {
+	macs := make(map[string]string, len(interfaces))
+	for _, iface := range interfaces {
+		macs[iface.Name] = iface.HardwareAddr
+	}
+
+	elapsed := now.Sub(c.prevTime).Seconds()
+	adapters := make([]models.NetworkAdapter, 0, len(counters))
+	for _, iface := range counters {
+		adapter := models.NetworkAdapter{
+			AdapterName:     iface.Name,
+			MacAddress:      macs[iface.Name],
+			SentMB:          float64(iface.BytesSent) / bytesInMB,
+			ReceivedMB:      float64(iface.BytesRecv) / bytesInMB,
+			SentPackets:     int64(iface.PacketsSent),
+			ReceivedPackets: int64(iface.PacketsRecv),
+			IsActive:        iface.BytesSent+iface.BytesRecv > 0,
+			Timestamp:       now,
+		}
+		// Счетчики сбрасываются при переподключении адаптера
+		if prev, ok := c.prevNet[iface.Name]; ok && elapsed > 0 &&
+			iface.BytesSent >= prev.BytesSent && iface.BytesRecv >= prev.BytesRecv {
+			adapter.UploadSpeed = float64(iface.BytesSent-prev.BytesSent) / bytesInMB / elapsed
+			adapter.DownloadSpeed = float64(iface.BytesRecv-prev.BytesRecv) / bytesInMB / elapsed
+		}
+		adapters = append(adapters, adapter)
+	}
+
+	c.prevNet = make(map[string]net.IOCountersStat, len(counters))
+	for _, iface := range counters {
+		c.prevNet[iface.Name] = iface
+	}
+	c.prevTime = now
+
+	return adapters
+}
+
+// CurrentComputer возвращает сведения о компьютере для таблицы computers
+func CurrentComputer() (models.Computer, error) {
+	hostInfo, err := host.Info()
+	if err != nil {
+		return models.Computer{}, fmt.Errorf("ошибка получения данных о хосте: %v", err)
+	}
+	currentUser, err := user.Current()
+	if err != nil {
+		return models.Computer{}, fmt.Errorf("ошибка получения текущего пользователя: %v", err)
+	}
+
+	return models.Computer{
+		MachineUUID:    MachineUUID(),
+		HostName:       hostInfo.Hostname,
+		UserName:       currentUser.Username,
+		OsName:         hostInfo.OS,
+		OsVersion:      hostInfo.PlatformVersion,
+		OsPlatform:     hostInfo.Platform,
+		OsArchitecture: hostInfo.KernelArch,
+		KernelVersion:  hostInfo.KernelVersion,
+		Uptime:         time.Now(),
+		ProcessCount:   int(hostInfo.Procs),
+		BootTime:       time.Unix(int64(hostInfo.BootTime), 0),
+		HomeDirectory:  currentUser.HomeDir,
+		Gid:            currentUser.Gid,
+		Uid:            currentUser.Uid,
+	}, nil
+}
+
+// MemoryType определяет тип памяти через dmidecode (только Linux)
+func MemoryType() string {
+	if runtime.GOOS == "linux" {
+		out, err := exec.Command("dmidecode", "-t", "17").Output()
+		if err == nil {
+			for _, t := range []string{"DDR5", "DDR4", "DDR3", "DDR2"} {
+				if strings.Contains(string(out), t) {
+					return t
+				}
+			}
+		}
+	}
+	return "Неизвестно"
+}
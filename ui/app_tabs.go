@@ -1,11 +1,14 @@
 package ui
 
 import (
+	"FYNEAPPS/apiclient"
 	"FYNEAPPS/database"
 	"FYNEAPPS/resources"
+	"FYNEAPPS/spool"
 	settings "FYNEAPPS/ui/setting_tab"
 	"FYNEAPPS/ui/tabs"
-	"database/sql"
+	"FYNEAPPSSERVER/auth"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -52,13 +55,31 @@ type User struct {
 	LoggedIn  bool      `json:"logged_in"`
 }
 
-// Добавьте глобальные переменные для управления состоянием вкладки
-var (
-	ticketsTab     fyne.CanvasObject
-	ticketsCleanup func()
+// Вкладки, которые можно открыть из меню трея
+const (
+	TabHardware = "hardware"
+	TabServers  = "servers"
+	TabTickets  = "tickets"
 )
 
-func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
+// showTab переключает вкладку последнего окна, построенного CreateAppTabs
+var showTab func(id string)
+
+// ShowTab открывает вкладку главного окна. Вызывается в потоке интерфейса.
+func ShowTab(id string) {
+	if showTab != nil {
+		showTab(id)
+	}
+}
+
+// CreateAppTabs строит главное окно. pool - общий пул соединений с БД,
+// созданный при запуске; вкладки не открывают собственных соединений.
+// queue - очередь операций, отложенных без связи (может быть nil).
+// role определяет, какие действия доступны пользователю во вкладках.
+// Вкладки создаются при первом открытии и хранятся до отмены ctx, которую
+// выполняет владелец окна при выходе пользователя; скрытые вкладки не
+// выполняют опросов.
+func CreateAppTabs(ctx context.Context, myApp fyne.App, window fyne.Window, pool *database.PGConnection, queue *spool.Spool, api *apiclient.Client, role auth.Role) fyne.CanvasObject {
 	// Создаем кнопки с иконками для вертикального меню
 
 	cpuBtn := widget.NewButtonWithIcon(settings.GetLocalizedString("MyComputer"), theme.ComputerIcon(), nil)
@@ -83,12 +104,12 @@ func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
 
 	// Добавляем новую кнопку обновления
 	updateBtn := widget.NewButtonWithIcon("Обновить приложение", theme.ViewRefreshIcon(), func() {
-		updateApp(window)
+		updateApp(window, api)
 	})
 
 	// Добавляем новую кнопку обновления
 	settingsBtn := widget.NewButtonWithIcon("Настройки приложения", theme.SettingsIcon(), func() {
-		updateApp(window)
+		updateApp(window, api)
 	})
 
 	// Настраиваем стиль кнопок
@@ -98,6 +119,14 @@ func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
 		btn.Importance = widget.MediumImportance
 	}
 
+	// Скрываем вкладки, которые роль пользователя не может просматривать
+	if !role.Can(auth.PermInventoryView) {
+		serverstatusBtn.Hide()
+	}
+	if !role.Can(auth.PermTicketsView) {
+		ticketBtn.Hide()
+	}
+
 	// Функция для обновления текстов
 	updateUI := func() {
 		cpuBtn.SetText(settings.GetLocalizedString("MyComputer"))
@@ -111,8 +140,13 @@ func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
 		settingsBtn.SetText(settings.GetLocalizedString("Settings"))
 	}
 
-	// Подписываемся на изменения языка
-	settings.OnLanguageChange(updateUI)
+	// Подписываемся на изменения языка; подписку нельзя снять, поэтому
+	// после закрытия окна она ничего не делает
+	settings.OnLanguageChange(func() {
+		if ctx.Err() == nil {
+			updateUI()
+		}
+	})
 
 	// Загрузка изображения
 	img := resources.ResourcePgatulogosmallPng // fyne.LoadResourceFromPath("images/main_screen/pgatu_logo_small.png")
@@ -161,6 +195,7 @@ func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
 		appGroup,
 
 		widget.NewSeparator(),
+		newConnectionStatus(ctx, pool, queue),
 		container.NewCenter(
 			widget.NewLabel("v0.0.17 alpha"),
 		),
@@ -171,8 +206,6 @@ func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
 
 	// Создаем контейнер для контента
 	content := container.NewStack()
-	currentTab := tabs.CreateHardwareTab(window) // Начальная вкладка
-	content.Objects = []fyne.CanvasObject{currentTab}
 
 	// Обновленная функция setActiveButton
 	setActiveButton := func(activeBtn *widget.Button) {
@@ -187,73 +220,104 @@ func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
 		}
 	}
 
-	// setTab := func(tab fyne.CanvasObject, btn *widget.Button) {
-	// 	setActiveButton(btn)
-	// 	content.Objects = []fyne.CanvasObject{tab}
-	// 	content.Refresh()
-	// }
+	// Вкладки кэшируются по кнопке; openTab скрывает текущую вкладку и
+	// показывает выбранную, создавая ее при первом открытии. Вкладка, которую
+	// не удалось создать, не кэшируется: следующее нажатие повторит попытку.
+	cache := make(map[*widget.Button]tabs.Tab)
+	var current tabs.Tab
+	openTab := func(btn *widget.Button, build func() (tabs.Tab, error)) {
+		tab, ok := cache[btn]
+		if !ok {
+			var err error
+			if tab, err = build(); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			cache[btn] = tab
+		}
 
-	// Создаем подключение к базе данных
-	db := database.New()
-	opts := database.ConnectionOptions{
-		Host:     "83.166.245.249",
-		Port:     "5432",
-		User:     "user",
-		Password: "user",
-		DBName:   "grafana_db",
-		SSLMode:  "default",
+		if current != nil && current != tab {
+			current.OnHide()
+		}
+		current = tab
+
+		setActiveButton(btn)
+		content.Objects = []fyne.CanvasObject{tab.Content()}
+		content.Refresh()
+		tab.OnShow()
 	}
 
-	// Устанавливаем соединение
-	db.Connect(opts)
-	defer db.Disconnect() // Закрываем соединение при выходе
+	// Вкладки закрываются вместе с окном. Close может ждать завершения
+	// начатых проверок, поэтому выполняется вне потока интерфейса.
+	context.AfterFunc(ctx, func() {
+		fyne.Do(func() {
+			for _, tab := range cache {
+				go tab.Close()
+			}
+		})
+	})
 
 	// Модифицированные обработчики для кнопок
 	cpuBtn.OnTapped = func() {
-		setActiveButton(cpuBtn)
-		content.Objects = []fyne.CanvasObject{tabs.CreateHardwareTab(window)}
-		content.Refresh()
+		openTab(cpuBtn, func() (tabs.Tab, error) {
+			return tabs.NewHardwareTab(ctx, window, pool, api), nil
+		})
 	}
 
 	appslibraryBtn.OnTapped = func() {
-		setActiveButton(appslibraryBtn)
-		content.Objects = []fyne.CanvasObject{tabs.CreateAppsLibraryTab(window, db)}
-		content.Refresh()
+		openTab(appslibraryBtn, func() (tabs.Tab, error) {
+			return tabs.StaticTab(tabs.CreateAppsLibraryTab(window, pool)), nil
+		})
 	}
 
 	processBtn.OnTapped = func() {
-		setActiveButton(processBtn)
-		content.Objects = []fyne.CanvasObject{tabs.CreateProcessesTab(window)}
-		content.Refresh()
+		openTab(processBtn, func() (tabs.Tab, error) {
+			return tabs.NewProcessesTab(ctx, window), nil
+		})
 	}
 
 	serverstatusBtn.OnTapped = func() {
-		setActiveButton(serverstatusBtn)
-		content.Objects = []fyne.CanvasObject{tabs.CreateServerStatusTab(window)}
-		content.Refresh()
+		openTab(serverstatusBtn, func() (tabs.Tab, error) {
+			return tabs.NewServerStatusTab(ctx, window, api, role), nil
+		})
 	}
 
 	compterprogramsBtn.OnTapped = func() {
-		setActiveButton(compterprogramsBtn)
-		content.Objects = []fyne.CanvasObject{tabs.CreateSoftwareTab(window)}
-		content.Refresh()
+		openTab(compterprogramsBtn, func() (tabs.Tab, error) {
+			return tabs.NewSoftwareTab(ctx, window, pool), nil
+		})
 	}
 
 	ticketBtn.OnTapped = func() {
-		setActiveButton(ticketBtn)
-		contentObj, _ := tabs.CreateTicketsTab(window)
-		content.Objects = []fyne.CanvasObject{contentObj}
-		content.Refresh()
+		openTab(ticketBtn, func() (tabs.Tab, error) {
+			return tabs.NewTicketsTab(ctx, window, pool, role)
+		})
 	}
 
 	settingsBtn.OnTapped = func() {
-		setActiveButton(settingsBtn)
-		content.Objects = []fyne.CanvasObject{settings.CreateSettingsTab(window, myApp)}
-		content.Refresh()
+		openTab(settingsBtn, func() (tabs.Tab, error) {
+			return tabs.StaticTab(settings.CreateSettingsTab(window, myApp)), nil
+		})
+	}
+
+	// Трей не открывает вкладки, скрытые для роли пользователя
+	showTab = func(id string) {
+		switch id {
+		case TabHardware:
+			cpuBtn.OnTapped()
+		case TabServers:
+			if !serverstatusBtn.Hidden {
+				serverstatusBtn.OnTapped()
+			}
+		case TabTickets:
+			if !ticketBtn.Hidden {
+				ticketBtn.OnTapped()
+			}
+		}
 	}
 
-	// Устанавливаем активную кнопку по умолчанию
-	setActiveButton(cpuBtn)
+	// Начальная вкладка
+	cpuBtn.OnTapped()
 
 	// Основной контейнер с вертикальным меню слева
 	return container.NewBorder(
@@ -273,15 +337,7 @@ func CreateAppTabs(myApp fyne.App, window fyne.Window) fyne.CanvasObject {
 	)
 }
 
-// Добавьте функцию для очистки при закрытии приложения
-func cleanupApp() {
-	if ticketsCleanup != nil {
-		ticketsCleanup()
-	}
-	// ... другие cleanup-функции ...
-}
-
-func updateApp(window fyne.Window) {
+func updateApp(window fyne.Window, api *apiclient.Client) {
 	progress := widget.NewProgressBarInfinite()
 	statusLabel := widget.NewLabel(settings.GetLocalizedString("CheckingUpdates"))
 
@@ -390,6 +446,16 @@ func updateApp(window fyne.Window) {
 		// 6. Подготовка к обновлению
 		statusLabel.SetText(settings.GetLocalizedString("CleaningUp"))
 
+		// Завершаем сессию на сервере API, пока ее токен еще сохранен
+		if sessionID := getCurrentSessionID(); sessionID != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			api.SetToken(sessionID)
+			if err := api.Logout(ctx); err != nil {
+				fmt.Print("Ошибка завершения сессии", err)
+			}
+			cancel()
+		}
+
 		// Удаляем файл сессии
 		sessionFile := filepath.Join(appDir, "session.json")
 		if _, err := os.Stat(sessionFile); err == nil {
@@ -398,13 +464,6 @@ func updateApp(window fyne.Window) {
 			}
 		}
 
-		// Удаляем сессию из базы данных
-		if sessionID := getCurrentSessionID(); sessionID != "" {
-			if err := deleteCurrentSession(sessionID); err != nil {
-				fmt.Print("Ошибка удаления сессии из БД", err)
-			}
-		}
-
 		// 7. Устанавливаем обновление
 		statusLabel.SetText(settings.GetLocalizedString("InstallingUpdate"))
 
@@ -592,26 +651,6 @@ func getCurrentSessionID() string {
 	return session.SessionID
 }
 
-func deleteCurrentSession(sessionID string) error {
-	connStr := "user=user dbname=grafana_db password=user host=83.166.245.249 port=5432 sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return fmt.Errorf("ошибка подключения: %w", err)
-	}
-	defer db.Close()
-
-	if err = db.Ping(); err != nil {
-		return fmt.Errorf("ошибка проверки соединения: %w", err)
-	}
-
-	_, err = db.Exec("DELETE FROM user_sessions WHERE session_id = $1", sessionID)
-	if err != nil {
-		return fmt.Errorf("ошибка удаления сессии: %w", err)
-	}
-
-	return nil
-}
-
 // Вспомогательные функции для отображения диалогов
 func showErrorDialog(window fyne.Window, message string) {
 	dialog := dialog.NewError(
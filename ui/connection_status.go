@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"FYNEAPPS/database"
+	"FYNEAPPS/spool"
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newConnectionStatus строит индикатор автономного режима: он виден, пока
+// нет связи с БД или в очереди на диске остаются неотправленные данные.
+// После отмены ctx индикатор перестает реагировать на события.
+func newConnectionStatus(ctx context.Context, pool *database.PGConnection, queue *spool.Spool) fyne.CanvasObject {
+	icon := widget.NewIcon(theme.WarningIcon())
+	label := widget.NewLabel("")
+	label.Wrapping = fyne.TextWrapWord
+	status := container.NewBorder(nil, nil, icon, nil, label)
+
+	update := func() {
+		if ctx.Err() != nil {
+			return
+		}
+		queued := 0
+		if queue != nil {
+			queued = queue.Len()
+		}
+
+		switch {
+		case !pool.Healthy() && queued > 0:
+			icon.SetResource(theme.WarningIcon())
+			label.SetText(fmt.Sprintf("Нет связи с БД, в очереди: %d", queued))
+		case !pool.Healthy():
+			icon.SetResource(theme.WarningIcon())
+			label.SetText("Нет связи с БД")
+		case queued > 0:
+			icon.SetResource(theme.UploadIcon())
+			label.SetText(fmt.Sprintf("Отправка отложенных данных: %d", queued))
+		default:
+			status.Hide()
+			return
+		}
+		status.Show()
+	}
+	update()
+
+	pool.OnStatusChange(func(bool) {
+		fyne.Do(update)
+	})
+	if queue != nil {
+		queue.OnChange(func(int) {
+			fyne.Do(update)
+		})
+	}
+	return status
+}
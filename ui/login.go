@@ -103,6 +103,9 @@ func ShowSuccessDialog(message string, parent fyne.Window) {
 	d.Show()
 }
 
+// CreateLoginWindow строит окно входа. onLogin вызывается в потоке
+// интерфейса; при успешном входе окно закрывает вызывающий код, поэтому
+// после true окно больше не используется.
 func CreateLoginWindow(app fyne.App, onLogin func(string, string) bool) fyne.Window {
 	loginWindow := app.NewWindow("ПГАТУ инфраструктура")
 	loginWindow.SetFixedSize(true)
@@ -153,19 +156,17 @@ func CreateLoginWindow(app fyne.App, onLogin func(string, string) bool) fyne.Win
 			ShowErrorDialog(err.Error(), loginWindow)
 			return
 		}
-		if onLogin(username.Text, password.Text) {
-			ShowSuccessDialog("Успешный вход в систему", loginWindow)
-			loginWindow.Hide()
-		} else {
+		if !onLogin(username.Text, password.Text) {
 			ShowErrorDialog("Неверный логин или пароль", loginWindow)
 		}
 	})
 
-	// Кнопка Отмена с перезапуском окна
+	// Кнопка Отмена возвращает форму в исходное состояние. Окно не
+	// пересоздается: им владеет вызывающий код.
 	cancelButton := widget.NewButton("Отмена", func() {
-		loginWindow.Close()
-		newLoginWindow := CreateLoginWindow(app, onLogin)
-		newLoginWindow.Show()
+		username.SetText("user")
+		password.SetText("user")
+		loginWindow.Canvas().Focus(username)
 	})
 
 	form := container.NewVBox(
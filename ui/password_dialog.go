@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"FYNEAPPSSERVER/auth"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ShowChangePasswordDialog открывает форму смены пароля. onChange вызывается
+// в отдельной горутине и должен проверить текущий пароль. Если forced, диалог
+// показан после сброса пароля администратором: отмена вызывает onCancel.
+func ShowChangePasswordDialog(parent fyne.Window, forced bool, onChange func(current, next string) error, onCancel func()) {
+	current := widget.NewPasswordEntry()
+	current.Validator = func(s string) error {
+		if s == "" {
+			return fmt.Errorf("Введите текущий пароль")
+		}
+		return nil
+	}
+
+	next := widget.NewPasswordEntry()
+	next.Validator = func(s string) error {
+		if len([]rune(s)) < auth.MinPasswordLength {
+			return fmt.Errorf("Пароль должен быть не короче %d символов", auth.MinPasswordLength)
+		}
+		if strings.TrimSpace(s) != s {
+			return fmt.Errorf("Пароль не должен начинаться или заканчиваться пробелом")
+		}
+		return nil
+	}
+
+	confirm := widget.NewPasswordEntry()
+	confirm.Validator = func(s string) error {
+		if s != next.Text {
+			return fmt.Errorf("Пароли не совпадают")
+		}
+		return nil
+	}
+
+	title := "Смена пароля"
+	currentLabel := "Текущий пароль"
+	if forced {
+		title = "Требуется смена пароля"
+		currentLabel = "Временный пароль"
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(currentLabel, current),
+		widget.NewFormItem("Новый пароль", next),
+		widget.NewFormItem("Повторите пароль", confirm),
+	}
+
+	d := dialog.NewForm(title, "Сменить", "Отмена", items, func(ok bool) {
+		if !ok {
+			if onCancel != nil {
+				onCancel()
+			}
+			return
+		}
+
+		oldPassword, newPassword := current.Text, next.Text
+		go func() {
+			err := onChange(oldPassword, newPassword)
+			fyne.Do(func() {
+				if err != nil {
+					ShowErrorDialog(err.Error(), parent)
+					ShowChangePasswordDialog(parent, forced, onChange, onCancel)
+					return
+				}
+				ShowSuccessDialog("Пароль изменен", parent)
+			})
+		}()
+	}, parent)
+
+	d.Resize(fyne.NewSize(420, 260))
+	d.Show()
+}
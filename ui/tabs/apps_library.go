@@ -127,19 +127,9 @@ func CreateAppsLibraryTab(window fyne.Window, db *database.PGConnection) fyne.Ca
 
 	updateContent := func() {
 		if !dbConn.IsConnected() {
-			opts := database.ConnectionOptions{
-				Host:     "83.166.245.249",
-				Port:     "5432",
-				User:     "user",
-				Password: "user",
-				DBName:   "grafana_db",
-				SSLMode:  "disable",
-			}
-			if err := dbConn.Connect(opts); err != nil {
-				log.Printf("Ошибка подключения к БД: %v", err)
-				scrollContainer.Content = widget.NewLabel("Ошибка подключения к базе данных")
-				return
-			}
+			log.Printf("Ошибка подключения к БД: %v", dbConn.LastError())
+			scrollContainer.Content = widget.NewLabel("Ошибка подключения к базе данных")
+			return
 		}
 
 		softwareList, err := loadLatestSoftwareVersions()
@@ -1,8 +1,11 @@
 package tabs
 
 import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPS/config"
+	"FYNEAPPS/database"
+	"FYNEAPPS/telemetry"
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"os/exec"
@@ -10,6 +13,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -32,6 +36,11 @@ type HardwareComponent struct {
 	Usage   float64
 	Details map[string]string
 	Disks   []DiskInfo
+
+	// Суммарная скорость сети в МБ/с; HasRates ложно на первом замере
+	SendRate float64
+	RecvRate float64
+	HasRates bool
 }
 
 type DiskInfo struct {
@@ -49,15 +58,30 @@ var (
 	prevNetTime  time.Time
 )
 
-// Database connection and computer ID
+// dbEnabled состояние флажка "Сохранять данные в БД"
+var dbEnabled bool
+
+// Замеры для БД копятся в памяти и пишутся пачкой раз в dbFlushInterval
+const (
+	dbFlushInterval = 30 * time.Second
+	dbMaxBuffered   = 3600
+)
+
+// dbCollector снимает замеры для записи в БД. Он отдельный от карточек,
+// чтобы скорость сети считалась между соседними сохранениями. dbFilter
+// отбрасывает неизменившиеся показатели; создается при первом замере,
+// когда настройки уже загружены.
 var (
-	db            *sql.DB
-	dbEnabled     bool
-	computerSaved bool // Флаг, что информация о компьютере уже сохранена
-	computerID    int  // ID сохраненного компьютера в БД
+	dbCollector  = telemetry.NewCollector()
+	dbBuffer     = telemetry.NewBuffer(dbMaxBuffered)
+	dbFilter     *telemetry.ChangeFilter
+	dbFilterOnce sync.Once
 )
 
-func CreateHardwareTab(window fyne.Window) fyne.CanvasObject {
+// NewHardwareTab строит вкладку оборудования. Карточки и живые графики
+// обновляются, пока вкладка видна; замеры для БД снимаются до отмены ctx,
+// то есть и тогда, когда открыта другая вкладка.
+func NewHardwareTab(ctx context.Context, window fyne.Window, pool *database.PGConnection, api *apiclient.Client) Tab {
 	title := canvas.NewText("Мониторинг системы", theme.Color(theme.ColorNameForeground))
 	title.TextSize = 24
 	title.Alignment = fyne.TextAlignCenter
@@ -66,14 +90,20 @@ func CreateHardwareTab(window fyne.Window) fyne.CanvasObject {
 	// Добавляем переключатель для активации/деактивации сохранения в БД
 	dbToggle := widget.NewCheck("Сохранять данные в БД", func(checked bool) {
 		dbEnabled = checked
-		if checked && db == nil {
-			initDB()
-		}
 	})
 	dbToggle.SetChecked(false)
 
 	// Создаем контейнер для карточек (теперь 3 в ряд)
 	cardsContainer := container.NewGridWithColumns(3)
+
+	// Запись замеров в БД; sink запоминает ID компьютера для графиков
+	sink := telemetry.NewDBSink(pool.DB())
+
+	// Графики загрузки: живое окно и история из БД
+	charts := newHardwareCharts(pool, api, sink)
+	// Лента изменений состава оборудования
+	timeline := newHardwareTimeline(api, charts.computerID)
+	timeline.Reload()
 	scrollContainer := container.NewVScroll(
 		container.NewVBox(
 			container.NewPadded(title),
@@ -81,13 +111,14 @@ func CreateHardwareTab(window fyne.Window) fyne.CanvasObject {
 			container.NewHBox(layout.NewSpacer(), dbToggle, layout.NewSpacer()),
 			widget.NewSeparator(),
 			cardsContainer,
+			widget.NewSeparator(),
+			charts.Content(),
+			widget.NewSeparator(),
+			timeline.Content(),
 		),
 	)
 	scrollContainer.SetMinSize(fyne.NewSize(800, 600))
 
-	// Канал для остановки обновлений
-	stopChan := make(chan struct{})
-
 	// Функция обновления данных
 	updateData := func() {
 		components, err := getSystemComponents()
@@ -95,6 +126,7 @@ func CreateHardwareTab(window fyne.Window) fyne.CanvasObject {
 			log.Printf("Error getting system info: %v", err)
 			return
 		}
+		recordLiveSample(components, time.Now())
 
 		fyne.Do(func() {
 			cardsContainer.Objects = make([]fyne.CanvasObject, 0)
@@ -104,175 +136,108 @@ func CreateHardwareTab(window fyne.Window) fyne.CanvasObject {
 				cardsContainer.Add(paddedCard)
 			}
 			cardsContainer.Refresh()
+			charts.RefreshLive()
 		})
-
-		// Сохраняем данные в БД, если включено
-		if dbEnabled {
-			saveAllData()
-		}
 	}
 
-	// Запускаем периодическое обновление
+	// Копим замеры для БД, если включено; пока связи нет, они ждут в буфере
 	go func() {
 		ticker := time.NewTicker(1 * time.Second)
 		defer ticker.Stop()
+		flushTicker := time.NewTicker(dbFlushInterval)
+		defer flushTicker.Stop()
 
 		for {
 			select {
 			case <-ticker.C:
-				updateData()
-			case <-stopChan:
+				if dbEnabled {
+					collectForDB()
+				}
+			case <-flushTicker.C:
+				flushToDB(pool, sink)
+			case <-ctx.Done():
+				// Накопленные замеры не ждут следующего входа в приложение
+				flushToDB(pool, sink)
 				return
 			}
 		}
 	}()
 
-	// Кнопка ручного обновления
-	refreshBtn := widget.NewButtonWithIcon("Обновить", theme.ViewRefreshIcon(), updateData)
-	refreshBtn.Importance = widget.MediumImportance
+	// Карточки обновляются раз в секунду на время показа вкладки
+	liveRefresh := func(ctx context.Context) {
+		go func() {
+			ticker := time.NewTicker(1 * time.Second)
+			defer ticker.Stop()
+
+			updateData()
+			for {
+				select {
+				case <-ticker.C:
+					updateData()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
 
-	// Очистка при закрытии
-	window.SetOnClosed(func() {
-		close(stopChan) // Останавливаем горутину обновления
-		if db != nil {
-			db.Close()
-		}
+	// Кнопка ручного обновления
+	refreshBtn := widget.NewButtonWithIcon("Обновить", theme.ViewRefreshIcon(), func() {
+		go updateData()
+		charts.Reload()
+		timeline.Reload()
 	})
+	refreshBtn.Importance = widget.MediumImportance
 
-	return container.NewBorder(
+	content := container.NewBorder(
 		nil,
 		container.NewHBox(layout.NewSpacer(), refreshBtn, layout.NewSpacer()),
 		nil,
 		nil,
 		scrollContainer,
 	)
+	return newPollingTab(ctx, content, liveRefresh)
 }
 
-// Инициализация БД
-func initDB() {
-	connStr := "user=user dbname=grafana_db password=user host=83.166.245.249 port=5432 sslmode=disable"
-	var err error
-
-	db, err = sql.Open("postgres", connStr)
+// collectForDB снимает замер и откладывает его до следующей записи в БД
+func collectForDB() {
+	snapshot, err := dbCollector.Collect()
 	if err != nil {
-		log.Printf("Error opening database: %v", err)
-		dbEnabled = false
+		log.Printf("Error collecting telemetry for DB: %v", err)
 		return
 	}
 
-	// Проверяем соединение
-	err = db.Ping()
-	if err != nil {
-		log.Printf("Error connecting to database: %v", err)
-		dbEnabled = false
+	dbFilterOnce.Do(func() {
+		dbFilter, err = telemetry.FilterFromConfig(config.Current().Telemetry)
+		if err != nil {
+			log.Printf("Ошибка настроек телеметрии, сохраняются все замеры: %v", err)
+		}
+	})
+	if dbFilter != nil && !dbFilter.Apply(snapshot) {
 		return
 	}
+	dbBuffer.Add(snapshot)
 }
 
-// Сохраняет все данные в БД
-func saveAllData() {
-	if db == nil {
-		return
-	}
-
-	// Получаем информацию о хосте
-	hostInfo, err := host.Info()
-	if err != nil {
-		log.Printf("Error getting host info for DB: %v", err)
-		return
-	}
-
-	// Получаем информацию о текущем пользователе
-	currentUser, err := user.Current()
-	if err != nil {
-		log.Printf("Error getting current user for DB: %v", err)
-		return
-	}
-
-	// Сохраняем основную информацию о компьютере
-	err = saveComputerInfo(hostInfo, currentUser)
-	if err != nil {
-		log.Printf("Error saving computer info: %v", err)
-	}
-
-	// CPU информация
-	cpuInfo, err := cpu.Info()
-	if err != nil {
-		log.Printf("Error getting CPU info for DB: %v", err)
-		return
-	}
-	cpuUsage, err := cpu.Percent(0, false)
-	if err != nil {
-		log.Printf("Error getting CPU usage for DB: %v", err)
-		return
-	}
-
-	// Сохраняем информацию о процессоре
-	err = saveCPUInfo(cpuInfo, cpuUsage[0])
-	if err != nil {
-		log.Printf("Error saving CPU info: %v", err)
-	}
-
-	// Память
-	memInfo, err := mem.VirtualMemory()
-	if err != nil {
-		log.Printf("Error getting memory info for DB: %v", err)
+// flushToDB пишет накопленные замеры одной транзакцией. Без связи с БД, а
+// также пока не разобрана очередь на диске, замеры уходят в эту очередь:
+// так они переживут перезапуск и будут записаны по порядку.
+func flushToDB(pool *database.PGConnection, sink *telemetry.DBSink) {
+	if offlineQueue != nil && (!pool.Healthy() || offlineQueue.Len() > 0) {
+		SpoolPendingTelemetry()
 		return
 	}
-
-	// Сохраняем информацию о памяти
-	err = saveMemoryInfo(memInfo)
-	if err != nil {
-		log.Printf("Error saving memory info: %v", err)
-	}
-
-	// Диски
-	partitions, err := disk.Partitions(false)
-	if err != nil {
-		log.Printf("Error getting disk info for DB: %v", err)
+	if !pool.Healthy() {
 		return
 	}
 
-	var disks []DiskInfo
-	for _, part := range partitions {
-		usage, err := disk.Usage(part.Mountpoint)
-		if err != nil {
-			continue
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := dbBuffer.Flush(ctx, sink); err != nil {
+		log.Printf("Error saving telemetry (%d samples queued): %v", dbBuffer.Len(), err)
+		if !pool.IsConnected() {
+			SpoolPendingTelemetry()
 		}
-
-		disks = append(disks, DiskInfo{
-			Name:      part.Device,
-			Total:     usage.Total,
-			Used:      usage.Used,
-			Free:      usage.Free,
-			Usage:     usage.UsedPercent,
-			MountPath: part.Mountpoint,
-		})
-	}
-
-	// Сохраняем информацию о дисках
-	err = saveDiskInfo(disks)
-	if err != nil {
-		log.Printf("Error saving disk info: %v", err)
-	}
-
-	// Сеть
-	netInterfaces, err := net.IOCounters(true)
-	if err != nil {
-		log.Printf("Error getting network info for DB: %v", err)
-		return
-	}
-
-	// Получаем дополнительную информацию о сетевых интерфейсах
-	netStats, err := net.Interfaces()
-	if err != nil {
-		log.Printf("Error getting network stats for DB: %v", err)
-	}
-
-	// Сохраняем информацию о сети
-	err = saveNetworkInfo(netInterfaces, netStats)
-	if err != nil {
-		log.Printf("Error saving network info: %v", err)
 	}
 }
 
@@ -364,227 +329,8 @@ func createHardwareCard(component HardwareComponent) fyne.CanvasObject {
 	)
 }
 
-// Сохраняет или обновляет информацию о компьютере
-func saveComputerInfo(hostInfo *host.InfoStat, currentUser *user.User) error {
-	if computerSaved {
-		return nil // Уже сохранено, пропускаем
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Сначала пытаемся найти существующую запись
-	err := db.QueryRowContext(ctx, `
-        SELECT computer_id 
-        FROM computers 
-        WHERE host_name = $1 
-        AND user_name = $2 
-        AND os_name = $3 
-        AND os_version = $4
-        LIMIT 1`,
-		hostInfo.Hostname,
-		currentUser.Username,
-		hostInfo.OS,
-		hostInfo.PlatformVersion,
-	).Scan(&computerID)
-
-	if err == nil {
-		// Запись найдена, используем существующий ID
-		computerSaved = true
-		return nil
-	}
-
-	// Если записи нет (ошибка не равна sql.ErrNoRows), возвращаем ошибку
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("ошибка поиска компьютера: %v", err)
-	}
-
-	// Создаем новую запись
-	bootTime := time.Unix(int64(hostInfo.BootTime), 0)
-	username := currentUser.Username
-
-	err = db.QueryRowContext(ctx, `
-        INSERT INTO computers (
-            host_name, user_name, os_name, os_version, os_platform, 
-            os_architecture, kernel_version, process_count, 
-            boot_time, home_directory, gid, uid
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
-		hostInfo.Hostname,
-		username,
-		hostInfo.OS,
-		hostInfo.PlatformVersion,
-		hostInfo.Platform,
-		hostInfo.KernelArch,
-		hostInfo.KernelVersion,
-		hostInfo.Procs,
-		bootTime,
-		currentUser.HomeDir,
-		currentUser.Gid,
-		currentUser.Uid,
-	).Scan(&computerID)
-
-	if err != nil {
-		return fmt.Errorf("ошибка сохранения информации о компьютере: %v", err)
-	}
-
-	computerSaved = true
-	return nil
-}
-
-// Сохраняет информацию о процессоре
-func saveCPUInfo(cpuInfo []cpu.InfoStat, cpuUsage float64) error {
-	if len(cpuInfo) == 0 || !computerSaved {
-		return nil
-	}
-
-	_, err := db.Exec(`
-        INSERT INTO processors (
-            computer_id, model, manufacturer, architecture, 
-            clock_speed, core_count, thread_count, usage_percent
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-		computerID,
-		cpuInfo[0].ModelName,
-		cpuInfo[0].VendorID,
-		runtime.GOARCH,
-		float64(cpuInfo[0].Mhz)/1000, // GHz
-		cpuInfo[0].Cores,
-		runtime.NumCPU(), // threads
-		cpuUsage,
-	)
-
-	return err
-}
-
-// Сохраняет информацию о памяти
-func saveMemoryInfo(memInfo *mem.VirtualMemoryStat) error {
-	if memInfo == nil || !computerSaved {
-		return nil
-	}
-
-	totalGB := float64(memInfo.Total) / 1024 / 1024 / 1024
-	usedGB := float64(memInfo.Used) / 1024 / 1024 / 1024
-	freeGB := float64(memInfo.Free) / 1024 / 1024 / 1024
-
-	_, err := db.Exec(`
-        INSERT INTO memory (
-            computer_id, total_memory_gb, used_memory_gb, 
-            free_memory_gb, usage_percent, memory_type
-        ) VALUES ($1, $2, $3, $4, $5, $6)`,
-		computerID,
-		totalGB,
-		usedGB,
-		freeGB,
-		memInfo.UsedPercent,
-		getMemoryType(),
-	)
-
-	return err
-}
-
-// Сохраняет информацию о дисках
-func saveDiskInfo(disks []DiskInfo) error {
-	if len(disks) == 0 || !computerSaved {
-		return nil
-	}
-
-	for _, disk := range disks {
-		totalGB := float64(disk.Total) / 1024 / 1024 / 1024
-		usedGB := float64(disk.Used) / 1024 / 1024 / 1024
-		freeGB := float64(disk.Free) / 1024 / 1024 / 1024
-
-		_, err := db.Exec(`
-            INSERT INTO disks (
-                computer_id, drive_letter, total_space_gb, 
-                used_space_gb, free_space_gb, usage_percent
-            ) VALUES ($1, $2, $3, $4, $5, $6)`,
-			computerID,
-			disk.Name,
-			totalGB,
-			usedGB,
-			freeGB,
-			disk.Usage,
-		)
-
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// Сохраняет информацию о сетевых адаптерах
-func saveNetworkInfo(netInterfaces []net.IOCountersStat, netStats []net.InterfaceStat) error {
-	if len(netInterfaces) == 0 || !computerSaved {
-		return nil
-	}
-
-	now := time.Now()
-
-	for _, iface := range netInterfaces {
-		var uploadSpeed, downloadSpeed float64
-		var macAddr string
-
-		// Находим MAC-адрес
-		for _, stat := range netStats {
-			if stat.Name == iface.Name {
-				macAddr = stat.HardwareAddr
-				break
-			}
-		}
-
-		// Рассчитываем скорости, если есть предыдущие данные
-		if prevNetStats != nil && !prevNetTime.IsZero() {
-			elapsed := now.Sub(prevNetTime).Seconds()
-
-			// Ищем предыдущие значения для этого интерфейса
-			var prevBytesSent, prevBytesRecv uint64
-			for _, prevIface := range prevNetStats {
-				if prevIface.Name == iface.Name {
-					prevBytesSent = prevIface.BytesSent
-					prevBytesRecv = prevIface.BytesRecv
-					break
-				}
-			}
-
-			// Рассчитываем скорости в МБ/с
-			uploadSpeed = float64(iface.BytesSent-prevBytesSent) / (1024 * 1024) / elapsed
-			downloadSpeed = float64(iface.BytesRecv-prevBytesRecv) / (1024 * 1024) / elapsed
-		}
-
-		sentMB := float64(iface.BytesSent) / 1024 / 1024
-		recvMB := float64(iface.BytesRecv) / 1024 / 1024
-
-		_, err := db.Exec(`
-            INSERT INTO network_adapters (
-                computer_id, adapter_name, mac_address, upload_speed_mbps, 
-                download_speed_mbps, sent_mb, received_mb, sent_packets, 
-                received_packets, is_active
-            ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
-			computerID,
-			iface.Name,
-			macAddr,
-			uploadSpeed,
-			downloadSpeed,
-			sentMB,
-			recvMB,
-			iface.PacketsSent,
-			iface.PacketsRecv,
-			iface.BytesSent+iface.BytesRecv > 0,
-		)
-
-		if err != nil {
-			return err
-		}
-	}
-
-	// Сохраняем текущие значения для следующего расчета
-	prevNetStats = netInterfaces
-	prevNetTime = now
-
-	return nil
-}
-
-// Модифицированная функция getSystemComponents для сохранения данных в БД
+// getSystemComponents собирает данные для карточек. Запись в БД выполняет
+// collectForDB, чтобы данные не сохранялись дважды за один тик.
 func getSystemComponents() ([]HardwareComponent, error) {
 	var components []HardwareComponent
 
@@ -600,14 +346,6 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		return nil, fmt.Errorf("error getting current user: %v", err)
 	}
 
-	// Сохраняем основную информацию о компьютере
-	if db != nil {
-		err = saveComputerInfo(hostInfo, currentUser)
-		if err != nil {
-			log.Printf("Error saving computer info: %v", err)
-		}
-	}
-
 	// CPU информация
 	cpuInfo, err := cpu.Info()
 	if err != nil {
@@ -618,14 +356,6 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		return nil, fmt.Errorf("error getting CPU usage: %v", err)
 	}
 
-	// Сохраняем информацию о процессоре
-	if db != nil {
-		err = saveCPUInfo(cpuInfo, cpuUsage[0])
-		if err != nil {
-			log.Printf("Error saving CPU info: %v", err)
-		}
-	}
-
 	// Определяем разрядность процессора
 	arch := "32-bit"
 	if runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64" {
@@ -655,14 +385,6 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		return nil, fmt.Errorf("error getting memory info: %v", err)
 	}
 
-	// Сохраняем информацию о памяти
-	if db != nil {
-		err = saveMemoryInfo(memInfo)
-		if err != nil {
-			log.Printf("Error saving memory info: %v", err)
-		}
-	}
-
 	// Получаем информацию о модулях памяти (только для Linux)
 	var memModules string
 	if runtime.GOOS == "linux" {
@@ -676,7 +398,7 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		"Всего":        fmt.Sprintf("%.2f GB", float64(memInfo.Total)/1024/1024/1024),
 		"Использовано": fmt.Sprintf("%.2f GB", float64(memInfo.Used)/1024/1024/1024),
 		"Свободно":     fmt.Sprintf("%.2f GB", float64(memInfo.Free)/1024/1024/1024),
-		"Тип":          getMemoryType(),
+		"Тип":          telemetry.MemoryType(),
 	}
 
 	if memModules != "" {
@@ -714,14 +436,6 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		})
 	}
 
-	// Сохраняем информацию о дисках
-	if db != nil {
-		err = saveDiskInfo(disks)
-		if err != nil {
-			log.Printf("Error saving disk info: %v", err)
-		}
-	}
-
 	components = append(components, HardwareComponent{
 		ID:    "disks",
 		Name:  "Диски",
@@ -745,12 +459,11 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		log.Printf("Ошибка получения информации о сетевых интерфейсах: %v", err)
 	}
 
-	// Сохраняем информацию о сети
-	if db != nil {
-		err = saveNetworkInfo(netInterfaces, netStats)
-		if err != nil {
-			log.Printf("Error saving network info: %v", err)
-		}
+	netComponent := HardwareComponent{
+		ID:    "network",
+		Name:  "Сетевая активность",
+		Icon:  theme.StorageIcon(),
+		Usage: 0,
 	}
 
 	// Создаем детализированную информацию о сети
@@ -761,6 +474,8 @@ func getSystemComponents() ([]HardwareComponent, error) {
 	// Добавляем общую статистику по сети
 	if len(netInterfaces) > 0 {
 		var totalSent, totalRecv, totalPacketsSent, totalPacketsRecv uint64
+		var totalSendRate, totalRecvRate float64
+		var haveRates bool
 		var activeAdapters []string
 
 		now := time.Now()
@@ -768,6 +483,7 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		// Рассчитываем скорости, если есть предыдущие данные
 		if prevNetStats != nil && !prevNetTime.IsZero() {
 			elapsed := now.Sub(prevNetTime).Seconds()
+			haveRates = true
 
 			for _, iface := range netInterfaces {
 				if iface.BytesSent+iface.BytesRecv > 0 {
@@ -784,6 +500,8 @@ func getSystemComponents() ([]HardwareComponent, error) {
 					// Рассчитываем скорости в МБ/с
 					sentSpeed := float64(iface.BytesSent-prevBytesSent) / (1024 * 1024) / elapsed
 					recvSpeed := float64(iface.BytesRecv-prevBytesRecv) / (1024 * 1024) / elapsed
+					totalSendRate += sentSpeed
+					totalRecvRate += recvSpeed
 
 					// Находим MAC-адрес
 					var macAddr string
@@ -847,15 +565,14 @@ func getSystemComponents() ([]HardwareComponent, error) {
 		// Сохраняем текущие значения для следующего расчета
 		prevNetStats = netInterfaces
 		prevNetTime = now
+
+		netComponent.SendRate = totalSendRate
+		netComponent.RecvRate = totalRecvRate
+		netComponent.HasRates = haveRates
 	}
 
-	components = append(components, HardwareComponent{
-		ID:      "network",
-		Name:    "Сетевая активность",
-		Icon:    theme.StorageIcon(),
-		Usage:   0,
-		Details: netDetails,
-	})
+	netComponent.Details = netDetails
+	components = append(components, netComponent)
 
 	// Системная информация
 	sysDetails := map[string]string{
@@ -914,52 +631,20 @@ func getSystemComponents() ([]HardwareComponent, error) {
 	return components, nil
 }
 
-// Остальные функции (createHardwareCard, getMemoryType, parseMemoryModules) остаются без изменений
-
-// Вспомогательная функция для определения типа памяти
-func getMemoryType() string {
-	if runtime.GOOS == "linux" {
-		out, err := exec.Command("dmidecode", "-t", "17").Output()
-		if err == nil {
-			if strings.Contains(string(out), "DDR4") {
-				return "DDR4"
-			} else if strings.Contains(string(out), "DDR3") {
-				return "DDR3"
-			} else if strings.Contains(string(out), "DDR2") {
-				return "DDR2"
-			}
-		}
-	}
-	return "Неизвестно"
-}
-
-// Вспомогательная функция для парсинга информации о модулях памяти
+// Вспомогательная функция для парсинга информации о модулях памяти. Разбор
+// общий с telemetry.CollectInventory, по которому сервер ищет изменения.
 func parseMemoryModules(dmidecodeOutput string) string {
 	var modules []string
-	parts := strings.Split(dmidecodeOutput, "Memory Device")
-
-	for _, part := range parts[1:] {
-		size := "Неизвестно"
-		speed := "Неизвестно"
-		manufacturer := "Неизвестно"
-
-		if strings.Contains(part, "Size:") {
-			sizeLine := strings.Split(strings.Split(part, "Size:")[1], "\n")[0]
-			size = strings.TrimSpace(sizeLine)
-		}
-		if strings.Contains(part, "Speed:") {
-			speedLine := strings.Split(strings.Split(part, "Speed:")[1], "\n")[0]
-			speed = strings.TrimSpace(speedLine)
-		}
-		if strings.Contains(part, "Manufacturer:") {
-			manLine := strings.Split(strings.Split(part, "Manufacturer:")[1], "\n")[0]
-			manufacturer = strings.TrimSpace(manLine)
-		}
-
-		if size != "No Module Installed" {
-			modules = append(modules, fmt.Sprintf("%s %s %s", manufacturer, size, speed))
-		}
+	for _, m := range telemetry.ParseMemoryModules(dmidecodeOutput) {
+		modules = append(modules, fmt.Sprintf("%s %s %s",
+			orUnknown(m.Manufacturer), orUnknown(m.Size), orUnknown(m.Speed)))
 	}
-
 	return strings.Join(modules, "; ")
 }
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "Неизвестно"
+	}
+	return s
+}
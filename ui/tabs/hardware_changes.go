@@ -0,0 +1,133 @@
+package tabs
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// timelineLimit сколько последних изменений показывать
+const timelineLimit = 50
+
+// hardwareChangeTitles подписи событий по компоненту и виду изменения
+var hardwareChangeTitles = map[string]map[string]string{
+	models.ComponentCPU: {
+		models.ChangeChanged: "Заменен процессор",
+	},
+	models.ComponentMemory: {
+		models.ChangeAdded:   "Установлен модуль памяти",
+		models.ChangeRemoved: "Извлечен модуль памяти",
+		models.ChangeChanged: "Изменился объем памяти",
+	},
+	models.ComponentDisk: {
+		models.ChangeAdded:   "Подключен диск",
+		models.ChangeRemoved: "Извлечен диск",
+	},
+	models.ComponentNetwork: {
+		models.ChangeAdded:   "Подключен сетевой адаптер",
+		models.ChangeRemoved: "Отключен сетевой адаптер",
+	},
+}
+
+// hardwareTimeline лента изменений состава оборудования этого компьютера.
+// Изменения находит сервер, сравнивая состав из очередного замера с
+// предыдущим.
+type hardwareTimeline struct {
+	api        *apiclient.Client
+	computerID func() (int, error)
+
+	list   *fyne.Container
+	status *widget.Label
+}
+
+func newHardwareTimeline(api *apiclient.Client, computerID func() (int, error)) *hardwareTimeline {
+	return &hardwareTimeline{
+		api:        api,
+		computerID: computerID,
+		list:       container.NewVBox(),
+		status:     widget.NewLabel(""),
+	}
+}
+
+// Content возвращает заголовок, состояние загрузки и список событий
+func (t *hardwareTimeline) Content() fyne.CanvasObject {
+	title := widget.NewLabelWithStyle("Изменения оборудования", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	return container.NewVBox(title, t.status, t.list)
+}
+
+// Reload загружает изменения с сервера в фоне
+func (t *hardwareTimeline) Reload() {
+	t.status.SetText("Загрузка истории изменений...")
+	go func() {
+		changes, err := t.load()
+		fyne.Do(func() {
+			t.show(changes, err)
+		})
+	}()
+}
+
+func (t *hardwareTimeline) load() ([]models.HardwareChange, error) {
+	id, err := t.computerID()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+	return t.api.HardwareChanges(ctx, id, timelineLimit)
+}
+
+func (t *hardwareTimeline) show(changes []models.HardwareChange, err error) {
+	t.list.Objects = nil
+	switch {
+	case err != nil:
+		t.status.SetText("Не удалось загрузить историю изменений: " + err.Error())
+	case len(changes) == 0:
+		t.status.SetText("Изменений оборудования не обнаружено")
+	default:
+		t.status.SetText(fmt.Sprintf("Последние изменения: %d", len(changes)))
+		for _, change := range changes {
+			t.list.Add(hardwareChangeRow(change))
+		}
+	}
+	t.list.Refresh()
+}
+
+// hardwareChangeRow строка ленты: значок, время и описание события.
+// Извлечение компонентов выделено: это возможная кража.
+func hardwareChangeRow(change models.HardwareChange) fyne.CanvasObject {
+	title := hardwareChangeTitles[change.Component][change.Change]
+	if title == "" {
+		title = change.Component + ": " + change.Change
+	}
+
+	var text string
+	switch {
+	case change.OldValue != "" && change.NewValue != "":
+		text = fmt.Sprintf("%s: %s → %s", title, change.OldValue, change.NewValue)
+	case change.OldValue != "":
+		text = title + ": " + change.OldValue
+	default:
+		text = title + ": " + change.NewValue
+	}
+
+	icon := theme.ViewRefreshIcon()
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+	switch change.Change {
+	case models.ChangeRemoved:
+		icon = theme.WarningIcon()
+		label.Importance = widget.DangerImportance
+	case models.ChangeAdded:
+		icon = theme.ContentAddIcon()
+	}
+
+	when := widget.NewLabel(change.DetectedAt.In(time.Local).Format("02.01.2006 15:04"))
+	return container.NewBorder(nil, nil, container.NewHBox(widget.NewIcon(icon), when), nil, label)
+}
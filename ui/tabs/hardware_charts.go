@@ -0,0 +1,340 @@
+package tabs
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPS/database"
+	"FYNEAPPS/telemetry"
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// liveWindow длина скользящего окна живых графиков
+const liveWindow = 5 * time.Minute
+
+// historyTimeout ограничивает загрузку истории с сервера
+const historyTimeout = 20 * time.Second
+
+// chartRange диапазон, выбираемый над графиками. Step == 0 означает живые
+// данные из памяти, иначе история из БД с усреднением на сервере.
+type chartRange struct {
+	Label  string
+	Window time.Duration
+	Step   time.Duration
+}
+
+var chartRanges = []chartRange{
+	{Label: "Сейчас", Window: liveWindow},
+	{Label: "1 час", Window: time.Hour, Step: time.Minute},
+	{Label: "24 часа", Window: 24 * time.Hour, Step: 5 * time.Minute},
+	{Label: "7 дней", Window: 7 * 24 * time.Hour, Step: 30 * time.Minute},
+}
+
+// Префиксы ключей живых серий; после префикса идет подпись в легенде
+const (
+	seriesCPU     = "cpu:"
+	seriesMemory  = "memory:"
+	seriesDisk    = "disk:"
+	seriesNetwork = "net:"
+)
+
+// liveSamples хранит последние измерения. Буфер переживает пересоздание
+// вкладки, поэтому при возврате на нее график не начинается с нуля.
+var liveSamples = newSampleBuffer(liveWindow)
+
+// sampleBuffer скользящее окно измерений по именованным сериям
+type sampleBuffer struct {
+	mu     sync.Mutex
+	window time.Duration
+	keys   []string
+	points map[string][]ChartPoint
+}
+
+func newSampleBuffer(window time.Duration) *sampleBuffer {
+	return &sampleBuffer{window: window, points: make(map[string][]ChartPoint)}
+}
+
+// Add добавляет значение и отбрасывает точки старше окна
+func (b *sampleBuffer) Add(key string, t time.Time, v float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	points, ok := b.points[key]
+	if !ok {
+		b.keys = append(b.keys, key)
+	}
+	points = append(points, ChartPoint{Time: t, Value: v})
+
+	cutoff := t.Add(-b.window)
+	drop := 0
+	for drop < len(points) && points[drop].Time.Before(cutoff) {
+		drop++
+	}
+	b.points[key] = points[drop:]
+}
+
+// Series возвращает копии серий, ключ которых начинается с prefix
+func (b *sampleBuffer) Series(prefix string) []ChartSeries {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var series []ChartSeries
+	for _, key := range b.keys {
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok || len(b.points[key]) == 0 {
+			continue
+		}
+		series = append(series, ChartSeries{
+			Name:   name,
+			Points: append([]ChartPoint(nil), b.points[key]...),
+		})
+	}
+	return series
+}
+
+// recordLiveSample переносит показания карточек в живые графики
+func recordLiveSample(components []HardwareComponent, now time.Time) {
+	for _, component := range components {
+		switch component.ID {
+		case "cpu":
+			liveSamples.Add(seriesCPU+"ЦП", now, component.Usage)
+		case "memory":
+			liveSamples.Add(seriesMemory+"ОЗУ", now, component.Usage)
+		case "disks":
+			for _, d := range component.Disks {
+				liveSamples.Add(seriesDisk+d.Name, now, d.Usage)
+			}
+		case "network":
+			if component.HasRates {
+				liveSamples.Add(seriesNetwork+"▲ отправка", now, component.SendRate)
+				liveSamples.Add(seriesNetwork+"▼ получение", now, component.RecvRate)
+			}
+		}
+	}
+}
+
+// hardwareCharts графики загрузки под карточками вкладки. Все поля,
+// кроме pool, api и sink, используются только из потока интерфейса.
+type hardwareCharts struct {
+	pool *database.PGConnection
+	api  *apiclient.Client
+	sink *telemetry.DBSink
+
+	cpu     *LineChart
+	memory  *LineChart
+	disks   *LineChart
+	network *LineChart
+	status  *widget.Label
+
+	selected chartRange
+	loadSeq  int
+}
+
+func newHardwareCharts(pool *database.PGConnection, api *apiclient.Client, sink *telemetry.DBSink) *hardwareCharts {
+	return &hardwareCharts{
+		pool:     pool,
+		api:      api,
+		sink:     sink,
+		cpu:      NewLineChart("Загрузка процессора", "%", 100),
+		memory:   NewLineChart("Использование памяти", "%", 100),
+		disks:    NewLineChart("Заполнение дисков", "%", 100),
+		network:  NewLineChart("Сетевой трафик", "MB/s", 0),
+		status:   widget.NewLabel(""),
+		selected: chartRanges[0],
+	}
+}
+
+// Content возвращает переключатель диапазона и сетку графиков
+func (h *hardwareCharts) Content() fyne.CanvasObject {
+	labels := make([]string, len(chartRanges))
+	for i, r := range chartRanges {
+		labels[i] = r.Label
+	}
+	rangeSelect := widget.NewRadioGroup(labels, func(label string) {
+		for _, r := range chartRanges {
+			if r.Label == label {
+				h.selected = r
+			}
+		}
+		h.Reload()
+	})
+	rangeSelect.Horizontal = true
+	rangeSelect.Required = true
+	rangeSelect.SetSelected(h.selected.Label)
+
+	return container.NewVBox(
+		container.NewHBox(layout.NewSpacer(), widget.NewLabel("Период:"), rangeSelect, layout.NewSpacer()),
+		container.NewHBox(layout.NewSpacer(), h.status, layout.NewSpacer()),
+		container.NewGridWithColumns(2,
+			container.NewPadded(h.cpu),
+			container.NewPadded(h.memory),
+			container.NewPadded(h.disks),
+			container.NewPadded(h.network),
+		),
+	)
+}
+
+// Reload перерисовывает графики выбранного диапазона; история загружается
+// заново.
+func (h *hardwareCharts) Reload() {
+	h.loadSeq++
+	if h.selected.Step == 0 {
+		h.status.SetText(fmt.Sprintf("Последние %d минут, обновление каждую секунду", int(liveWindow.Minutes())))
+		h.RefreshLive()
+		return
+	}
+
+	h.status.SetText("Загрузка истории...")
+	go h.loadHistory(h.selected, h.loadSeq)
+}
+
+// RefreshLive обновляет графики из буфера, если выбран живой режим
+func (h *hardwareCharts) RefreshLive() {
+	if h.selected.Step != 0 {
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-liveWindow)
+	// Пропуск больше нескольких тиков - приложение было свернуто или занято
+	gap := 5 * time.Second
+	h.cpu.SetData(liveSamples.Series(seriesCPU), from, to, gap)
+	h.memory.SetData(liveSamples.Series(seriesMemory), from, to, gap)
+	h.disks.SetData(liveSamples.Series(seriesDisk), from, to, gap)
+	h.network.SetData(liveSamples.Series(seriesNetwork), from, to, gap)
+}
+
+// historyMetric метрика API и ее представление на графике
+type historyMetric struct {
+	Name  string
+	Chart *LineChart
+	// Label подпись серии; пустая - по имени диска или адаптера
+	Label string
+	// Sum складывает серии разных устройств в одну (трафик всех адаптеров)
+	Sum bool
+}
+
+func (h *hardwareCharts) loadHistory(r chartRange, seq int) {
+	to := time.Now()
+	from := to.Add(-r.Window)
+
+	metrics := []historyMetric{
+		{Name: "cpu_usage", Chart: h.cpu, Label: "ЦП"},
+		{Name: "memory_usage", Chart: h.memory, Label: "ОЗУ"},
+		{Name: "disk_usage", Chart: h.disks},
+		{Name: "network_upload", Chart: h.network, Label: "▲ отправка", Sum: true},
+		{Name: "network_download", Chart: h.network, Label: "▼ получение", Sum: true},
+	}
+
+	series := make(map[*LineChart][]ChartSeries)
+	err := func() error {
+		id, err := h.computerID()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+		defer cancel()
+		for _, m := range metrics {
+			result, err := h.api.Metrics(ctx, id, m.Name, from, to, r.Step)
+			if err != nil {
+				return err
+			}
+			series[m.Chart] = append(series[m.Chart], bucketsToSeries(result, m)...)
+		}
+		return nil
+	}()
+
+	fyne.Do(func() {
+		// Пользователь уже выбрал другой диапазон
+		if seq != h.loadSeq {
+			return
+		}
+		if err != nil {
+			h.status.SetText("Не удалось загрузить историю: " + err.Error())
+		} else {
+			h.status.SetText(fmt.Sprintf("Средние значения за %s, интервал %s", strings.ToLower(r.Label), r.Step))
+		}
+
+		// Соседние интервалы без данных показываем разрывом линии
+		gap := 2 * r.Step
+		for _, chart := range []*LineChart{h.cpu, h.memory, h.disks, h.network} {
+			chart.SetData(series[chart], from, to, gap)
+		}
+	})
+}
+
+// bucketsToSeries превращает ответ API в серии графика по средним значениям
+func bucketsToSeries(result *models.MetricSeries, m historyMetric) []ChartSeries {
+	var names []string
+	points := make(map[string][]ChartPoint)
+	for _, b := range result.Buckets {
+		name := b.Series
+		if m.Label != "" {
+			name = m.Label
+		}
+		if _, ok := points[name]; !ok {
+			names = append(names, name)
+		}
+		points[name] = append(points[name], ChartPoint{Time: b.Time, Value: b.Avg})
+	}
+
+	series := make([]ChartSeries, 0, len(names))
+	for _, name := range names {
+		p := points[name]
+		if m.Sum {
+			p = sumByTime(p)
+		}
+		series = append(series, ChartSeries{Name: name, Points: p})
+	}
+	return series
+}
+
+// sumByTime складывает точки с одинаковым временем и сортирует по времени
+func sumByTime(points []ChartPoint) []ChartPoint {
+	totals := make(map[time.Time]float64)
+	for _, p := range points {
+		totals[p.Time] += p.Value
+	}
+
+	result := make([]ChartPoint, 0, len(totals))
+	for t, v := range totals {
+		result = append(result, ChartPoint{Time: t, Value: v})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result
+}
+
+// computerID возвращает ID этого компьютера в таблице computers
+func (h *hardwareCharts) computerID() (int, error) {
+	if id := h.sink.ComputerID(); id != 0 {
+		return id, nil
+	}
+	if !h.pool.Healthy() {
+		return 0, errors.New("нет соединения с БД")
+	}
+
+	computer, err := telemetry.CurrentComputer()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	id, err := telemetry.FindComputerID(ctx, h.pool.DB(), computer)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("компьютер еще не сохранялся в БД, включите «Сохранять данные в БД»")
+	}
+	return id, err
+}
@@ -0,0 +1,261 @@
+package tabs
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Отступы области построения графика
+const (
+	chartAxisWidth   = 48
+	chartAxisHeight  = 20
+	chartHeaderSize  = 24
+	chartGridLines   = 4
+	chartMinHeight   = 180
+	chartMinWidth    = 320
+	chartStrokeWidth = 1.5
+)
+
+// chartPalette цвета линий по порядку серий
+var chartPalette = []color.Color{
+	color.NRGBA{R: 0x29, G: 0x79, B: 0xff, A: 0xff},
+	color.NRGBA{R: 0xff, G: 0x8f, B: 0x00, A: 0xff},
+	color.NRGBA{R: 0x43, G: 0xa0, B: 0x47, A: 0xff},
+	color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff},
+	color.NRGBA{R: 0x8e, G: 0x24, B: 0xaa, A: 0xff},
+	color.NRGBA{R: 0x00, G: 0xac, B: 0xc1, A: 0xff},
+}
+
+// ChartPoint одно значение временного ряда
+type ChartPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// ChartSeries линия графика с подписью в легенде
+type ChartSeries struct {
+	Name   string
+	Points []ChartPoint
+}
+
+// LineChart простой линейный график временных рядов на canvas-примитивах.
+// Точки, между которыми прошло больше Gap, не соединяются, чтобы пропуски
+// в данных были видны.
+type LineChart struct {
+	widget.BaseWidget
+
+	title    string
+	unit     string
+	maxValue float64
+
+	series   []ChartSeries
+	from, to time.Time
+	gap      time.Duration
+}
+
+// NewLineChart создает график. maxValue фиксирует верх шкалы (например, 100
+// для процентов); 0 - масштаб подбирается по данным.
+func NewLineChart(title, unit string, maxValue float64) *LineChart {
+	c := &LineChart{title: title, unit: unit, maxValue: maxValue}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetData заменяет данные графика. Вызывается из потока интерфейса.
+func (c *LineChart) SetData(series []ChartSeries, from, to time.Time, gap time.Duration) {
+	c.series = series
+	c.from = from
+	c.to = to
+	c.gap = gap
+	c.Refresh()
+}
+
+func (c *LineChart) CreateRenderer() fyne.WidgetRenderer {
+	r := &lineChartRenderer{chart: c}
+	r.rebuild(c.Size())
+	return r
+}
+
+type lineChartRenderer struct {
+	chart   *LineChart
+	objects []fyne.CanvasObject
+}
+
+func (r *lineChartRenderer) Layout(size fyne.Size) {
+	r.rebuild(size)
+}
+
+func (r *lineChartRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(chartMinWidth, chartMinHeight)
+}
+
+func (r *lineChartRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *lineChartRenderer) Refresh() {
+	r.rebuild(r.chart.Size())
+	canvas.Refresh(r.chart)
+}
+
+func (r *lineChartRenderer) Destroy() {}
+
+// rebuild заново создает все примитивы под текущий размер и данные
+func (r *lineChartRenderer) rebuild(size fyne.Size) {
+	c := r.chart
+	fg := theme.Color(theme.ColorNameForeground)
+	muted := theme.Color(theme.ColorNameDisabled)
+
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameBackground))
+	background.StrokeColor = theme.Color(theme.ColorNameSeparator)
+	background.StrokeWidth = 1
+	background.CornerRadius = 8
+	background.Resize(size)
+	objects := []fyne.CanvasObject{background}
+
+	title := canvas.NewText(c.title, fg)
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	title.Move(fyne.NewPos(8, 4))
+	objects = append(objects, title)
+
+	// Легенда справа от заголовка
+	legendX := title.MinSize().Width + 24
+	for i, s := range c.series {
+		if len(c.series) < 2 {
+			break
+		}
+		label := canvas.NewText("● "+s.Name, chartPalette[i%len(chartPalette)])
+		label.TextSize = theme.CaptionTextSize()
+		label.Move(fyne.NewPos(legendX, 6))
+		legendX += label.MinSize().Width + 12
+		objects = append(objects, label)
+	}
+
+	plotX := float32(chartAxisWidth)
+	plotY := float32(chartHeaderSize)
+	plotW := size.Width - plotX - 12
+	plotH := size.Height - plotY - chartAxisHeight
+	if plotW <= 0 || plotH <= 0 || !c.from.Before(c.to) {
+		r.objects = objects
+		return
+	}
+
+	maxValue := c.maxValue
+	if maxValue <= 0 {
+		maxValue = niceCeil(seriesMax(c.series) * 1.1)
+	}
+
+	// Горизонтальная сетка с подписями значений
+	for i := 0; i <= chartGridLines; i++ {
+		y := plotY + plotH - plotH*float32(i)/chartGridLines
+		line := canvas.NewLine(theme.Color(theme.ColorNameSeparator))
+		line.StrokeWidth = 1
+		line.Position1 = fyne.NewPos(plotX, y)
+		line.Position2 = fyne.NewPos(plotX+plotW, y)
+
+		label := canvas.NewText(formatChartValue(maxValue*float64(i)/chartGridLines, c.unit), muted)
+		label.TextSize = theme.CaptionTextSize()
+		label.Alignment = fyne.TextAlignTrailing
+		label.Move(fyne.NewPos(0, y-label.MinSize().Height/2))
+		label.Resize(fyne.NewSize(plotX-4, label.MinSize().Height))
+
+		objects = append(objects, line, label)
+	}
+
+	// Подписи начала и конца интервала
+	layoutTime := "15:04:05"
+	if c.to.Sub(c.from) > 24*time.Hour {
+		layoutTime = "02.01 15:04"
+	} else if c.to.Sub(c.from) > time.Hour {
+		layoutTime = "15:04"
+	}
+	fromLabel := canvas.NewText(c.from.Format(layoutTime), muted)
+	fromLabel.TextSize = theme.CaptionTextSize()
+	fromLabel.Move(fyne.NewPos(plotX, plotY+plotH+2))
+	toLabel := canvas.NewText(c.to.Format(layoutTime), muted)
+	toLabel.TextSize = theme.CaptionTextSize()
+	toLabel.Move(fyne.NewPos(plotX+plotW-toLabel.MinSize().Width, plotY+plotH+2))
+	objects = append(objects, fromLabel, toLabel)
+
+	span := float64(c.to.Sub(c.from))
+	point := func(p ChartPoint) fyne.Position {
+		x := plotX + plotW*float32(float64(p.Time.Sub(c.from))/span)
+		v := math.Max(0, math.Min(p.Value, maxValue))
+		y := plotY + plotH - plotH*float32(v/maxValue)
+		return fyne.NewPos(x, y)
+	}
+
+	empty := true
+	for i, s := range c.series {
+		stroke := chartPalette[i%len(chartPalette)]
+		for j := 1; j < len(s.Points); j++ {
+			prev, cur := s.Points[j-1], s.Points[j]
+			if prev.Time.Before(c.from) || cur.Time.After(c.to) {
+				continue
+			}
+			if c.gap > 0 && cur.Time.Sub(prev.Time) > c.gap {
+				continue
+			}
+			line := canvas.NewLine(stroke)
+			line.StrokeWidth = chartStrokeWidth
+			line.Position1 = point(prev)
+			line.Position2 = point(cur)
+			objects = append(objects, line)
+			empty = false
+		}
+	}
+
+	if empty {
+		noData := canvas.NewText("Нет данных", muted)
+		noData.Alignment = fyne.TextAlignCenter
+		noData.Move(fyne.NewPos(plotX, plotY+plotH/2-noData.MinSize().Height/2))
+		noData.Resize(fyne.NewSize(plotW, noData.MinSize().Height))
+		objects = append(objects, noData)
+	}
+
+	r.objects = objects
+}
+
+func seriesMax(series []ChartSeries) float64 {
+	var max float64
+	for _, s := range series {
+		for _, p := range s.Points {
+			max = math.Max(max, p.Value)
+		}
+	}
+	return max
+}
+
+// niceCeil округляет верх шкалы до 1, 2 или 5, умноженных на степень десяти
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+func formatChartValue(v float64, unit string) string {
+	switch {
+	case v == 0:
+		return "0"
+	case v < 1:
+		return fmt.Sprintf("%.2f %s", v, unit)
+	case v < 10:
+		return fmt.Sprintf("%.1f %s", v, unit)
+	default:
+		return fmt.Sprintf("%.0f %s", v, unit)
+	}
+}
@@ -0,0 +1,241 @@
+package tabs
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/maintenance"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// maintenanceTimeLayout формат времени разовых окон обслуживания
+const maintenanceTimeLayout = "02.01.2006 15:04"
+
+// Виды окон обслуживания в форме
+const (
+	windowOnce      = "Разовое"
+	windowRecurring = "По расписанию"
+)
+
+// windowApplies сообщает, что окно задано для сервера target или его группы
+func windowApplies(w models.MaintenanceWindow, target models.MonitoringTarget) bool {
+	if w.TargetID != nil {
+		return *w.TargetID == target.TargetID
+	}
+	return target.Group != "" && w.Group == target.Group
+}
+
+// describeWindow описывает окно обслуживания одной строкой
+func describeWindow(w models.MaintenanceWindow) string {
+	scope := "сервер"
+	if w.TargetID == nil {
+		scope = fmt.Sprintf("группа «%s»", w.Group)
+	}
+	when := fmt.Sprintf("по расписанию «%s», %d мин", w.Schedule, w.DurationMin)
+	if w.Schedule == "" && w.StartsAt != nil && w.EndsAt != nil {
+		when = fmt.Sprintf("%s - %s", w.StartsAt.Format(maintenanceTimeLayout), w.EndsAt.Format(maintenanceTimeLayout))
+	}
+	return fmt.Sprintf("%s (%s): %s", when, scope, w.Reason)
+}
+
+// showMaintenanceDialog показывает окна обслуживания сервера target и его
+// группы. canManage позволяет добавлять и удалять окна; onChanged
+// вызывается в потоке интерфейса после каждого изменения.
+func showMaintenanceDialog(ctx context.Context, window fyne.Window, api *apiclient.Client, target models.MonitoringTarget, canManage bool, onChanged func()) {
+	list := container.NewVBox(widget.NewLabel("Загрузка..."))
+
+	var reload func()
+	reload = func() {
+		go func() {
+			windows, err := api.MaintenanceWindows(ctx)
+			fyne.Do(func() {
+				list.RemoveAll()
+				if err != nil {
+					list.Add(widget.NewLabel(fmt.Sprintf("Не удалось загрузить окна обслуживания: %v", err)))
+					return
+				}
+				now := time.Now()
+				for _, w := range windows {
+					if !windowApplies(w, target) {
+						continue
+					}
+					label := widget.NewLabel(describeWindow(w))
+					label.Wrapping = fyne.TextWrapWord
+					if maintenance.Active(w, now) {
+						label.SetText("Идет сейчас: " + label.Text)
+						label.TextStyle = fyne.TextStyle{Bold: true}
+					}
+					if !canManage {
+						list.Add(label)
+						continue
+					}
+					windowID := w.WindowID
+					deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+						dialog.ShowConfirm("Удаление окна", "Удалить окно обслуживания?", func(ok bool) {
+							if !ok {
+								return
+							}
+							go func() {
+								err := api.DeleteMaintenanceWindow(ctx, windowID)
+								fyne.Do(func() {
+									if err != nil {
+										dialog.ShowError(fmt.Errorf("Не удалось удалить окно: %v", err), window)
+										return
+									}
+									reload()
+									onChanged()
+								})
+							}()
+						}, window)
+					})
+					list.Add(container.NewBorder(nil, nil, nil, deleteBtn, label))
+				}
+				if len(list.Objects) == 0 {
+					list.Add(widget.NewLabel("Окон обслуживания нет"))
+				}
+			})
+		}()
+	}
+
+	var bottom fyne.CanvasObject
+	if canManage {
+		bottom = widget.NewButtonWithIcon("Добавить окно", theme.ContentAddIcon(), func() {
+			showMaintenanceForm(ctx, window, api, target, func() {
+				reload()
+				onChanged()
+			})
+		})
+	}
+	hint := widget.NewLabel("Во время окна сбои проверок записываются как обслуживание и не учитываются в доступности")
+	hint.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(hint, bottom, nil, nil, container.NewVScroll(list))
+
+	d := dialog.NewCustom("Обслуживание: "+target.Name, "Закрыть", content, window)
+	d.Resize(fyne.NewSize(640, 420))
+	d.Show()
+	reload()
+}
+
+// showMaintenanceForm открывает форму нового окна обслуживания сервера
+// target или его группы. onSaved вызывается в потоке интерфейса после
+// сохранения.
+func showMaintenanceForm(ctx context.Context, window fyne.Window, api *apiclient.Client, target models.MonitoringTarget, onSaved func()) {
+	scopes := []string{"Этот сервер"}
+	if target.Group != "" {
+		scopes = append(scopes, fmt.Sprintf("Вся группа «%s»", target.Group))
+	}
+	scope := widget.NewRadioGroup(scopes, nil)
+	scope.Required = true
+	scope.SetSelected(scopes[0])
+
+	// По умолчанию окно начинается в следующий час и длится два часа
+	next := time.Now().Truncate(time.Hour).Add(time.Hour)
+	start := widget.NewEntry()
+	start.SetText(next.Format(maintenanceTimeLayout))
+	end := widget.NewEntry()
+	end.SetText(next.Add(2 * time.Hour).Format(maintenanceTimeLayout))
+
+	schedule := widget.NewEntry()
+	schedule.SetPlaceHolder("0 2 * * 3 - по средам в 02:00")
+	duration := widget.NewEntry()
+	duration.SetText("120")
+
+	reason := widget.NewEntry()
+	reason.SetPlaceHolder("Плановое обновление портала")
+	reason.Validator = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("Укажите причину")
+		}
+		return nil
+	}
+
+	kind := widget.NewRadioGroup([]string{windowOnce, windowRecurring}, func(selected string) {
+		if selected == windowOnce {
+			start.Enable()
+			end.Enable()
+			schedule.Disable()
+			duration.Disable()
+		} else {
+			start.Disable()
+			end.Disable()
+			schedule.Enable()
+			duration.Enable()
+		}
+	})
+	kind.Horizontal = true
+	kind.Required = true
+	kind.SetSelected(windowOnce)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Для", scope),
+		widget.NewFormItem("Вид", kind),
+		widget.NewFormItem("Начало", start),
+		widget.NewFormItem("Конец", end),
+		widget.NewFormItem("Расписание (cron)", schedule),
+		widget.NewFormItem("Длительность, мин", duration),
+		widget.NewFormItem("Причина", reason),
+	}
+
+	d := dialog.NewForm("Новое окно обслуживания", "Добавить", "Отмена", items, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		w := models.MaintenanceWindow{Reason: strings.TrimSpace(reason.Text)}
+		if scope.Selected == scopes[0] {
+			targetID := target.TargetID
+			w.TargetID = &targetID
+		} else {
+			w.Group = target.Group
+		}
+
+		if kind.Selected == windowOnce {
+			startsAt, err := time.ParseInLocation(maintenanceTimeLayout, strings.TrimSpace(start.Text), time.Local)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Начало укажите как %s", next.Format(maintenanceTimeLayout)), window)
+				return
+			}
+			endsAt, err := time.ParseInLocation(maintenanceTimeLayout, strings.TrimSpace(end.Text), time.Local)
+			if err != nil || !endsAt.After(startsAt) {
+				dialog.ShowError(fmt.Errorf("Конец должен быть позже начала, например %s", startsAt.Add(2*time.Hour).Format(maintenanceTimeLayout)), window)
+				return
+			}
+			w.StartsAt, w.EndsAt = &startsAt, &endsAt
+		} else {
+			w.Schedule = strings.TrimSpace(schedule.Text)
+			if _, err := maintenance.ParseSchedule(w.Schedule); err != nil {
+				dialog.ShowError(fmt.Errorf("Неверное расписание: %v", err), window)
+				return
+			}
+			minutes, err := strconv.Atoi(strings.TrimSpace(duration.Text))
+			if err != nil || minutes < 1 || minutes > maintenance.MaxDurationMin {
+				dialog.ShowError(fmt.Errorf("Длительность от 1 до %d минут", maintenance.MaxDurationMin), window)
+				return
+			}
+			w.DurationMin = minutes
+		}
+
+		go func() {
+			_, err := api.CreateMaintenanceWindow(ctx, w)
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("Не удалось сохранить окно обслуживания: %v", err), window)
+					return
+				}
+				onSaved()
+			})
+		}()
+	}, window)
+
+	d.Resize(fyne.NewSize(560, 480))
+	d.Show()
+}
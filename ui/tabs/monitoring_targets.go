@@ -0,0 +1,376 @@
+package tabs
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/checks"
+	"FYNEAPPSSERVER/maintenance"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// basicChecks проверки встроенных серверов и новых серверов в форме
+var basicChecks = []string{models.CheckPing, models.CheckHTTP, models.CheckTrace}
+
+// defaultTargets серверы, которые проверяются, пока список с сервера API
+// ни разу не был получен
+var defaultTargets = []models.MonitoringTarget{
+	{Name: "Сайт ПГАТУ", Host: "91.203.238.2", Group: "ПГАТУ", CheckTypes: basicChecks, IntervalSec: 30, Enabled: true},
+	{Name: "Корпоративный Портал ПГАТУ", Host: "91.203.238.4", Group: "ПГАТУ", CheckTypes: basicChecks, IntervalSec: 30, Enabled: true},
+	{Name: "Мой хост", Host: "83.166.245.249", CheckTypes: basicChecks, IntervalSec: 30, Enabled: true},
+}
+
+var (
+	targetsMu sync.Mutex
+	// lastTargets последний список, полученный от сервера API
+	lastTargets []models.MonitoringTarget
+)
+
+// loadTargets возвращает общий список серверов. Если сервер API
+// недоступен, возвращается последний полученный список, а до первого
+// успешного запроса - defaultTargets; ошибка при этом тоже возвращается.
+func loadTargets(ctx context.Context, api *apiclient.Client) ([]models.MonitoringTarget, error) {
+	targets, err := api.MonitoringTargets(ctx)
+
+	targetsMu.Lock()
+	defer targetsMu.Unlock()
+	if err != nil {
+		if lastTargets != nil {
+			return lastTargets, err
+		}
+		return defaultTargets, err
+	}
+	lastTargets = targets
+	return targets, nil
+}
+
+func hasCheck(target models.MonitoringTarget, check string) bool {
+	for _, c := range target.CheckTypes {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// UnreachableServers возвращает имена включенных серверов общего списка,
+// которые недоступны. Если сервер API проверяет серверы сам, берутся его
+// последние проверки; иначе серверы пингуются отсюда, а серверы без
+// проверки ping и серверы в окне обслуживания не учитываются.
+func UnreachableServers(ctx context.Context, api *apiclient.Client) []string {
+	if report, err := api.Status(ctx); err == nil && report.Checker {
+		var down []string
+		for _, status := range report.Targets {
+			if status.Enabled && status.Check != nil && status.Check.Status == models.CheckStatusDown {
+				down = append(down, status.Name)
+			}
+		}
+		sort.Strings(down)
+		return down
+	}
+
+	targets, err := loadTargets(ctx, api)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Не удалось получить список серверов: %v", err)
+	}
+	windows, err := api.MaintenanceWindows(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Не удалось получить окна обслуживания: %v", err)
+	}
+	now := time.Now()
+
+	var (
+		mu   sync.Mutex
+		down []string
+		wg   sync.WaitGroup
+	)
+	for _, target := range targets {
+		if !target.Enabled || !hasCheck(target, models.CheckPing) {
+			continue
+		}
+		if maintenance.Find(windows, target.TargetID, target.Group, now) != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(target models.MonitoringTarget) {
+			defer wg.Done()
+			if result := checks.Ping(ctx, target.Host); !result.OK && ctx.Err() == nil {
+				mu.Lock()
+				down = append(down, target.Name)
+				mu.Unlock()
+			}
+		}(target)
+	}
+	wg.Wait()
+	sort.Strings(down)
+	return down
+}
+
+// checkLabels подписи проверок в форме сервера
+var checkLabels = map[string]string{
+	models.CheckPing:  "Ping",
+	models.CheckHTTP:  "HTTP",
+	models.CheckTCP:   "TCP",
+	models.CheckTLS:   "TLS",
+	models.CheckDNS:   "DNS",
+	models.CheckTrace: "Trace",
+}
+
+// optionalInt проверяет необязательное целое поле формы; пустое поле
+// означает значение по умолчанию
+func optionalInt(min, max int, message string) fyne.StringValidator {
+	return func(s string) error {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil
+		}
+		if n, err := strconv.Atoi(s); err != nil || n < min || n > max {
+			return fmt.Errorf("%s", message)
+		}
+		return nil
+	}
+}
+
+func parseOptionalInt(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+func formatOptionalInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// parsePorts разбирает список портов через запятую или пробел
+func parsePorts(s string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		port, err := strconv.Atoi(field)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("Неверный порт %q", field)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// showTargetDialog открывает форму сервера. target == nil добавляет новый
+// сервер, иначе редактирует переданный. groups предлагаются в поле группы.
+// onSaved вызывается в потоке интерфейса после успешного сохранения.
+func showTargetDialog(ctx context.Context, window fyne.Window, api *apiclient.Client, target *models.MonitoringTarget, groups []string, onSaved func()) {
+	editing := target != nil
+	if !editing {
+		target = &models.MonitoringTarget{CheckTypes: basicChecks, IntervalSec: 30, Enabled: true}
+	}
+
+	name := widget.NewEntry()
+	name.SetText(target.Name)
+	name.Validator = func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("Введите имя сервера")
+		}
+		return nil
+	}
+
+	host := widget.NewEntry()
+	host.SetText(target.Host)
+	host.SetPlaceHolder("example.org или 10.0.0.1")
+	host.Validator = func(s string) error {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return fmt.Errorf("Введите адрес сервера")
+		}
+		if strings.ContainsAny(s, " /") {
+			return fmt.Errorf("Укажите имя хоста или IP-адрес без схемы и пути")
+		}
+		return nil
+	}
+
+	group := widget.NewSelectEntry(groups)
+	group.SetText(target.Group)
+
+	var options []string
+	for _, check := range models.CheckTypes {
+		options = append(options, checkLabels[check])
+	}
+	checkGroup := widget.NewCheckGroup(options, nil)
+	checkGroup.Horizontal = true
+	for _, check := range target.CheckTypes {
+		checkGroup.SetSelected(append(checkGroup.Selected, checkLabels[check]))
+	}
+
+	interval := widget.NewEntry()
+	interval.SetText(strconv.Itoa(target.IntervalSec))
+	interval.Validator = func(s string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 5 || n > 86400 {
+			return fmt.Errorf("Интервал от 5 до 86400 секунд")
+		}
+		return nil
+	}
+
+	enabled := widget.NewCheck("Проверять", nil)
+	enabled.SetChecked(target.Enabled)
+
+	httpURL := widget.NewEntry()
+	httpURL.SetText(target.HTTPURL)
+	httpURL.SetPlaceHolder("http://адрес сервера")
+	httpURL.Validator = func(s string) error {
+		s = strings.TrimSpace(s)
+		if s != "" && !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+			return fmt.Errorf("Адрес должен начинаться с http:// или https://")
+		}
+		return nil
+	}
+	httpStatus := widget.NewEntry()
+	httpStatus.SetText(formatOptionalInt(target.HTTPExpectStatus))
+	httpStatus.SetPlaceHolder("любой код ниже 400")
+	httpStatus.Validator = optionalInt(100, 599, "Код ответа от 100 до 599")
+	httpBody := widget.NewEntry()
+	httpBody.SetText(target.HTTPExpectBody)
+	httpBody.SetPlaceHolder("не проверяется")
+	httpLatency := widget.NewEntry()
+	httpLatency.SetText(formatOptionalInt(target.HTTPMaxLatencyMs))
+	httpLatency.SetPlaceHolder("не ограничено")
+	httpLatency.Validator = optionalInt(1, 60000, "Задержка от 1 до 60000 мс")
+
+	var portList []string
+	for _, port := range target.TCPPorts {
+		portList = append(portList, strconv.Itoa(port))
+	}
+	tcpPorts := widget.NewEntry()
+	tcpPorts.SetText(strings.Join(portList, ", "))
+	tcpPorts.SetPlaceHolder("22, 5432, 10051")
+	tcpPorts.Validator = func(s string) error {
+		_, err := parsePorts(s)
+		return err
+	}
+	tlsPort := widget.NewEntry()
+	tlsPort.SetText(formatOptionalInt(target.TLSPort))
+	tlsPort.SetPlaceHolder(strconv.Itoa(checks.DefaultTLSPort))
+	tlsPort.Validator = optionalInt(1, 65535, "Порт от 1 до 65535")
+	tlsWarn := widget.NewEntry()
+	tlsWarn.SetText(formatOptionalInt(target.TLSWarnDays))
+	tlsWarn.SetPlaceHolder(strconv.Itoa(checks.DefaultTLSWarnDays))
+	tlsWarn.Validator = optionalInt(1, 365, "От 1 до 365 дней")
+	dnsResolver := widget.NewEntry()
+	dnsResolver.SetText(target.DNSResolver)
+	dnsResolver.SetPlaceHolder("системный")
+	dnsResolver.Validator = func(s string) error {
+		if strings.ContainsAny(strings.TrimSpace(s), " /") {
+			return fmt.Errorf("Укажите адрес DNS-сервера, например 10.0.0.1:53")
+		}
+		return nil
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Имя", name),
+		widget.NewFormItem("Адрес", host),
+		widget.NewFormItem("Группа", group),
+		widget.NewFormItem("Проверки", checkGroup),
+		widget.NewFormItem("Интервал, сек", interval),
+		widget.NewFormItem("", enabled),
+		widget.NewFormItem("HTTP: адрес", httpURL),
+		widget.NewFormItem("HTTP: код ответа", httpStatus),
+		widget.NewFormItem("HTTP: текст в ответе", httpBody),
+		widget.NewFormItem("HTTP: задержка, мс", httpLatency),
+		widget.NewFormItem("TCP: порты", tcpPorts),
+		widget.NewFormItem("TLS: порт", tlsPort),
+		widget.NewFormItem("TLS: предупреждать, дней", tlsWarn),
+		widget.NewFormItem("DNS: сервер", dnsResolver),
+	}
+
+	title, confirm := "Новый сервер", "Добавить"
+	if editing {
+		title, confirm = "Изменение сервера", "Сохранить"
+	}
+
+	d := dialog.NewForm(title, confirm, "Отмена", items, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		result := *target
+		result.Name = strings.TrimSpace(name.Text)
+		result.Host = strings.TrimSpace(host.Text)
+		result.Group = strings.TrimSpace(group.Text)
+		result.IntervalSec, _ = strconv.Atoi(strings.TrimSpace(interval.Text))
+		result.Enabled = enabled.Checked
+		result.CheckTypes = nil
+		for _, check := range models.CheckTypes {
+			for _, selected := range checkGroup.Selected {
+				if selected == checkLabels[check] {
+					result.CheckTypes = append(result.CheckTypes, check)
+				}
+			}
+		}
+		if len(result.CheckTypes) == 0 {
+			dialog.ShowError(fmt.Errorf("Выберите хотя бы одну проверку"), window)
+			return
+		}
+		result.HTTPURL = strings.TrimSpace(httpURL.Text)
+		result.HTTPExpectStatus = parseOptionalInt(httpStatus.Text)
+		result.HTTPExpectBody = httpBody.Text
+		result.HTTPMaxLatencyMs = parseOptionalInt(httpLatency.Text)
+		result.TCPPorts, _ = parsePorts(tcpPorts.Text)
+		result.TLSPort = parseOptionalInt(tlsPort.Text)
+		result.TLSWarnDays = parseOptionalInt(tlsWarn.Text)
+		result.DNSResolver = strings.TrimSpace(dnsResolver.Text)
+		if hasCheck(result, models.CheckTCP) && len(result.TCPPorts) == 0 {
+			dialog.ShowError(fmt.Errorf("Для проверки TCP укажите порты"), window)
+			return
+		}
+
+		go func() {
+			var err error
+			if editing {
+				_, err = api.UpdateMonitoringTarget(ctx, result)
+			} else {
+				_, err = api.CreateMonitoringTarget(ctx, result)
+			}
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("Не удалось сохранить сервер: %v", err), window)
+					return
+				}
+				onSaved()
+			})
+		}()
+	}, window)
+
+	d.Resize(fyne.NewSize(560, 640))
+	d.Show()
+}
+
+// confirmDeleteTarget спрашивает подтверждение и удаляет сервер из общего
+// списка
+func confirmDeleteTarget(ctx context.Context, window fyne.Window, api *apiclient.Client, target models.MonitoringTarget, onDeleted func()) {
+	message := fmt.Sprintf("Удалить сервер \"%s\" (%s) из общего списка?", target.Name, target.Host)
+	dialog.ShowConfirm("Удаление сервера", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		go func() {
+			err := api.DeleteMonitoringTarget(ctx, target.TargetID)
+			fyne.Do(func() {
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("Не удалось удалить сервер: %v", err), window)
+					return
+				}
+				onDeleted()
+			})
+		}()
+	}, window)
+}
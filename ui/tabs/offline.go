@@ -0,0 +1,123 @@
+package tabs
+
+import (
+	"FYNEAPPS/database"
+	"FYNEAPPS/spool"
+	"FYNEAPPS/telemetry"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+// Виды записей в очереди на диске
+const (
+	spoolTelemetry = "telemetry"
+	spoolSoftware  = "software"
+	spoolTicket    = "ticket"
+)
+
+// offlineQueue очередь операций, отложенных до восстановления связи с БД.
+// nil, если очередь открыть не удалось: тогда вкладки работают как раньше.
+var offlineQueue *spool.Spool
+
+// SetupSpool подключает очередь на диске к вкладкам и регистрирует
+// обработчики, которые выполняют отложенные операции при ее разборе
+func SetupSpool(queue *spool.Spool, pool *database.PGConnection) {
+	offlineQueue = queue
+
+	var sink *telemetry.DBSink
+	queue.Handle(spoolTelemetry, func(ctx context.Context, data json.RawMessage) error {
+		var batch telemetry.Batch
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return err
+		}
+		db := pool.DB()
+		if db == nil {
+			return errors.New("нет соединения с БД")
+		}
+		if sink == nil {
+			sink = telemetry.NewDBSink(db)
+		}
+		// ID пачки сохранен в очереди, поэтому повтор не создаст дублей
+		return sink.Send(ctx, &batch)
+	})
+
+	queue.Handle(spoolSoftware, func(ctx context.Context, data json.RawMessage) error {
+		var software []SystemSoftware
+		if err := json.Unmarshal(data, &software); err != nil {
+			return err
+		}
+		return saveToPostgreSQL(pool.DB(), software)
+	})
+
+	queue.Handle(spoolTicket, func(ctx context.Context, data json.RawMessage) error {
+		var ticket Ticket
+		if err := json.Unmarshal(data, &ticket); err != nil {
+			return err
+		}
+		db := pool.DB()
+		if db == nil {
+			return errors.New("нет соединения с БД")
+		}
+		return addTicket(db, ticket)
+	})
+}
+
+// SpoolPendingTelemetry переносит неотправленные замеры из памяти в очередь
+// на диске, например перед выходом из приложения
+func SpoolPendingTelemetry() {
+	if offlineQueue == nil {
+		return
+	}
+	for _, batch := range dbBuffer.Drain() {
+		if err := offlineQueue.Enqueue(spoolTelemetry, batch); err != nil {
+			log.Printf("Не удалось поставить замеры в очередь, потеряно замеров: %d: %v", len(batch.Snapshots), err)
+		}
+	}
+}
+
+// saveSoftware сохраняет список ПО, а без связи с БД откладывает его в очередь
+func saveSoftware(pool *database.PGConnection, software []SystemSoftware) {
+	if pool.Healthy() {
+		err := saveToPostgreSQL(pool.DB(), software)
+		if err == nil {
+			return
+		}
+		log.Printf("Ошибка сохранения ПО в БД: %v", err)
+		if offlineQueue == nil || pool.IsConnected() {
+			// БД доступна, значит повтор не поможет
+			return
+		}
+	}
+
+	if offlineQueue == nil {
+		return
+	}
+	if err := offlineQueue.Enqueue(spoolSoftware, software); err != nil {
+		log.Printf("Не удалось поставить список ПО в очередь: %v", err)
+	}
+}
+
+// createTicket добавляет тикет. Без связи с БД тикет ставится в очередь и
+// будет создан позже; queued сообщает, что так и произошло.
+func createTicket(pool *database.PGConnection, ticket Ticket) (queued bool, err error) {
+	if pool.Healthy() {
+		err = addTicket(pool.DB(), ticket)
+		if err == nil || offlineQueue == nil || pool.IsConnected() {
+			return false, err
+		}
+	} else if offlineQueue == nil {
+		return false, errors.New("нет соединения с БД")
+	}
+
+	if ticket.CreatedAt == nil {
+		now := time.Now()
+		ticket.CreatedAt = &now
+	}
+	if err := offlineQueue.Enqueue(spoolTicket, ticket); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package tabs
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showServerHistory показывает доступность сервера за 24 часа, 7 и 30
+// дней, график задержки за сутки и простои за 30 дней
+func showServerHistory(ctx context.Context, window fyne.Window, api *apiclient.Client, target models.MonitoringTarget) {
+	uptimeLabel := widget.NewLabel("Загрузка...")
+	chart := NewLineChart("Задержка за сутки", "ms", 0)
+	incidentsBox := container.NewVBox()
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabelWithStyle("Доступность", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			uptimeLabel,
+			chart,
+			widget.NewLabelWithStyle("Простои за 30 дней", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		),
+		nil, nil, nil,
+		container.NewVScroll(incidentsBox),
+	)
+
+	d := dialog.NewCustom(fmt.Sprintf("История: %s (%s)", target.Name, target.Host), "Закрыть", content, window)
+	d.Resize(fyne.NewSize(680, 560))
+	d.Show()
+
+	go func() {
+		report, err := api.Uptime(ctx, target.TargetID)
+		fyne.Do(func() {
+			if err != nil {
+				uptimeLabel.SetText(fmt.Sprintf("Не удалось получить историю: %v", err))
+				return
+			}
+
+			uptimeLabel.SetText(fmt.Sprintf("24 часа: %s    7 дней: %s    30 дней: %s",
+				formatUptime(report.Uptime24h), formatUptime(report.Uptime7d), formatUptime(report.Uptime30d)))
+
+			step := time.Duration(report.LatencyStepSec) * time.Second
+			points := make([]ChartPoint, 0, len(report.Latency))
+			for _, p := range report.Latency {
+				points = append(points, ChartPoint{Time: p.Time, Value: p.AvgMs})
+			}
+			to := time.Now()
+			chart.SetData([]ChartSeries{{Name: "Задержка", Points: points}}, to.Add(-24*time.Hour), to, 2*step)
+
+			if len(report.Incidents) == 0 {
+				incidentsBox.Add(widget.NewLabel("Простоев не было"))
+				return
+			}
+			for _, incident := range report.Incidents {
+				incidentsBox.Add(widget.NewLabel(formatIncident(incident)))
+			}
+		})
+	}()
+}
+
+func formatUptime(percent *float64) string {
+	if percent == nil {
+		return "нет данных"
+	}
+	return fmt.Sprintf("%.2f%%", *percent)
+}
+
+// formatIncident описывает простой одной строкой, например
+// "02.10.2026 14:05 - 14:40 (35 мин), проверок: 70"
+func formatIncident(incident models.Incident) string {
+	start := incident.Start.Format("02.01.2006 15:04")
+	duration := formatOutage(time.Duration(incident.DurationSec) * time.Second)
+	if incident.End == nil {
+		return fmt.Sprintf("%s - продолжается (%s), проверок: %d", start, duration, incident.Checks)
+	}
+
+	end := incident.End.Format("15:04")
+	if incident.End.YearDay() != incident.Start.YearDay() || incident.End.Year() != incident.Start.Year() {
+		end = incident.End.Format("02.01.2006 15:04")
+	}
+	return fmt.Sprintf("%s - %s (%s), проверок: %d", start, end, duration, incident.Checks)
+}
+
+func formatOutage(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%d сек", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%d мин", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d ч %d мин", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%d дн %d ч", int(d.Hours())/24, int(d.Hours())%24)
+}
@@ -1,32 +1,109 @@
 package tabs
 
 import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPSSERVER/api/models"
+	"FYNEAPPSSERVER/auth"
+	"FYNEAPPSSERVER/checks"
+	"FYNEAPPSSERVER/maintenance"
 	"context"
 	"fmt"
-	"net/http"
-	"os/exec"
-	"runtime"
+	"log"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
+// notChecked значение столбца проверки, которая для сервера не выбрана
+const notChecked = "—"
+
+// statusColumns проверки в порядке столбцов таблицы
+var statusColumns = []string{models.CheckPing, models.CheckHTTP, models.CheckTCP, models.CheckTLS, models.CheckDNS, models.CheckTrace}
+
+// statusLabels статусы таблицы для статусов проверок сервера API
+var statusLabels = map[string]string{
+	models.CheckStatusUp:          "Online",
+	models.CheckStatusDegraded:    "Degraded",
+	models.CheckStatusDown:        "Offline",
+	models.CheckStatusMaintenance: "Maintenance",
+}
+
+// Источники результатов в таблице: проверки сервера API или проверки с
+// этого компьютера
+const (
+	sourceServer = "Сервер API"
+	sourceLocal  = "Этот компьютер"
+)
+
+// streamRetry пауза перед повторным подключением к потоку проверок
+const streamRetry = 15 * time.Second
+
 type ServerInfo struct {
+	Target  models.MonitoringTarget
 	Name    string
 	Host    string
+	Group   string
 	Status  string
-	Ping    string
-	HTTP    string
-	Trace   string
 	Updated string
+	// Results результаты последней проверки по видам проверок
+	Results map[string]models.CheckResult
+	// Maintenance окно обслуживания, идущее на момент последней проверки
+	Maintenance *models.MaintenanceWindow
+
+	// nextCheck время следующей проверки при автообновлении
+	nextCheck time.Time
+	checking  bool
+}
+
+// cell возвращает текст столбца проверки check
+func (s ServerInfo) cell(check string) string {
+	if !s.Target.Enabled || !hasCheck(s.Target, check) {
+		return notChecked
+	}
+	if result, ok := s.Results[check]; ok {
+		return result.Summary
+	}
+	return "N/A"
+}
+
+// applyCheck показывает проверку, выполненную или записанную сервером API
+func (s *ServerInfo) applyCheck(check models.ServerCheck) {
+	s.Results = make(map[string]models.CheckResult, len(check.Results))
+	for _, result := range check.Results {
+		s.Results[result.Check] = result
+	}
+	s.Status = statusLabels[check.Status]
+	s.Updated = check.CheckedAt.Format("15:04:05")
+}
+
+// details описывает результаты последней проверки для панели под таблицей
+func (s ServerInfo) details() string {
+	lines := []string{fmt.Sprintf("%s (%s): %s", s.Name, s.Host, s.Status)}
+	for _, check := range statusColumns {
+		result, ok := s.Results[check]
+		if !ok {
+			continue
+		}
+		detail := strings.ReplaceAll(result.Detail, "\n", "; ")
+		if detail == "" {
+			detail = result.Summary
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", checkLabels[check], detail))
+	}
+	if s.Maintenance != nil {
+		lines = append(lines, "Обслуживание: "+s.Maintenance.Reason)
+	}
+	return strings.Join(lines, "\n")
 }
 
 type ServerStatusTab struct {
@@ -36,38 +113,68 @@ type ServerStatusTab struct {
 	searchEntry      *widget.Entry
 	autoRefreshCheck *widget.Check
 	sortSelect       *widget.Select
+	sourceSelect     *widget.RadioGroup
+	sourceLabel      *widget.Label
+	detailsLabel     *widget.Label
 	ctx              context.Context
 	cancel           context.CancelFunc
-	refreshMutex     sync.Mutex
 	wg               sync.WaitGroup
+
+	// generation растет при каждой загрузке списка; результаты проверок,
+	// начатых до загрузки, отбрасываются
+	generation int
+	// selectedKey targetKey сервера, выбранного в таблице
+	selectedKey string
+	// serverChecks установлен, если сервер API сам проверяет серверы; тогда
+	// проверки с этого компьютера не записываются в историю
+	serverChecks bool
+	// windows окна обслуживания всех серверов
+	windows []models.MaintenanceWindow
+	// recordFailing не дает повторять в журнале ошибку сохранения истории
+	recordFailing atomic.Bool
+}
+
+func newServerInfo(target models.MonitoringTarget) ServerInfo {
+	server := ServerInfo{
+		Target: target,
+		Name:   target.Name,
+		Host:   target.Host,
+		Group:  target.Group,
+		Status: "Checking...",
+	}
+	if !target.Enabled {
+		server.Status = "Disabled"
+	}
+	return server
 }
 
-func CreateServerStatusTab(window fyne.Window) fyne.CanvasObject {
+// NewServerStatusTab строит таблицу доступности серверов из общего списка
+// на сервере API. Если сервер API проверяет серверы сам, по умолчанию
+// показываются его результаты, которые обновляются по потоку проверок.
+// Иначе, или если выбран этот компьютер, каждый сервер проверяется отсюда
+// со своим интервалом, пока вкладка видна и включено автообновление;
+// отмена ctx прерывает и начатые проверки. Роли с правом targets:manage
+// могут править список.
+func NewServerStatusTab(ctx context.Context, window fyne.Window, api *apiclient.Client, role auth.Role) Tab {
 	title := canvas.NewText("Доступность серверов ПГАТУ", theme.ForegroundColor())
 	title.TextSize = 24
 	title.Alignment = fyne.TextAlignCenter
 	title.TextStyle = fyne.TextStyle{Bold: true}
 
-	initialServers := []ServerInfo{
-		{"Сайт ПГАТУ", "91.203.238.2", "Checking...", "N/A", "N/A", "N/A", ""},
-		{"Корпоративный Портал ПГАТУ", "91.203.238.4", "Checking...", "N/A", "N/A", "N/A", ""},
-		{"Мой хост", "83.166.245.249", "Checking...", "N/A", "N/A", "N/A", ""},
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 
 	tab := &ServerStatusTab{
-		servers:         initialServers,
-		filteredServers: make([]ServerInfo, len(initialServers)),
-		ctx:             ctx,
-		cancel:          cancel,
+		ctx:          ctx,
+		cancel:       cancel,
+		sourceLabel:  widget.NewLabel("Загрузка списка серверов..."),
+		detailsLabel: widget.NewLabel("Выберите сервер, чтобы увидеть подробности проверок"),
 	}
-	copy(tab.filteredServers, initialServers)
+	tab.detailsLabel.Wrapping = fyne.TextWrapWord
 
-	columnWidths := []float32{200, 120, 100, 80, 80, 150, 120}
+	columnWidths := []float32{180, 120, 120, 90, 70, 100, 100, 80, 70, 70, 90}
 
 	headerRow := container.NewHBox()
-	headers := []string{"Имя сервера", "Адрес", "Статус", "Ping", "HTTP", "Trace", "Обновлено"}
+	headers := []string{"Имя сервера", "Группа", "Адрес", "Статус", "Ping", "HTTP", "TCP", "TLS", "DNS", "Trace", "Обновлено"}
 
 	for _, header := range headers {
 		label := widget.NewLabel(header)
@@ -91,28 +198,27 @@ func CreateServerStatusTab(window fyne.Window) fyne.CanvasObject {
 			}
 
 			server := tab.filteredServers[id.Row]
-			switch id.Col {
-			case 0:
+			switch {
+			case id.Col == 0:
 				label.SetText("  " + server.Name)
 				resetLabelStyle(label)
-			case 1:
+			case id.Col == 1:
+				label.SetText("  " + server.Group)
+				resetLabelStyle(label)
+			case id.Col == 2:
 				label.SetText("  " + server.Host)
 				resetLabelStyle(label)
-			case 2:
+			case id.Col == 3:
 				label.SetText("  " + server.Status)
 				updateStatusStyle(label, server.Status)
-			case 3:
-				label.SetText("  " + server.Ping)
-				resetLabelStyle(label)
-			case 4:
-				label.SetText("  " + server.HTTP)
-				updateHTTPStatusStyle(label, server.HTTP)
-			case 5:
-				label.SetText("  " + server.Trace)
-				updateTraceStyle(label, server.Trace)
-			case 6:
+			case id.Col == len(columnWidths)-1:
 				label.SetText("  " + server.Updated)
 				resetLabelStyle(label)
+			default:
+				check := statusColumns[id.Col-4]
+				label.SetText("  " + server.cell(check))
+				result, ok := server.Results[check]
+				updateResultStyle(label, result, ok)
 			}
 		},
 	)
@@ -122,203 +228,431 @@ func CreateServerStatusTab(window fyne.Window) fyne.CanvasObject {
 	}
 
 	tab.searchEntry = widget.NewEntry()
-	tab.searchEntry.SetPlaceHolder("Поиск по имени или адресу...")
+	tab.searchEntry.SetPlaceHolder("Поиск по имени, группе или адресу...")
 
 	refreshBtn := widget.NewButtonWithIcon("Обновить", theme.ViewRefreshIcon(), nil)
-	tab.autoRefreshCheck = widget.NewCheck("Автообновление (30 сек)", nil)
+	tab.autoRefreshCheck = widget.NewCheck("Автообновление", nil)
 	tab.autoRefreshCheck.SetChecked(false)
-	tab.sortSelect = widget.NewSelect([]string{"Имя", "Статус", "Ping", "HTTP", "Trace", "Обновлено"}, nil)
-	tab.sortSelect.SetSelected("Имя")
+	tab.sortSelect = widget.NewSelect([]string{"Группа", "Имя", "Статус", "Ping", "HTTP", "TLS", "Trace", "Обновлено"}, nil)
+	tab.sortSelect.SetSelected("Группа")
+	tab.sourceSelect = widget.NewRadioGroup([]string{sourceServer, sourceLocal}, nil)
+	tab.sourceSelect.Horizontal = true
+	tab.sourceSelect.Required = true
+	// remote сообщает, что в таблице результаты сервера API
+	remote := func() bool { return tab.sourceSelect.Selected == sourceServer }
+	// Результаты сервера API обновляются потоком, автообновление нужно
+	// только для проверок с этого компьютера
+	updateControls := func() {
+		if remote() {
+			tab.autoRefreshCheck.Disable()
+		} else {
+			tab.autoRefreshCheck.Enable()
+		}
+	}
 
+	// filterServers и sortServers вызываются в потоке интерфейса
 	filterServers := func(search string) {
-		fyne.Do(func() {
-			if search == "" {
-				tab.filteredServers = make([]ServerInfo, len(tab.servers))
-				copy(tab.filteredServers, tab.servers)
-				return
-			}
+		search = strings.ToLower(search)
+		if search == "" {
+			tab.filteredServers = make([]ServerInfo, len(tab.servers))
+			copy(tab.filteredServers, tab.servers)
+			return
+		}
 
-			var filtered []ServerInfo
-			for _, s := range tab.servers {
-				if strings.Contains(strings.ToLower(s.Name), strings.ToLower(search)) ||
-					strings.Contains(strings.ToLower(s.Host), strings.ToLower(search)) {
-					filtered = append(filtered, s)
-				}
+		var filtered []ServerInfo
+		for _, s := range tab.servers {
+			if strings.Contains(strings.ToLower(s.Name), search) ||
+				strings.Contains(strings.ToLower(s.Group), search) ||
+				strings.Contains(strings.ToLower(s.Host), search) {
+				filtered = append(filtered, s)
 			}
-			tab.filteredServers = filtered
-		})
+		}
+		tab.filteredServers = filtered
 	}
 
 	sortServers := func(sortBy string) {
-		fyne.Do(func() {
-			switch sortBy {
-			case "Имя":
-				sort.Slice(tab.filteredServers, func(i, j int) bool {
-					return tab.filteredServers[i].Name < tab.filteredServers[j].Name
-				})
-			case "Статус":
-				sort.Slice(tab.filteredServers, func(i, j int) bool {
-					return tab.filteredServers[i].Status < tab.filteredServers[j].Status
-				})
-			case "Ping":
-				sort.Slice(tab.filteredServers, func(i, j int) bool {
-					return extractNumericValue(tab.filteredServers[i].Ping) < extractNumericValue(tab.filteredServers[j].Ping)
-				})
-			case "HTTP":
-				sort.Slice(tab.filteredServers, func(i, j int) bool {
-					return extractNumericValue(tab.filteredServers[i].HTTP) < extractNumericValue(tab.filteredServers[j].HTTP)
-				})
-			case "Trace":
-				sort.Slice(tab.filteredServers, func(i, j int) bool {
-					return extractNumericValue(tab.filteredServers[i].Trace) < extractNumericValue(tab.filteredServers[j].Trace)
-				})
-			case "Обновлено":
-				sort.Slice(tab.filteredServers, func(i, j int) bool {
-					return tab.filteredServers[i].Updated > tab.filteredServers[j].Updated
-				})
+		servers := tab.filteredServers
+		switch sortBy {
+		case "Группа":
+			sort.SliceStable(servers, func(i, j int) bool {
+				if servers[i].Group != servers[j].Group {
+					return servers[i].Group < servers[j].Group
+				}
+				return servers[i].Name < servers[j].Name
+			})
+		case "Имя":
+			sort.SliceStable(servers, func(i, j int) bool {
+				return servers[i].Name < servers[j].Name
+			})
+		case "Статус":
+			sort.SliceStable(servers, func(i, j int) bool {
+				return servers[i].Status < servers[j].Status
+			})
+		case "Ping", "HTTP", "TLS", "Trace":
+			check := map[string]string{
+				"Ping":  models.CheckPing,
+				"HTTP":  models.CheckHTTP,
+				"TLS":   models.CheckTLS,
+				"Trace": models.CheckTrace,
+			}[sortBy]
+			sort.SliceStable(servers, func(i, j int) bool {
+				return resultSortValue(servers[i], check) < resultSortValue(servers[j], check)
+			})
+		case "Обновлено":
+			sort.SliceStable(servers, func(i, j int) bool {
+				return servers[i].Updated > servers[j].Updated
+			})
+		}
+	}
+
+	showDetails := func() {
+		for _, s := range tab.servers {
+			if tab.selectedKey != "" && targetKey(s.Target) == tab.selectedKey {
+				tab.detailsLabel.SetText(s.details())
+				return
 			}
-		})
+		}
+		tab.detailsLabel.SetText("Выберите сервер, чтобы увидеть подробности проверок")
 	}
 
-	updateServers := func() {
-		tab.refreshMutex.Lock()
-		defer tab.refreshMutex.Unlock()
+	refreshTable := func() {
+		filterServers(tab.searchEntry.Text)
+		sortServers(tab.sortSelect.Selected)
+		tab.serverTable.Refresh()
+		showDetails()
+	}
 
-		select {
-		case <-tab.ctx.Done():
+	// checkServers запускает проверку серверов, для которых due возвращает
+	// true. Вызывается в потоке интерфейса; уже проверяемые и отключенные
+	// серверы пропускаются.
+	checkServers := func(due func(*ServerInfo) bool) {
+		if tab.ctx.Err() != nil || remote() {
 			return
-		default:
 		}
-
-		fyne.Do(func() {
-			for i := range tab.servers {
-				tab.servers[i].Status = "Checking..."
-				tab.servers[i].Ping = "N/A"
-				tab.servers[i].HTTP = "N/A"
-				tab.servers[i].Trace = "N/A"
-				tab.servers[i].Updated = time.Now().Format("15:04:05")
+		generation := tab.generation
+		record := !tab.serverChecks && role.Can(auth.PermTelemetryWrite)
+		for i := range tab.servers {
+			server := &tab.servers[i]
+			if server.checking || !server.Target.Enabled || !due(server) {
+				continue
 			}
-			filterServers(tab.searchEntry.Text)
-			tab.serverTable.Refresh()
-		})
+			server.checking = true
+			server.Status = "Checking..."
+			target := server.Target
 
-		var wg sync.WaitGroup
-		for i := range tab.servers {
-			wg.Add(1)
 			tab.wg.Add(1)
-
 			go func(index int) {
-				defer wg.Done()
 				defer tab.wg.Done()
+				results := checkTarget(tab.ctx, target)
+				if target.TargetID != 0 && tab.ctx.Err() == nil && record {
+					tab.recordCheck(api, checks.Summarize(target, results))
+				}
+				fyne.Do(func() {
+					if tab.ctx.Err() != nil || tab.generation != generation {
+						return
+					}
+					server := &tab.servers[index]
+					server.Results = results
+					server.Status = targetStatus(target, results)
+					server.Maintenance = tab.activeWindow(target)
+					if server.Maintenance != nil && server.Status != "Online" {
+						server.Status = "Maintenance"
+					}
+					server.Updated = time.Now().Format("15:04:05")
+					server.nextCheck = time.Now().Add(time.Duration(target.IntervalSec) * time.Second)
+					server.checking = false
+					refreshTable()
+				})
+			}(i)
+		}
+		refreshTable()
+	}
 
-				select {
-				case <-tab.ctx.Done():
+	// reloadTargets загружает общий список и последние проверки сервера
+	// API. Пока источник не выбран, выбираются проверки сервера API, если
+	// он проверяет серверы сам. Для проверок с этого компьютера результаты
+	// прошлых проверок сохраняются для серверов с теми же адресом и
+	// настройками проверок, новые серверы проверяются сразу.
+	reloadTargets := func() {
+		tab.wg.Add(1)
+		go func() {
+			defer tab.wg.Done()
+			targets, err := loadTargets(tab.ctx, api)
+			var report *models.StatusReport
+			var windows []models.MaintenanceWindow
+			if err == nil {
+				report, err = api.Status(tab.ctx)
+			}
+			if err == nil {
+				windows, err = api.MaintenanceWindows(tab.ctx)
+			}
+			fyne.Do(func() {
+				if tab.ctx.Err() != nil {
 					return
+				}
+				if report != nil {
+					tab.serverChecks = report.Checker
+				}
+				if err == nil {
+					tab.windows = windows
+				}
+				if tab.sourceSelect.Selected == "" {
+					tab.sourceSelect.Selected = sourceLocal
+					if tab.serverChecks {
+						tab.sourceSelect.Selected = sourceServer
+					}
+					tab.sourceSelect.Refresh()
+					updateControls()
+				}
+				switch {
+				case err != nil:
+					tab.sourceLabel.SetText("Сервер API недоступен, показан сохраненный список")
+				case remote() && tab.serverChecks:
+					tab.sourceLabel.SetText(fmt.Sprintf("Серверов в общем списке: %d, проверяет сервер API", len(targets)))
+				case remote():
+					tab.sourceLabel.SetText(fmt.Sprintf("Серверов в общем списке: %d, показаны проверки, записанные клиентами", len(targets)))
 				default:
-					host := tab.servers[index].Host
-					now := time.Now().Format("15:04:05")
-
-					pingOnline, pingTime := pingHost(tab.ctx, host)
-					httpStatus := checkHTTP(tab.ctx, host)
-					traceResult := traceHost(tab.ctx, host)
+					tab.sourceLabel.SetText(fmt.Sprintf("Серверов в общем списке: %d, проверяет этот компьютер", len(targets)))
+				}
 
-					fyne.Do(func() {
-						select {
-						case <-tab.ctx.Done():
-							return
-						default:
-							if pingOnline {
-								tab.servers[index].Status = "Online"
-								tab.servers[index].Ping = fmt.Sprintf("%d ms", pingTime)
-							} else {
-								tab.servers[index].Status = "Offline"
-								tab.servers[index].Ping = "Timeout"
-							}
-							tab.servers[index].HTTP = httpStatus
-							tab.servers[index].Trace = traceResult
-							tab.servers[index].Updated = now
-
-							filterServers(tab.searchEntry.Text)
-							sortServers(tab.sortSelect.Selected)
-							tab.serverTable.Refresh()
+				if remote() {
+					latest := make(map[int]*models.ServerCheck)
+					if report != nil {
+						for _, status := range report.Targets {
+							latest[status.TargetID] = status.Check
 						}
-					})
+					}
+					servers := make([]ServerInfo, 0, len(targets))
+					for _, target := range targets {
+						server := newServerInfo(target)
+						if check := latest[target.TargetID]; check != nil && target.Enabled {
+							server.applyCheck(*check)
+							server.Maintenance = tab.activeWindow(target)
+						} else if target.Enabled {
+							server.Status = "N/A"
+						}
+						servers = append(servers, server)
+					}
+					tab.servers = servers
+					tab.generation++
+					refreshTable()
+					return
 				}
-			}(i)
+
+				previous := make(map[string]ServerInfo, len(tab.servers))
+				for _, s := range tab.servers {
+					previous[targetKey(s.Target)] = s
+				}
+				servers := make([]ServerInfo, 0, len(targets))
+				for _, target := range targets {
+					server := newServerInfo(target)
+					if old, ok := previous[targetKey(target)]; ok && target.Enabled && !old.checking && old.Updated != "" {
+						server.Status, server.Results = old.Status, old.Results
+						server.Updated, server.nextCheck = old.Updated, old.nextCheck
+					}
+					servers = append(servers, server)
+				}
+				tab.servers = servers
+				tab.generation++
+
+				checkServers(func(s *ServerInfo) bool { return s.Updated == "" })
+			})
+		}()
+	}
+
+	// applyCheck показывает проверку из потока сервера API
+	applyCheck := func(check models.ServerCheck) {
+		if tab.ctx.Err() != nil || !remote() {
+			return
+		}
+		for i := range tab.servers {
+			if tab.servers[i].Target.TargetID == check.TargetID && tab.servers[i].Target.Enabled {
+				tab.servers[i].applyCheck(check)
+				tab.servers[i].Maintenance = tab.activeWindow(tab.servers[i].Target)
+				refreshTable()
+				return
+			}
 		}
-		wg.Wait()
 	}
 
-	refreshBtn.OnTapped = func() { go updateServers() }
-	tab.searchEntry.OnChanged = func(s string) {
-		filterServers(s)
-		sortServers(tab.sortSelect.Selected)
-		fyne.Do(func() {
-			tab.serverTable.Refresh()
-		})
+	refreshBtn.OnTapped = func() {
+		if remote() {
+			reloadTargets()
+			return
+		}
+		checkServers(func(*ServerInfo) bool { return true })
+	}
+	// При смене источника результаты другого источника сбрасываются
+	tab.sourceSelect.OnChanged = func(string) {
+		for i := range tab.servers {
+			tab.servers[i] = newServerInfo(tab.servers[i].Target)
+		}
+		tab.generation++
+		updateControls()
+		refreshTable()
+		reloadTargets()
+	}
+	tab.searchEntry.OnChanged = func(string) {
+		refreshTable()
 	}
 	tab.autoRefreshCheck.OnChanged = func(checked bool) {
 		if checked {
-			go updateServers()
+			checkServers(func(s *ServerInfo) bool { return !time.Now().Before(s.nextCheck) })
 		}
 	}
 	tab.sortSelect.OnChanged = func(string) {
-		sortServers(tab.sortSelect.Selected)
-		fyne.Do(func() {
-			tab.serverTable.Refresh()
-		})
+		refreshTable()
+	}
+
+	tab.serverTable.OnSelected = func(id widget.TableCellID) {
+		if id.Row < len(tab.filteredServers) {
+			tab.selectedKey = targetKey(tab.filteredServers[id.Row].Target)
+			showDetails()
+		}
 	}
 
-	tab.wg.Add(1)
-	go func() {
-		defer tab.wg.Done()
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				if tab.autoRefreshCheck.Checked {
-					go updateServers()
+	// При каждом показе список перечитывается, чтобы были видны правки
+	// коллег. Проверки сервера API приходят по потоку; после обрыва поток
+	// переподключается, а пропущенное перечитывается. При автообновлении
+	// проверок с этого компьютера сервер проверяется, когда истек его
+	// интервал.
+	autoRefresh := func(ctx context.Context) {
+		reloadTargets()
+		tab.wg.Add(1)
+		go func() {
+			defer tab.wg.Done()
+			logged := false
+			for {
+				err := api.StreamStatus(ctx, func(check models.ServerCheck) {
+					fyne.Do(func() {
+						if ctx.Err() == nil {
+							applyCheck(check)
+						}
+					})
+				})
+				if ctx.Err() != nil {
+					return
+				}
+				if !logged {
+					log.Printf("Поток проверок серверов прерван: %v", err)
+					logged = true
+				}
+				select {
+				case <-time.After(streamRetry):
+					fyne.Do(reloadTargets)
+				case <-ctx.Done():
+					return
 				}
-			case <-tab.ctx.Done():
-				return
+			}
+		}()
+		tab.wg.Add(1)
+		go func() {
+			defer tab.wg.Done()
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					fyne.Do(func() {
+						if ctx.Err() == nil && tab.autoRefreshCheck.Checked {
+							checkServers(func(s *ServerInfo) bool { return !time.Now().Before(s.nextCheck) })
+						}
+					})
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	toolbar := container.NewHBox(
+		widget.NewLabel("Проверки:"),
+		tab.sourceSelect,
+		widget.NewLabel("Сортировка:"),
+		tab.sortSelect,
+		tab.autoRefreshCheck,
+		refreshBtn,
+	)
+
+	// selected возвращает выбранный в таблице сервер общего списка;
+	// встроенные серверы, показанные без связи с API, не имеют истории и не
+	// редактируются
+	selected := func() (models.MonitoringTarget, bool) {
+		for _, s := range tab.servers {
+			if s.Target.TargetID != 0 && targetKey(s.Target) == tab.selectedKey {
+				return s.Target, true
 			}
 		}
-	}()
-
-	tab.wg.Add(1)
-	go func() {
-		defer tab.wg.Done()
-		select {
-		case <-time.After(500 * time.Millisecond):
-			updateServers()
-		case <-tab.ctx.Done():
-			return
+		dialog.ShowInformation("Серверы", "Выберите сервер общего списка в таблице", window)
+		return models.MonitoringTarget{}, false
+	}
+
+	historyBtn := widget.NewButtonWithIcon("История", theme.HistoryIcon(), func() {
+		if target, ok := selected(); ok {
+			showServerHistory(tab.ctx, window, api, target)
 		}
-	}()
+	})
+	toolbar.Add(historyBtn)
 
-	window.SetOnClosed(func() {
-		tab.Close()
+	maintenanceBtn := widget.NewButtonWithIcon("Обслуживание", theme.SettingsIcon(), func() {
+		if target, ok := selected(); ok {
+			showMaintenanceDialog(tab.ctx, window, api, target, role.Can(auth.PermTargetsManage), reloadTargets)
+		}
 	})
+	toolbar.Add(maintenanceBtn)
+
+	if role.Can(auth.PermTargetsManage) {
+		groups := func() []string {
+			seen := map[string]bool{}
+			var groups []string
+			for _, s := range tab.servers {
+				if s.Group != "" && !seen[s.Group] {
+					seen[s.Group] = true
+					groups = append(groups, s.Group)
+				}
+			}
+			sort.Strings(groups)
+			return groups
+		}
+		addBtn := widget.NewButtonWithIcon("Добавить", theme.ContentAddIcon(), func() {
+			showTargetDialog(tab.ctx, window, api, nil, groups(), reloadTargets)
+		})
+		editBtn := widget.NewButtonWithIcon("Изменить", theme.DocumentCreateIcon(), func() {
+			if target, ok := selected(); ok {
+				showTargetDialog(tab.ctx, window, api, &target, groups(), reloadTargets)
+			}
+		})
+		deleteBtn := widget.NewButtonWithIcon("Удалить", theme.DeleteIcon(), func() {
+			if target, ok := selected(); ok {
+				confirmDeleteTarget(tab.ctx, window, api, target, func() {
+					tab.selectedKey = ""
+					tab.serverTable.UnselectAll()
+					reloadTargets()
+				})
+			}
+		})
+		toolbar.Add(widget.NewSeparator())
+		toolbar.Add(addBtn)
+		toolbar.Add(editBtn)
+		toolbar.Add(deleteBtn)
+	}
 
-	return container.NewBorder(
+	content := container.NewBorder(
 		container.NewVBox(
 			title,
 			widget.NewSeparator(),
 			container.NewBorder(
 				nil, nil,
 				widget.NewLabel("Поиск:"),
-				container.NewHBox(
-					widget.NewLabel("Сортировка:"),
-					tab.sortSelect,
-					tab.autoRefreshCheck,
-					refreshBtn,
-				),
+				toolbar,
 				tab.searchEntry,
 			),
 		),
-		nil,
+		container.NewVBox(
+			widget.NewSeparator(),
+			tab.detailsLabel,
+			tab.sourceLabel,
+		),
 		nil,
 		nil,
 		container.NewBorder(
@@ -332,126 +666,77 @@ func CreateServerStatusTab(window fyne.Window) fyne.CanvasObject {
 			container.NewScroll(tab.serverTable),
 		),
 	)
+
+	polling := newPollingTab(ctx, content, autoRefresh)
+	polling.onClose = tab.Close
+	return polling
 }
 
 func (tab *ServerStatusTab) Close() {
 	tab.cancel()
 	tab.wg.Wait()
-	tab.refreshMutex.Lock()
-	defer tab.refreshMutex.Unlock()
 }
 
-func pingHost(ctx context.Context, host string) (bool, int) {
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	var cmd *exec.Cmd
-	var pingTime int
-
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.CommandContext(ctx, "ping", "-n", "1", "-w", "1000", host)
-	case "linux", "darwin":
-		cmd = exec.CommandContext(ctx, "ping", "-c", "1", "-W", "1", host)
-	default:
-		return false, 0
-	}
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false, 0
-	}
+// activeWindow возвращает окно обслуживания сервера target или его группы,
+// идущее сейчас, или nil
+func (tab *ServerStatusTab) activeWindow(target models.MonitoringTarget) *models.MaintenanceWindow {
+	return maintenance.Find(tab.windows, target.TargetID, target.Group, time.Now())
+}
 
-	outputStr := strings.ToLower(string(output))
-	switch runtime.GOOS {
-	case "windows":
-		if strings.Contains(outputStr, "ttl=") {
-			timeIndex := strings.Index(outputStr, "time=")
-			if timeIndex != -1 {
-				timeStr := outputStr[timeIndex+5:]
-				endIndex := strings.Index(timeStr, "ms")
-				if endIndex != -1 {
-					timeStr = timeStr[:endIndex]
-					if t, err := strconv.Atoi(timeStr); err == nil {
-						pingTime = t
-					}
-				}
-			}
-			return true, pingTime
-		}
-	case "linux", "darwin":
-		if strings.Contains(outputStr, "1 packets received") || strings.Contains(outputStr, "1 received") {
-			timeIndex := strings.Index(outputStr, "time=")
-			if timeIndex != -1 {
-				timeStr := outputStr[timeIndex+5:]
-				endIndex := strings.Index(timeStr, " ms")
-				if endIndex != -1 {
-					timeStr = timeStr[:endIndex]
-					if t, err := strconv.ParseFloat(timeStr, 64); err == nil {
-						pingTime = int(t)
-					}
-				}
-			}
-			return true, pingTime
+// recordCheck сохраняет проверку в истории на сервере API. Пока сервер
+// недоступен, ошибка пишется в журнал только один раз.
+func (tab *ServerStatusTab) recordCheck(api *apiclient.Client, check models.ServerCheck) {
+	err := api.RecordServerCheck(tab.ctx, check)
+	switch {
+	case err == nil:
+		if tab.recordFailing.Swap(false) {
+			log.Println("История проверок серверов снова сохраняется")
 		}
+	case tab.ctx.Err() != nil:
+	case !tab.recordFailing.Swap(true):
+		log.Printf("Не удалось сохранить проверку сервера: %v", err)
 	}
-
-	return false, 0
 }
 
-func checkHTTP(ctx context.Context, host string) string {
-	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
-		host = "http://" + host
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", host, nil)
-	if err != nil {
-		return "No HTTP"
-	}
-
-	client := http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "No HTTP"
-	}
-	defer resp.Body.Close()
-
-	return fmt.Sprintf("HTTP %d", resp.StatusCode)
+// targetKey определяет сервер в таблице и то, можно ли перенести результат
+// его проверки в перечитанный список: ключ меняется вместе с адресом и
+// настройками проверок
+func targetKey(target models.MonitoringTarget) string {
+	target.Name, target.Group = "", ""
+	target.IntervalSec, target.Enabled = 0, false
+	target.CreatedAt = time.Time{}
+	return fmt.Sprintf("%+v", target)
 }
 
-func traceHost(ctx context.Context, host string) string {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	var cmd *exec.Cmd
+// checkTarget выполняет выбранные для сервера проверки параллельно
+func checkTarget(ctx context.Context, target models.MonitoringTarget) map[string]models.CheckResult {
+	return checks.Run(ctx, target, target.CheckTypes)
+}
 
-	switch runtime.GOOS {
-	case "windows":
-		cmd = exec.CommandContext(ctx, "tracert", "-d", "-h", "5", host)
-	case "linux", "darwin":
-		cmd = exec.CommandContext(ctx, "traceroute", "-m", "5", host)
-	default:
-		return "N/A"
-	}
+// targetStatus сводит результаты в статус сервера по правилам сервера API
+// (см. checks.Summarize)
+func targetStatus(target models.MonitoringTarget, results map[string]models.CheckResult) string {
+	return statusLabels[checks.Summarize(target, results).Status]
+}
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "Trace err"
+// resultSortValue ключ сортировки по проверке: задержка, для TLS - дни до
+// истечения сертификата, для трассировки - число прыжков. Непройденные и
+// невыполненные проверки оказываются в конце.
+func resultSortValue(server ServerInfo, check string) int {
+	result, ok := server.Results[check]
+	if !ok || !result.OK {
+		return 1 << 30
 	}
-
-	lines := strings.Split(string(output), "\n")
-	if len(lines) > 1 {
-		return fmt.Sprintf("%d hops", len(lines)-2)
+	switch check {
+	case models.CheckTLS:
+		return extractNumericValue(result.Summary)
+	case models.CheckTrace:
+		return len(result.Hops)
 	}
-
-	return "Trace err"
+	return result.LatencyMs
 }
 
 func extractNumericValue(s string) int {
-	if s == "N/A" || s == "Timeout" || s == "No HTTP" || s == "Trace err" {
-		return 9999
-	}
-
 	parts := strings.Fields(s)
 	if len(parts) > 0 {
 		if num, err := strconv.Atoi(parts[0]); err == nil {
@@ -471,6 +756,12 @@ func updateStatusStyle(label *widget.Label, status string) {
 		case "Offline":
 			label.Importance = widget.DangerImportance
 			label.TextStyle = fyne.TextStyle{Bold: true}
+		case "Maintenance":
+			label.Importance = widget.HighImportance
+			label.TextStyle = fyne.TextStyle{Italic: true}
+		case "Disabled":
+			label.Importance = widget.LowImportance
+			label.TextStyle = fyne.TextStyle{}
 		default:
 			label.Importance = widget.WarningImportance
 			label.TextStyle = fyne.TextStyle{Bold: false}
@@ -479,30 +770,18 @@ func updateStatusStyle(label *widget.Label, status string) {
 	})
 }
 
-func updateHTTPStatusStyle(label *widget.Label, status string) {
+// updateResultStyle выделяет ячейку проверки цветом по ее результату
+func updateResultStyle(label *widget.Label, result models.CheckResult, ok bool) {
 	fyne.Do(func() {
-		if strings.HasPrefix(status, "HTTP 2") || strings.HasPrefix(status, "HTTP 3") {
-			label.Importance = widget.SuccessImportance
-		} else if strings.HasPrefix(status, "HTTP 4") || strings.HasPrefix(status, "HTTP 5") {
-			label.Importance = widget.DangerImportance
-		} else if status == "No HTTP" {
-			label.Importance = widget.WarningImportance
-		} else {
+		switch {
+		case !ok:
 			label.Importance = widget.MediumImportance
-		}
-		label.Refresh()
-	})
-}
-
-func updateTraceStyle(label *widget.Label, trace string) {
-	fyne.Do(func() {
-		if strings.Contains(trace, "hops") {
+		case result.OK:
 			label.Importance = widget.SuccessImportance
-		} else if trace == "Trace err" {
+		default:
 			label.Importance = widget.DangerImportance
-		} else {
-			label.Importance = widget.MediumImportance
 		}
+		label.TextStyle = fyne.TextStyle{}
 		label.Refresh()
 	})
 }
@@ -1,6 +1,8 @@
 package tabs
 
 import (
+	"FYNEAPPS/database"
+	"FYNEAPPS/telemetry"
 	"context"
 	"database/sql"
 	"encoding/json"
@@ -37,7 +39,9 @@ var (
 	cacheExpiry     = 5 * time.Minute
 )
 
-func CreateSoftwareTab(window fyne.Window) fyne.CanvasObject {
+// NewSoftwareTab строит список установленных программ. Он обновляется раз
+// в 30 секунд, пока вкладка видна.
+func NewSoftwareTab(ctx context.Context, window fyne.Window, pool *database.PGConnection) Tab {
 	title := canvas.NewText("Программы на компьютере (Загружает дольше обычного)", theme.ForegroundColor())
 	title.TextSize = 24
 	title.Alignment = fyne.TextAlignCenter
@@ -102,9 +106,6 @@ func CreateSoftwareTab(window fyne.Window) fyne.CanvasObject {
 	sortSelect := widget.NewSelect([]string{"Название", "Издатель", "Версия", "Дата установки"}, nil)
 	sortSelect.SetSelected("Название")
 
-	// Канал для остановки автообновления
-	stopChan := make(chan struct{})
-
 	// Функция получения данных с кэшированием
 	getCachedSoftware := func() ([]SystemSoftware, error) {
 		softwareCacheMu.Lock()
@@ -121,6 +122,9 @@ func CreateSoftwareTab(window fyne.Window) fyne.CanvasObject {
 
 		softwareCache = software
 		lastUpdateTime = time.Now()
+
+		// Свежий список сохраняем в общий пул в фоне, без связи - в очередь
+		go saveSoftware(pool, software)
 		return software, nil
 	}
 
@@ -171,20 +175,24 @@ func CreateSoftwareTab(window fyne.Window) fyne.CanvasObject {
 		})
 	}
 
-	// Автообновление (раз в 30 секунд)
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				updateSoftware()
-			case <-stopChan:
-				return
+	// Автообновление (раз в 30 секунд) на время показа вкладки; при показе
+	// список сразу перечитывается из кэша
+	autoRefresh := func(ctx context.Context) {
+		go func() {
+			ticker := time.NewTicker(30 * time.Second)
+			defer ticker.Stop()
+
+			updateSoftware()
+			for {
+				select {
+				case <-ticker.C:
+					updateSoftware()
+				case <-ctx.Done():
+					return
+				}
 			}
-		}
-	}()
+		}()
+	}
 
 	// Обработчики событий
 	searchEntry.OnChanged = func(s string) { go updateSoftware() }
@@ -196,14 +204,6 @@ func CreateSoftwareTab(window fyne.Window) fyne.CanvasObject {
 	}
 	sortSelect.OnChanged = func(s string) { go updateSoftware() }
 
-	// Первоначальное обновление
-	go updateSoftware()
-
-	// Очистка при закрытии
-	window.SetOnClosed(func() {
-		close(stopChan)
-	})
-
 	// Компоновка элементов управления (как во вкладке процессов)
 	controls := container.NewBorder(
 		nil, nil, nil, nil,
@@ -241,7 +241,7 @@ func CreateSoftwareTab(window fyne.Window) fyne.CanvasObject {
 		),
 	)
 
-	return mainContent
+	return newPollingTab(ctx, mainContent, autoRefresh)
 }
 
 func getInstalledSoftware() ([]SystemSoftware, error) {
@@ -572,26 +572,28 @@ func saveToJSONWithSwappedFields(softwareList []SystemSoftware) error {
 	return nil
 }
 
-func saveToPostgreSQL(softwareList []SystemSoftware) error {
+func saveToPostgreSQL(db *sql.DB, softwareList []SystemSoftware) error {
 	hostName, err := os.Hostname()
 	if err != nil {
 		return fmt.Errorf("не удалось получить hostname: %v", err)
 	}
 
-	connString := "user=user dbname=grafana_db password=user host=83.166.245.249 port=5432 sslmode=disable"
-	db, err := sql.Open("postgres", connString)
-	if err != nil {
-		return fmt.Errorf("ошибка подключения: %v", err)
+	if db == nil {
+		return fmt.Errorf("нет соединения с БД")
 	}
-	defer db.Close()
 
-	// Упрощаем настройки соединения
-	db.SetMaxOpenConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	// Записи привязаны к ID компьютера, а не к имени хоста, поэтому
+	// переименование не теряет список ПО
+	regCtx, regCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	computerID, err := telemetry.RegisterCurrentComputer(regCtx, db)
+	regCancel()
+	if err != nil {
+		return err
+	}
 
 	// Получаем существующие записи одним запросом
 	existingRecords := make(map[string]bool)
-	rows, err := db.Query("SELECT name, version, publisher FROM software_on_computer WHERE host_name = $1", hostName)
+	rows, err := db.Query("SELECT COALESCE(name, ''), COALESCE(version, ''), COALESCE(publisher, '') FROM software_on_computer WHERE computer_id = $1", computerID)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("ошибка получения существующих записей: %v", err)
 	}
@@ -609,8 +611,8 @@ func saveToPostgreSQL(softwareList []SystemSoftware) error {
 	// Подготовка запроса на вставку
 	stmt, err := db.Prepare(`
         INSERT INTO software_on_computer 
-        (name, version, publisher, installed, metadata, host_name)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        (name, version, publisher, installed, metadata, host_name, computer_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
     `)
 	if err != nil {
 		return fmt.Errorf("ошибка подготовки запроса: %v", err)
@@ -650,10 +652,11 @@ func saveToPostgreSQL(softwareList []SystemSoftware) error {
 			nullIfEmpty(s.Installed),
 			metadata,
 			hostName,
+			computerID,
 		)
 
 		if err != nil {
-			return fmt.Errorf("ошибка вставки %s: %v", s.Name, err)
+			return fmt.Errorf("ошибка вставки %s: %w", s.Name, err)
 		}
 
 		inserted++
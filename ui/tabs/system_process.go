@@ -1,6 +1,7 @@
 package tabs
 
 import (
+	"context"
 	"fmt"
 	"image/color"
 	"log"
@@ -27,7 +28,9 @@ type ProcessInfo struct {
 	Command string
 }
 
-func CreateProcessesTab(window fyne.Window) fyne.CanvasObject {
+// NewProcessesTab строит список процессов. Он обновляется раз в секунду,
+// пока вкладка видна.
+func NewProcessesTab(ctx context.Context, window fyne.Window) Tab {
 	title := canvas.NewText("Процессы компьютера", theme.ForegroundColor())
 	title.TextSize = 24
 	title.Alignment = fyne.TextAlignCenter
@@ -105,9 +108,6 @@ func CreateProcessesTab(window fyne.Window) fyne.CanvasObject {
 	sortSelect := widget.NewSelect([]string{"CPU", "Память", "PID", "Имя"}, nil)
 	sortSelect.SetSelected("CPU")
 
-	// Канал для остановки автообновления
-	stopChan := make(chan struct{})
-
 	// Функция обновления списка процессов
 	updateProcesses := func() {
 		processes, err := getSystemProcesses()
@@ -152,34 +152,30 @@ func CreateProcessesTab(window fyne.Window) fyne.CanvasObject {
 		})
 	}
 
-	// Автообновление
-	go func() {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				updateProcesses()
-			case <-stopChan:
-				return
+	// Автообновление на время показа вкладки; при показе список сразу
+	// перечитывается
+	autoRefresh := func(ctx context.Context) {
+		go func() {
+			ticker := time.NewTicker(1 * time.Second)
+			defer ticker.Stop()
+
+			updateProcesses()
+			for {
+				select {
+				case <-ticker.C:
+					updateProcesses()
+				case <-ctx.Done():
+					return
+				}
 			}
-		}
-	}()
+		}()
+	}
 
 	// Обработчики событий
 	searchEntry.OnChanged = func(s string) { go updateProcesses() }
 	refreshBtn.OnTapped = func() { go updateProcesses() }
 	sortSelect.OnChanged = func(s string) { go updateProcesses() }
 
-	// Первоначальное обновление
-	go updateProcesses()
-
-	// Очистка при закрытии
-	window.SetOnClosed(func() {
-		close(stopChan)
-	})
-
 	// Компоновка элементов управления
 	controls := container.NewBorder(
 		nil, nil, nil, nil,
@@ -218,7 +214,7 @@ func CreateProcessesTab(window fyne.Window) fyne.CanvasObject {
 		),
 	)
 
-	return mainContent
+	return newPollingTab(ctx, mainContent, autoRefresh)
 }
 
 func getSystemProcesses() ([]ProcessInfo, error) {
@@ -0,0 +1,94 @@
+package tabs
+
+import (
+	"context"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// Tab вкладка главного окна. Экземпляр создается при первом открытии и
+// живет до закрытия окна: OnShow и OnHide вызываются при переключении
+// вкладок, Close - при закрытии окна. Пока вкладка скрыта, ее опросы не
+// выполняются.
+type Tab interface {
+	Content() fyne.CanvasObject
+	OnShow()
+	OnHide()
+	Close()
+}
+
+// StaticTab вкладка без фоновой работы
+func StaticTab(content fyne.CanvasObject) Tab {
+	return staticTab{content: content}
+}
+
+type staticTab struct {
+	content fyne.CanvasObject
+}
+
+func (t staticTab) Content() fyne.CanvasObject { return t.content }
+func (t staticTab) OnShow()                    {}
+func (t staticTab) OnHide()                    {}
+func (t staticTab) Close()                     {}
+
+// pollingTab запускает опросы вкладки на время ее показа. start вызывается
+// при каждом показе с контекстом, который отменяется при скрытии вкладки,
+// закрытии или отмене родительского контекста; горутины, запущенные в
+// start, должны завершаться по нему.
+type pollingTab struct {
+	parent  context.Context
+	content fyne.CanvasObject
+	start   func(ctx context.Context)
+	onClose func()
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	closed bool
+}
+
+func newPollingTab(parent context.Context, content fyne.CanvasObject, start func(ctx context.Context)) *pollingTab {
+	return &pollingTab{parent: parent, content: content, start: start}
+}
+
+func (t *pollingTab) Content() fyne.CanvasObject {
+	return t.content
+}
+
+func (t *pollingTab) OnShow() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed || t.cancel != nil || t.parent.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.parent)
+	t.cancel = cancel
+	t.start(ctx)
+}
+
+func (t *pollingTab) OnHide() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+		t.cancel = nil
+	}
+}
+
+// Close останавливает опросы и вызывает onClose; повторный вызов ничего не
+// делает
+func (t *pollingTab) Close() {
+	t.OnHide()
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	if t.onClose != nil {
+		t.onClose()
+	}
+}
@@ -1,6 +1,8 @@
 package tabs
 
 import (
+	"FYNEAPPS/database"
+	"FYNEAPPSSERVER/auth"
 	"context"
 	"database/sql"
 	"fmt"
@@ -17,7 +19,7 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Ticket struct {
@@ -31,6 +33,8 @@ type Ticket struct {
 	Cabinet      int
 	CreatedAt    *time.Time
 	UpdatedAt    *time.Time
+	// Assignee логин исполнителя; пустой, пока тикет не назначен
+	Assignee string
 }
 
 type TicketsTab struct {
@@ -49,6 +53,8 @@ type TicketsTab struct {
 	ticketsList    *widget.List
 	split          *container.Split
 	statusSelect   *widget.Select
+	assigneeSelect *widget.Select
+	role           auth.Role
 }
 
 var (
@@ -62,6 +68,9 @@ var (
 	}
 )
 
+// noAssignee пункт списка исполнителей, снимающий назначение
+const noAssignee = "Не назначен"
+
 func showCustomDialog(window fyne.Window, title, message string, icon fyne.Resource) {
 	fyne.Do(func() {
 		dialog.ShowCustom(
@@ -108,45 +117,28 @@ func getStatusColor(statusName string) color.Color {
 	}
 }
 
-// Инициализация базы данных
-func initDBT() (*sql.DB, error) {
-	connStr := "user=user dbname=grafana_db password=user host=83.166.245.249 port=5432 sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, err
-	}
-
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := db.PingContext(ctx); err != nil {
-		return nil, err
-	}
-
-	return db, nil
-}
-
-func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
+// NewTicketsTab строит вкладку тикетов. Действия, не разрешенные роли
+// пользователя, скрываются: наблюдатель может только просматривать тикеты.
+// Список перечитывается раз в 5 секунд, пока вкладка видна. Без соединения
+// с БД возвращается ошибка.
+func NewTicketsTab(ctx context.Context, window fyne.Window, pool *database.PGConnection, role auth.Role) (Tab, error) {
 	tab := &TicketsTab{
 		window:         window,
+		role:           role,
 		refreshChan:    make(chan struct{}, 1),
 		running:        true,
 		sortField:      "created_at",
 		sortDescending: true,
 	}
 
-	// В функции CreateTicketsTab:
-	db, err := initDBT()
-	if err != nil {
-		showCustomDialog(window, "Ошибка", "Ошибка подключения к базе данных: "+err.Error(), theme.ErrorIcon())
-		return widget.NewLabel("Ошибка подключения к БД"), func() {}
+	// Пул общий для всего приложения, поэтому вкладка его не закрывает
+	db := pool.DB()
+	if db == nil {
+		return nil, fmt.Errorf("нет соединения с базой данных")
 	}
 	tab.db = db
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	tab.cancelFunc = cancel
 
 	title := canvas.NewText("Управление тикетами", theme.ForegroundColor())
@@ -192,10 +184,7 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 	}
 
 	// Устанавливаем заранее заданное значение
-	hn, err := os.Hostname()
-	// if err != nil {
-	// 	return nil
-	// }
+	hn, _ := os.Hostname()
 	computerName.SetText(hn)
 
 	// Блокируем поле для ввода
@@ -256,6 +245,8 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 				widget.NewLabel(""),
 				widget.NewLabelWithStyle("Обновлен:", fyne.TextAlignLeading, fyne.TextStyle{}),
 				widget.NewLabel(""),
+				widget.NewLabelWithStyle("Исполнитель:", fyne.TextAlignLeading, fyne.TextStyle{}),
+				widget.NewLabel(""),
 			)
 
 			content := container.NewVBox(
@@ -313,6 +304,12 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 				updatedAtLabel.SetText("не обновлялся")
 			}
 
+			assignee := tab.ticketsCache[i].Assignee
+			if assignee == "" {
+				assignee = "не назначен"
+			}
+			metaContainer.Objects[13].(*widget.Label).SetText(assignee)
+
 			border := stack.Objects[2].(*canvas.Rectangle)
 			if i == lastSelectedID {
 				border.StrokeColor = theme.PrimaryColor()
@@ -346,7 +343,43 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		}
 
 		showCustomDialog(window, "Успех", "Статус тикета успешно обновлен", theme.ConfirmIcon())
-		tab.refreshChan <- struct{}{}
+		tab.requestRefresh()
+	})
+
+	// Исполнителями могут быть пользователи, которым разрешено вести тикеты.
+	// Список читается при открытии вкладки.
+	assigneeOptions := []string{noAssignee}
+	if assignees, err := getAssignees(tab.db); err != nil {
+		log.Printf("Ошибка получения списка исполнителей: %v", err)
+	} else {
+		assigneeOptions = append(assigneeOptions, assignees...)
+	}
+	assigneeSelect := widget.NewSelect(assigneeOptions, nil)
+	assigneeSelect.PlaceHolder = "Выберите исполнителя"
+	tab.assigneeSelect = assigneeSelect
+
+	assignBtn := widget.NewButtonWithIcon("Назначить", theme.AccountIcon(), func() {
+		if selectedTicketID == -1 {
+			showCustomDialog(window, "Ошибка", "Выберите тикет для назначения", theme.WarningIcon())
+			return
+		}
+
+		if assigneeSelect.Selected == "" {
+			showCustomDialog(window, "Ошибка", "Выберите исполнителя", theme.WarningIcon())
+			return
+		}
+
+		assignee := assigneeSelect.Selected
+		if assignee == noAssignee {
+			assignee = ""
+		}
+		if err := assignTicket(tab.db, selectedTicketID, assignee); err != nil {
+			showCustomDialog(window, "Ошибка", "Не удалось назначить исполнителя: "+err.Error(), theme.ErrorIcon())
+			return
+		}
+
+		showCustomDialog(window, "Успех", "Исполнитель тикета успешно назначен", theme.ConfirmIcon())
+		tab.requestRefresh()
 	})
 
 	ticketsList.OnSelected = func(id widget.ListItemID) {
@@ -364,6 +397,7 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 			computerName.SetText("")
 			cabinet.SetText("")
 			statusSelect.SetSelected("")
+			assigneeSelect.SetSelected("")
 			return
 		}
 
@@ -377,6 +411,11 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 			computerName.SetText(tab.ticketsCache[id].ComputerName)
 			cabinet.SetText(fmt.Sprintf("%d", tab.ticketsCache[id].Cabinet))
 			statusSelect.SetSelected(tab.ticketsCache[id].StatusName)
+			if tab.ticketsCache[id].Assignee != "" {
+				assigneeSelect.SetSelected(tab.ticketsCache[id].Assignee)
+			} else {
+				assigneeSelect.SetSelected(noAssignee)
+			}
 		}
 		fyne.Do(func() {
 			ticketsList.Refresh()
@@ -418,6 +457,7 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		computerName.SetText(hn)
 		cabinet.SetText("")
 		statusSelect.SetSelected("")
+		assigneeSelect.SetSelected("")
 	})
 
 	createBtn := widget.NewButtonWithIcon("Создать", theme.ContentAddIcon(), func() {
@@ -448,7 +488,8 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 			UpdatedAt:    nil,
 		}
 
-		if err := addTicket(tab.db, ticket); err != nil {
+		queued, err := createTicket(pool, ticket)
+		if err != nil {
 			showCustomDialog(window, "Ошибка", "Не удалось создать тикет: "+err.Error(), theme.ErrorIcon())
 			return
 		}
@@ -458,8 +499,12 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		userID.SetText("")
 		computerName.SetText("")
 		cabinet.SetText("")
+		if queued {
+			showCustomDialog(window, "Нет связи с БД", "Тикет сохранен на этом компьютере и будет создан автоматически, когда связь восстановится", theme.InfoIcon())
+			return
+		}
 		showCustomDialog(window, "Успех", "Тикет успешно создан", theme.ConfirmIcon())
-		tab.refreshChan <- struct{}{}
+		tab.requestRefresh()
 	})
 
 	updateBtn := widget.NewButtonWithIcon("Обновить", theme.ViewRefreshIcon(), func() {
@@ -488,10 +533,14 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		}
 
 		showCustomDialog(window, "Успех", "Тикет успешно обновлен", theme.ConfirmIcon())
-		tab.refreshChan <- struct{}{}
+		tab.requestRefresh()
 	})
 
 	deleteBtn := widget.NewButtonWithIcon("Удалить", theme.DeleteIcon(), func() {
+		if !tab.role.Can(auth.PermTicketsDelete) {
+			showCustomDialog(window, "Ошибка", "Недостаточно прав для удаления тикета", theme.WarningIcon())
+			return
+		}
 		if selectedTicketID == -1 {
 			showCustomDialog(window, "Ошибка", "Выберите тикет для удаления", theme.WarningIcon())
 			return
@@ -521,7 +570,7 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 					selectedTicketID = -1
 					lastSelectedID = -1
 					showCustomDialog(window, "Успех", "Тикет успешно удален", theme.ConfirmIcon())
-					tab.refreshChan <- struct{}{}
+					tab.requestRefresh()
 				}
 			},
 		)
@@ -543,7 +592,7 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		case "Дата обновления":
 			tab.sortField = "update_at"
 		}
-		tab.refreshChan <- struct{}{}
+		tab.requestRefresh()
 	})
 	sortSelect.SetSelected("Дата создания")
 
@@ -557,7 +606,7 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 			sortDirectionBtn.SetText("По возрастанию")
 		}
 
-		tab.refreshChan <- struct{}{}
+		tab.requestRefresh()
 	})
 
 	sortContainer := container.NewHBox(
@@ -566,26 +615,50 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		sortDirectionBtn,
 	)
 
-	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-
-		refreshList()
-
-		for {
-			select {
-			case <-ticker.C:
-				refreshList()
-			case <-tab.refreshChan:
-				refreshList()
-			case <-ctx.Done():
-				return
+	// Запросы обновления, пришедшие при скрытой вкладке, ждут в refreshChan
+	// и выполняются при показе
+	autoRefresh := func(ctx context.Context) {
+		go func() {
+			ticker := time.NewTicker(5 * time.Second)
+			defer ticker.Stop()
+
+			refreshList()
+
+			for {
+				select {
+				case <-ticker.C:
+					refreshList()
+				case <-tab.refreshChan:
+					refreshList()
+				case <-ctx.Done():
+					return
+				}
 			}
-		}
-	}()
+		}()
+	}
+
+	formTitle := "Создать/редактировать тикет"
+
+	// Скрываем действия, недоступные роли пользователя
+	if !role.Can(auth.PermTicketsWrite) {
+		formTitle = "Просмотр тикета"
+		createBtn.Hide()
+		updateBtn.Hide()
+		updateStatusBtn.Hide()
+		statusSelect.Disable()
+		assignBtn.Hide()
+		assigneeSelect.Disable()
+		ticketTitle.Disable()
+		ticketDesc.Disable()
+		userID.Disable()
+		cabinet.Disable()
+	}
+	if !role.Can(auth.PermTicketsDelete) {
+		deleteBtn.Hide()
+	}
 
 	form := container.NewVBox(
-		widget.NewLabelWithStyle("Создать/редактировать тикет", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle(formTitle, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		widget.NewForm(
 			widget.NewFormItem("Заголовок", ticketTitle),
 			widget.NewFormItem("Описание", ticketDesc),
@@ -615,10 +688,27 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		),
 	)
 
+	assigneeForm := container.NewVBox(
+		widget.NewLabelWithStyle("Назначить исполнителя", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewBorder(
+			nil,
+			container.NewHBox(
+				widget.NewLabel(""),
+				assignBtn,
+				widget.NewLabel(""),
+			),
+			nil,
+			nil,
+			assigneeSelect,
+		),
+	)
+
 	leftPanel := container.NewVBox(
 		form,
 		widget.NewSeparator(),
 		statusForm,
+		widget.NewSeparator(),
+		assigneeForm,
 	)
 
 	split := container.NewHSplit(
@@ -646,7 +736,8 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		split,
 	)
 
-	cleanup := func() {
+	polling := newPollingTab(ctx, tab.content, autoRefresh)
+	polling.onClose = func() {
 		tab.mutex.Lock()
 		defer tab.mutex.Unlock()
 
@@ -658,14 +749,8 @@ func CreateTicketsTab(window fyne.Window) (fyne.CanvasObject, func()) {
 		if tab.cancelFunc != nil {
 			tab.cancelFunc()
 		}
-		close(tab.refreshChan)
-
-		if tab.db != nil {
-			tab.db.Close()
-		}
 	}
-
-	return tab.content, cleanup
+	return polling, nil
 }
 
 func getTickets(db *sql.DB, sortField string, sortDescending bool) ([]Ticket, error) {
@@ -680,7 +765,7 @@ func getTickets(db *sql.DB, sortField string, sortDescending bool) ([]Ticket, er
 	query := fmt.Sprintf(`
 		SELECT t.id, t.title, t.description, t.user_id,
 		       t.computer_name, t.status_id, ts.name, t.cabinet, 
-		       t.created_at, t.update_at
+		       t.assignee, t.created_at, t.update_at
 		FROM tickets t
 		JOIN tickets_statuses ts ON t.status_id = ts.id
 		ORDER BY %s %s`, sortField, sortDirection)
@@ -694,6 +779,7 @@ func getTickets(db *sql.DB, sortField string, sortDescending bool) ([]Ticket, er
 	var tickets []Ticket
 	for rows.Next() {
 		var ticket Ticket
+		var assignee sql.NullString
 		var createdAt, updatedAt sql.NullTime
 
 		if err := rows.Scan(
@@ -705,11 +791,13 @@ func getTickets(db *sql.DB, sortField string, sortDescending bool) ([]Ticket, er
 			&ticket.StatusID,
 			&ticket.StatusName,
 			&ticket.Cabinet,
+			&assignee,
 			&createdAt,
 			&updatedAt); err != nil {
 			return nil, err
 		}
 
+		ticket.Assignee = assignee.String
 		if createdAt.Valid {
 			ticket.CreatedAt = &createdAt.Time
 		}
@@ -783,6 +871,57 @@ func updateTicketStatus(db *sql.DB, ticketID, statusID int) error {
 	return err
 }
 
+// assignTicket назначает тикет пользователю assignee; пустая строка снимает
+// назначение
+func assignTicket(db *sql.DB, ticketID int, assignee string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	query := `
+		UPDATE tickets 
+		SET assignee = NULLIF($1, ''), update_at = $2 
+		WHERE id = $3`
+	_, err := db.ExecContext(ctx, query,
+		assignee,
+		now,
+		ticketID)
+	return err
+}
+
+// getAssignees возвращает логины пользователей, роли которых могут вести
+// тикеты
+func getAssignees(db *sql.DB) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var roles []string
+	for _, role := range auth.Roles {
+		if role.Can(auth.PermTicketsWrite) {
+			roles = append(roles, string(role))
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT username FROM users
+		WHERE role = ANY($1)
+		ORDER BY username`, pq.Array(roles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usernames []string
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		usernames = append(usernames, username)
+	}
+	return usernames, rows.Err()
+}
+
 func deleteTicket(db *sql.DB, id int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -791,3 +930,13 @@ func deleteTicket(db *sql.DB, id int) error {
 	_, err := db.ExecContext(ctx, query, id)
 	return err
 }
+
+// requestRefresh просит горутину вкладки перечитать список. Запрос не
+// блокирует интерфейс: повторные запросы до обработки схлопываются, а после
+// остановки вкладки отбрасываются.
+func (tab *TicketsTab) requestRefresh() {
+	select {
+	case tab.refreshChan <- struct{}{}:
+	default:
+	}
+}
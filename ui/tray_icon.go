@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"FYNEAPPS/resources"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"runtime"
+	"sync"
+)
+
+// trayIconSize размер значка трея в пикселях
+const trayIconSize = 32
+
+var (
+	alertIcon     []byte
+	alertIconOnce sync.Once
+)
+
+// TrayIcon возвращает значок трея: обычный логотип или логотип с красной
+// точкой, если alert. Для Windows данные упакованы в ICO, для остальных
+// систем это PNG.
+func TrayIcon(alert bool) []byte {
+	if !alert {
+		return resources.ResourcePgatulogosmallicoIco.StaticContent
+	}
+	alertIconOnce.Do(func() {
+		data, err := badgeIcon(resources.ResourcePgatulogosmall128Png.StaticContent)
+		if err != nil {
+			log.Printf("Не удалось построить значок трея: %v", err)
+			return
+		}
+		alertIcon = data
+	})
+	if alertIcon == nil {
+		return resources.ResourcePgatulogosmallicoIco.StaticContent
+	}
+	return alertIcon
+}
+
+// badgeIcon уменьшает PNG до trayIconSize и рисует в правом нижнем углу
+// красную точку
+func badgeIcon(source []byte) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+
+	// Вписываем логотип в квадрат с сохранением пропорций
+	bounds := src.Bounds()
+	scale := float64(trayIconSize) / float64(max(bounds.Dx(), bounds.Dy()))
+	width := int(float64(bounds.Dx()) * scale)
+	height := int(float64(bounds.Dy()) * scale)
+	offsetX, offsetY := (trayIconSize-width)/2, (trayIconSize-height)/2
+
+	dst := image.NewNRGBA(image.Rect(0, 0, trayIconSize, trayIconSize))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + int(float64(x)/scale)
+			sy := bounds.Min.Y + int(float64(y)/scale)
+			dst.Set(offsetX+x, offsetY+y, src.At(sx, sy))
+		}
+	}
+
+	// Точка с белой обводкой, чтобы она была видна на любом фоне панели
+	const radius = 7
+	cx, cy := trayIconSize-radius-1, trayIconSize-radius-1
+	for y := cy - radius; y <= cy+radius; y++ {
+		for x := cx - radius; x <= cx+radius; x++ {
+			d := (x-cx)*(x-cx) + (y-cy)*(y-cy)
+			switch {
+			case d <= (radius-2)*(radius-2):
+				dst.Set(x, y, color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff})
+			case d <= radius*radius:
+				dst.Set(x, y, color.White)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" {
+		return buf.Bytes(), nil
+	}
+	return pngToICO(buf.Bytes(), trayIconSize), nil
+}
+
+// pngToICO упаковывает PNG в контейнер ICO из одного изображения; такой
+// формат Windows принимает начиная с Vista
+func pngToICO(data []byte, size int) []byte {
+	var buf bytes.Buffer
+	// ICONDIR: reserved, type (1 - значок), число изображений
+	binary.Write(&buf, binary.LittleEndian, [3]uint16{0, 1, 1})
+	// ICONDIRENTRY
+	buf.WriteByte(byte(size))                                  // ширина, 0 означает 256
+	buf.WriteByte(byte(size))                                  // высота
+	buf.WriteByte(0)                                           // число цветов палитры
+	buf.WriteByte(0)                                           // reserved
+	binary.Write(&buf, binary.LittleEndian, uint16(1))         // цветовые плоскости
+	binary.Write(&buf, binary.LittleEndian, uint16(32))        // бит на пиксель
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data))) // размер изображения
+	binary.Write(&buf, binary.LittleEndian, uint32(6+16))      // смещение изображения
+	buf.Write(data)
+	return buf.Bytes()
+}
@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"FYNEAPPS/apiclient"
+	"FYNEAPPS/database"
+	"FYNEAPPS/ui/tabs"
+	"FYNEAPPSSERVER/auth"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+const (
+	// watchInterval период фоновой проверки тикетов, серверов и ресурсов
+	watchInterval = time.Minute
+	// localThreshold заполнение диска или памяти в процентах, начиная с
+	// которого показывается предупреждение
+	localThreshold = 90.0
+	// ticketStatusNew идентификатор статуса "Новый" в tickets_statuses
+	ticketStatusNew = 1
+)
+
+// TrayStatus состояние, которое фоновый наблюдатель показывает в трее
+type TrayStatus struct {
+	// NewTickets число тикетов в статусе "Новый", назначенных текущему
+	// пользователю
+	NewTickets int
+	// ServersDown имена серверов общего списка, не ответивших на пинг
+	ServersDown []string
+	// Local превышенные пороги этого компьютера, например "Диск C: 93%"
+	Local []string
+}
+
+// HasProblems сообщает, нужно ли выделить значок в трее
+func (s TrayStatus) HasProblems() bool {
+	return s.NewTickets > 0 || len(s.ServersDown) > 0 || len(s.Local) > 0
+}
+
+// Summary краткое описание для подсказки значка
+func (s TrayStatus) Summary() string {
+	var parts []string
+	if s.NewTickets > 0 {
+		parts = append(parts, fmt.Sprintf("назначено новых тикетов: %d", s.NewTickets))
+	}
+	if len(s.ServersDown) > 0 {
+		parts = append(parts, fmt.Sprintf("недоступно серверов: %d", len(s.ServersDown)))
+	}
+	parts = append(parts, s.Local...)
+	if len(parts) == 0 {
+		return "Проблем не обнаружено"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// watcher периодически проверяет состояние и отправляет уведомления о
+// новых проблемах, чтобы приложение могло весь день оставаться свернутым
+type watcher struct {
+	pool     *database.PGConnection
+	api      *apiclient.Client
+	username string
+	role     auth.Role
+	onChange func(TrayStatus)
+
+	// assigned заголовки новых тикетов, назначенных пользователю, по
+	// идентификаторам на момент прошлой проверки
+	assigned     map[int]string
+	ticketsReady bool
+	prev         TrayStatus
+}
+
+// StartWatcher запускает фоновую проверку до отмены ctx. username логин
+// пользователя, которому назначены отслеживаемые тикеты. onChange
+// вызывается из фоновой горутины после каждой проверки.
+func StartWatcher(ctx context.Context, pool *database.PGConnection, api *apiclient.Client, username string, role auth.Role, onChange func(TrayStatus)) {
+	w := &watcher{pool: pool, api: api, username: username, role: role, onChange: onChange}
+	go func() {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for {
+			w.check(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (w *watcher) check(ctx context.Context) {
+	var status TrayStatus
+	status.NewTickets = w.checkTickets(ctx)
+	status.ServersDown = tabs.UnreachableServers(ctx, w.api)
+	status.Local = localProblems()
+	if ctx.Err() != nil {
+		return
+	}
+
+	for _, name := range added(w.prev.ServersDown, status.ServersDown, nil) {
+		notify("Сервер недоступен", name)
+	}
+	for _, problem := range added(w.prev.Local, status.Local, resourceName) {
+		notify("Мало ресурсов на компьютере", problem)
+	}
+	w.prev = status
+
+	if w.onChange != nil {
+		w.onChange(status)
+	}
+}
+
+// checkTickets возвращает число новых тикетов, назначенных пользователю, и
+// уведомляет о назначенных с прошлой проверки. Без связи с БД повторяется
+// прошлое значение.
+func (w *watcher) checkTickets(ctx context.Context) int {
+	if !w.role.Can(auth.PermTicketsWrite) {
+		return 0
+	}
+	if !w.pool.Healthy() {
+		return w.prev.NewTickets
+	}
+
+	assigned, err := w.assignedTickets(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Ошибка проверки новых тикетов: %v", err)
+		}
+		return w.prev.NewTickets
+	}
+
+	// Тикеты, назначенные до запуска, видны в трее, но не дают уведомления
+	if w.ticketsReady {
+		for _, id := range sortedIDs(assigned) {
+			if _, seen := w.assigned[id]; !seen {
+				notify("Вам назначен тикет", assigned[id])
+			}
+		}
+	}
+	w.assigned = assigned
+	w.ticketsReady = true
+	return len(assigned)
+}
+
+// assignedTickets читает заголовки новых тикетов, назначенных пользователю,
+// по идентификаторам
+func (w *watcher) assignedTickets(ctx context.Context) (map[int]string, error) {
+	rows, err := w.pool.DB().QueryContext(ctx, `
+		SELECT id, title
+		FROM tickets
+		WHERE status_id = $1 AND assignee = $2`,
+		ticketStatusNew, w.username,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assigned := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			return nil, err
+		}
+		assigned[id] = title
+	}
+	return assigned, rows.Err()
+}
+
+// sortedIDs возвращает идентификаторы тикетов по возрастанию
+func sortedIDs(tickets map[int]string) []int {
+	ids := make([]int, 0, len(tickets))
+	for id := range tickets {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// localProblems проверяет заполнение дисков и памяти этого компьютера
+func localProblems() []string {
+	var problems []string
+	if vm, err := mem.VirtualMemory(); err == nil && vm.UsedPercent >= localThreshold {
+		problems = append(problems, fmt.Sprintf("Память %.0f%%", vm.UsedPercent))
+	}
+
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return problems
+	}
+	for _, part := range partitions {
+		usage, err := disk.Usage(part.Mountpoint)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+		if usage.UsedPercent >= localThreshold {
+			problems = append(problems, fmt.Sprintf("Диск %s %.0f%%", part.Mountpoint, usage.UsedPercent))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// resourceName отрезает процент от строки localProblems, чтобы рост
+// заполнения уже отмеченного диска не давал повторного уведомления
+func resourceName(problem string) string {
+	if i := strings.LastIndex(problem, " "); i > 0 {
+		return problem[:i]
+	}
+	return problem
+}
+
+// added возвращает элементы next, которых не было в prev. key, если
+// задан, определяет, какие элементы считаются одинаковыми.
+func added(prev, next []string, key func(string) string) []string {
+	if key == nil {
+		key = func(s string) string { return s }
+	}
+	seen := make(map[string]bool, len(prev))
+	for _, s := range prev {
+		seen[key(s)] = true
+	}
+	var result []string
+	for _, s := range next {
+		if !seen[key(s)] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func notify(title, content string) {
+	fyne.CurrentApp().SendNotification(fyne.NewNotification(title, content))
+}
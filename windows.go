@@ -0,0 +1,155 @@
+package main
+
+import (
+	"FYNEAPPS/resources"
+	"FYNEAPPS/ui"
+	setting "FYNEAPPS/ui/setting_tab"
+	"context"
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+)
+
+// windowManager владеет главным окном и окном входа. Fyne завершает
+// приложение, когда закрыто последнее окно, поэтому при смене окон новое
+// всегда показывается до закрытия старого. Методы вызываются только в
+// потоке интерфейса: из обработчиков Fyne или через fyne.Do.
+type windowManager struct {
+	app   fyne.App
+	main  fyne.Window
+	login fyne.Window
+
+	// stopSession останавливает горутины вкладок и наблюдателя трея,
+	// запущенные для главного окна
+	stopSession context.CancelFunc
+}
+
+func newWindowManager(app fyne.App) *windowManager {
+	return &windowManager{app: app}
+}
+
+// Main возвращает главное окно или nil, если пользователь не вошел
+func (m *windowManager) Main() fyne.Window {
+	return m.main
+}
+
+// ShowLogin показывает окно входа и закрывает главное окно вместе со всеми
+// горутинами его вкладок
+func (m *windowManager) ShowLogin() {
+	if m.login == nil {
+		m.login = ui.CreateLoginWindow(m.app, m.onLogin)
+		m.login.CenterOnScreen()
+	}
+	m.login.Show()
+	m.login.RequestFocus()
+
+	m.closeMain()
+}
+
+// onLogin проверяет учетные данные и при успехе открывает главное окно.
+// Окно входа после этого закрывается, следующий вход начнется с новой формы.
+func (m *windowManager) onLogin(username, password string) bool {
+	if username == "" || password == "" {
+		return false
+	}
+
+	user, err := authenticate(username, password)
+	if err != nil {
+		log.Printf("Ошибка входа: %v", err)
+		return false
+	}
+	if user == nil {
+		return false
+	}
+
+	currentUser = user
+	m.ShowMain()
+	return true
+}
+
+// ShowMain строит главное окно для currentUser. Прежнее главное окно и
+// окно входа закрываются после того, как новое окно показано.
+func (m *windowManager) ShowMain() {
+	title := "ПГАТУ Инфраструктура"
+	if currentUser != nil {
+		title = fmt.Sprintf("%s - %s (%s)", title, currentUser.Username, currentUser.Role.Title())
+	}
+	window := m.app.NewWindow(title)
+	window.CenterOnScreen()
+
+	if icon := resources.ResourcePgatulogosmallPng; icon != nil {
+		window.SetIcon(icon)
+	}
+
+	appSettings := setting.LoadSettings(m.app, window)
+	window.Resize(fyne.NewSize(float32(appSettings.Width), float32(appSettings.Height)))
+
+	// Крестик сворачивает окно в трей; закрывает его только менеджер
+	window.SetCloseIntercept(func() {
+		window.Hide()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	window.SetContent(ui.CreateAppTabs(ctx, m.app, window, pool, queue, api, currentUser.Role))
+	window.Show()
+
+	m.closeMain()
+	m.main, m.stopSession = window, cancel
+	startTrayWatcher(ctx)
+
+	if m.login != nil {
+		m.login.Close()
+		m.login = nil
+	}
+
+	if currentUser.MustChangePassword {
+		showChangePassword(true)
+	}
+}
+
+// Show разворачивает текущее окно: главное или окно входа
+func (m *windowManager) Show() {
+	switch {
+	case m.main != nil:
+		m.main.Show()
+		m.main.RequestFocus()
+	case m.login != nil:
+		m.login.Show()
+		m.login.RequestFocus()
+	}
+}
+
+// Hide сворачивает все окна в трей
+func (m *windowManager) Hide() {
+	if m.main != nil {
+		m.main.Hide()
+	}
+	if m.login != nil {
+		m.login.Hide()
+	}
+}
+
+// Quit останавливает вкладки, закрывает окна и завершает приложение
+func (m *windowManager) Quit() {
+	m.closeMain()
+	if m.login != nil {
+		m.login.Close()
+		m.login = nil
+	}
+	m.app.Quit()
+}
+
+// closeMain останавливает горутины главного окна и закрывает его
+func (m *windowManager) closeMain() {
+	if m.stopSession != nil {
+		m.stopSession()
+		m.stopSession = nil
+	}
+	setTrayStatus(ui.TrayStatus{})
+
+	if m.main != nil {
+		m.main.Close()
+		m.main = nil
+	}
+}